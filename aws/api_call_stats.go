@@ -0,0 +1,192 @@
+package aws
+
+// API call statistics
+//
+// Every client of a connection counts its API calls, retries, throttles,
+// errors and latency per region, service and operation. The counts are kept
+// in memory for the life of the plugin process, and are exposed by the
+// aws_api_call_stat table to find the hydrate functions (by their service and
+// action tags) that make expensive or throttled calls.
+//
+// Implementation notes:
+// - The call middleware runs once per operation, after the connection rate
+//   limiters, so the latency includes retries and backoff but not the time
+//   spent waiting for a limiter.
+// - The attempt middleware runs for each attempt (after the retry
+//   middleware), so throttled attempts count even if a later retry succeeds.
+// - Calls served from the Steampipe cache make no API calls, and are not
+//   counted.
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+)
+
+type apiCallStatKey struct {
+	connection string
+	region     string
+	service    string
+	operation  string
+}
+
+type apiCallStat struct {
+	mu            sync.Mutex
+	Calls         int64
+	Retries       int64
+	Throttles     int64
+	Errors        int64
+	TotalLatency  time.Duration
+	MaxLatency    time.Duration
+	LastErrorCode string
+	FirstCallTime time.Time
+	LastCallTime  time.Time
+}
+
+// Stats by apiCallStatKey, for the life of the plugin process.
+var apiCallStats sync.Map
+
+func getAPICallStat(key apiCallStatKey) *apiCallStat {
+	if i, ok := apiCallStats.Load(key); ok {
+		return i.(*apiCallStat)
+	}
+	i, _ := apiCallStats.LoadOrStore(key, &apiCallStat{})
+	return i.(*apiCallStat)
+}
+
+// Number of attempts of the current call, shared by the call and attempt
+// middleware through the stack values.
+type apiCallAttemptsKey struct{}
+
+func apiCallStatsAPIOption(connectionName string) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		err := stack.Initialize.Add(middleware.InitializeMiddlewareFunc("SteampipeAPICallStats", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			attempts := new(int64)
+			ctx = middleware.WithStackValue(ctx, apiCallAttemptsKey{}, attempts)
+			start := time.Now()
+
+			out, metadata, err := next.HandleInitialize(ctx, in)
+
+			latency := time.Since(start)
+			stat := getAPICallStat(apiCallStatKey{
+				connection: connectionName,
+				region:     awsmiddleware.GetRegion(ctx),
+				service:    awsmiddleware.GetServiceID(ctx),
+				operation:  awsmiddleware.GetOperationName(ctx),
+			})
+			stat.mu.Lock()
+			defer stat.mu.Unlock()
+			stat.Calls++
+			if n := atomic.LoadInt64(attempts); n > 1 {
+				stat.Retries += n - 1
+			}
+			stat.TotalLatency += latency
+			if latency > stat.MaxLatency {
+				stat.MaxLatency = latency
+			}
+			if stat.FirstCallTime.IsZero() {
+				stat.FirstCallTime = start
+			}
+			stat.LastCallTime = start
+			if err != nil {
+				stat.Errors++
+				stat.LastErrorCode = "Unknown"
+				var ae smithy.APIError
+				if errors.As(err, &ae) {
+					stat.LastErrorCode = ae.ErrorCode()
+				}
+			}
+			return out, metadata, err
+		}), middleware.After)
+		if err != nil {
+			return err
+		}
+
+		return stack.Finalize.Insert(middleware.FinalizeMiddlewareFunc("SteampipeAPICallAttemptStats", func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+			if attempts, ok := middleware.GetStackValue(ctx, apiCallAttemptsKey{}).(*int64); ok {
+				atomic.AddInt64(attempts, 1)
+			}
+
+			out, metadata, err := next.HandleFinalize(ctx, in)
+
+			if err != nil && retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary {
+				stat := getAPICallStat(apiCallStatKey{
+					connection: connectionName,
+					region:     awsmiddleware.GetRegion(ctx),
+					service:    awsmiddleware.GetServiceID(ctx),
+					operation:  awsmiddleware.GetOperationName(ctx),
+				})
+				stat.mu.Lock()
+				stat.Throttles++
+				stat.mu.Unlock()
+			}
+			return out, metadata, err
+		}), "Retry", middleware.After)
+	}
+}
+
+// apiCallStatRow is a snapshot of the stats of a region, service and
+// operation.
+type apiCallStatRow struct {
+	Region        string
+	Service       string
+	Operation     string
+	Calls         int64
+	Retries       int64
+	Throttles     int64
+	Errors        int64
+	TotalLatency  time.Duration
+	MaxLatency    time.Duration
+	LastErrorCode string
+	FirstCallTime time.Time
+	LastCallTime  time.Time
+}
+
+// Get a snapshot of the stats of a connection, sorted by region, service and
+// operation.
+func getAPICallStatRows(connectionName string) []apiCallStatRow {
+	var rows []apiCallStatRow
+	apiCallStats.Range(func(k, v interface{}) bool {
+		key := k.(apiCallStatKey)
+		if key.connection != connectionName {
+			return true
+		}
+		stat := v.(*apiCallStat)
+		stat.mu.Lock()
+		rows = append(rows, apiCallStatRow{
+			Region:        key.region,
+			Service:       key.service,
+			Operation:     key.operation,
+			Calls:         stat.Calls,
+			Retries:       stat.Retries,
+			Throttles:     stat.Throttles,
+			Errors:        stat.Errors,
+			TotalLatency:  stat.TotalLatency,
+			MaxLatency:    stat.MaxLatency,
+			LastErrorCode: stat.LastErrorCode,
+			FirstCallTime: stat.FirstCallTime,
+			LastCallTime:  stat.LastCallTime,
+		})
+		stat.mu.Unlock()
+		return true
+	})
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Region != rows[j].Region {
+			return rows[i].Region < rows[j].Region
+		}
+		if rows[i].Service != rows[j].Service {
+			return rows[i].Service < rows[j].Service
+		}
+		return rows[i].Operation < rows[j].Operation
+	})
+	return rows
+}
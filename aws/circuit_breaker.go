@@ -0,0 +1,194 @@
+package aws
+
+// Circuit breaker
+//
+// When a region is degraded, or an SCP denies a whole service, every API call
+// for that service and region fails the same way, and the query spends
+// minutes retrying calls that will never work. The circuit breaker is opt-in:
+// with circuit_breaker_threshold set, it counts consecutive calls that still
+// fail with throttling or access denied errors per service and region. Once
+// the count reaches the threshold, the breaker trips and every further call
+// for that service and region fails immediately with the last error, for the
+// rest of the query.
+//
+// Implementation notes:
+// - Clients are cached across queries, so the breaker is added to a copy of
+//   the cached config for each client request, keyed on the query context.
+// - The middleware runs once per call (before the retry middleware), so only
+//   calls that fail after all retry attempts count. A successful call of any
+//   operation of the service resets the count, so a policy that only denies
+//   some operations (e.g. a Get* call for a sensitive attribute) doesn't trip
+//   the breaker while the rest of the service works.
+// - Errors returned while the breaker is open wrap the last error, so
+//   ignore_error_codes still match it, and are never retried.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// Number of consecutive failed calls before the breaker trips, unless set by
+// circuit_breaker_threshold. The circuit breaker is disabled by default.
+const defaultCircuitBreakerThreshold = 0
+
+// Error codes returned when the caller is not allowed to use the service.
+var circuitBreakerAccessDeniedErrors = []string{
+	"AccessDenied",
+	"AccessDeniedException",
+	"UnauthorizedOperation",
+	"AuthorizationError",
+}
+
+type circuitBreakerKey struct {
+	query   *plugin.QueryContext
+	service string
+	region  string
+}
+
+type circuitBreaker struct {
+	mu       sync.Mutex
+	failures int
+	open     bool
+	lastErr  error
+	lastUsed time.Time
+}
+
+// circuitBreakerOpenError is returned for calls made while the breaker is
+// open. It is not retryable.
+type circuitBreakerOpenError struct {
+	service string
+	region  string
+	err     error
+}
+
+func (e *circuitBreakerOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker open for %s in %s: %v", e.service, e.region, e.err)
+}
+
+func (e *circuitBreakerOpenError) Unwrap() error { return e.err }
+
+func (e *circuitBreakerOpenError) RetryableError() bool { return false }
+
+var (
+	circuitBreakers          sync.Map
+	circuitBreakersMu        sync.Mutex
+	circuitBreakersLastSweep time.Time
+)
+
+// Breakers are per query, but there is no notification when a query ends, so
+// breakers that have not been used for a while are removed when new ones are
+// created.
+const circuitBreakerIdleTtl = time.Hour
+
+func getCircuitBreaker(key circuitBreakerKey) *circuitBreaker {
+	if i, ok := circuitBreakers.Load(key); ok {
+		return i.(*circuitBreaker)
+	}
+
+	circuitBreakersMu.Lock()
+	if time.Since(circuitBreakersLastSweep) > circuitBreakerIdleTtl {
+		circuitBreakers.Range(func(k, v interface{}) bool {
+			b := v.(*circuitBreaker)
+			b.mu.Lock()
+			idle := time.Since(b.lastUsed) > circuitBreakerIdleTtl
+			b.mu.Unlock()
+			if idle {
+				circuitBreakers.Delete(k)
+			}
+			return true
+		})
+		circuitBreakersLastSweep = time.Now()
+	}
+	circuitBreakersMu.Unlock()
+
+	i, _ := circuitBreakers.LoadOrStore(key, &circuitBreaker{lastUsed: time.Now()})
+	return i.(*circuitBreaker)
+}
+
+// Get the circuit breaker threshold for the connection. Returns 0 if the
+// circuit breaker is disabled.
+func getCircuitBreakerThreshold(awsSpcConfig awsConfig) int {
+	if awsSpcConfig.CircuitBreakerThreshold != nil {
+		return *awsSpcConfig.CircuitBreakerThreshold
+	}
+	return defaultCircuitBreakerThreshold
+}
+
+// Return a copy of the client config with the circuit breaker for this query
+// added, or the config itself if the circuit breaker is disabled.
+func withQueryCircuitBreaker(d *plugin.QueryData, cfg *aws.Config) *aws.Config {
+	threshold := getCircuitBreakerThreshold(GetConfig(d.Connection))
+	if threshold <= 0 || d.QueryContext == nil {
+		return cfg
+	}
+	queryCfg := cfg.Copy()
+	queryCfg.APIOptions = append(append([]func(*middleware.Stack) error{}, cfg.APIOptions...), circuitBreakerAPIOption(d.Connection.Name, d.QueryContext, threshold))
+	return &queryCfg
+}
+
+func circuitBreakerAPIOption(connectionName string, query *plugin.QueryContext, threshold int) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Finalize.Insert(middleware.FinalizeMiddlewareFunc("SteampipeCircuitBreaker", func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+			service := awsmiddleware.GetServiceID(ctx)
+			operation := awsmiddleware.GetOperationName(ctx)
+			region := awsmiddleware.GetRegion(ctx)
+			breaker := getCircuitBreaker(circuitBreakerKey{query: query, service: service, region: region})
+
+			breaker.mu.Lock()
+			breaker.lastUsed = time.Now()
+			if breaker.open {
+				err := &circuitBreakerOpenError{service: service, region: region, err: breaker.lastErr}
+				breaker.mu.Unlock()
+				return middleware.FinalizeOutput{}, middleware.Metadata{}, err
+			}
+			breaker.mu.Unlock()
+
+			out, metadata, err := next.HandleFinalize(ctx, in)
+
+			switch {
+			case err == nil:
+				breaker.mu.Lock()
+				breaker.failures = 0
+				breaker.mu.Unlock()
+			case isCircuitBreakerThrottleError(err), isCircuitBreakerAccessDeniedError(err):
+				breaker.mu.Lock()
+				breaker.failures++
+				breaker.lastErr = err
+				if !breaker.open && breaker.failures >= threshold {
+					breaker.open = true
+					plugin.Logger(ctx).Warn("circuitBreakerAPIOption", "connection_name", connectionName, "service", service, "operation", operation, "region", region, "failures", breaker.failures, "status", "tripped", "error", err)
+				}
+				breaker.mu.Unlock()
+			}
+			return out, metadata, err
+		}), "Retry", middleware.Before)
+	}
+}
+
+func isCircuitBreakerThrottleError(err error) bool {
+	return retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary
+}
+
+func isCircuitBreakerAccessDeniedError(err error) bool {
+	var ae smithy.APIError
+	if errors.As(err, &ae) {
+		for _, code := range circuitBreakerAccessDeniedErrors {
+			if strings.EqualFold(ae.ErrorCode(), code) {
+				return true
+			}
+		}
+	}
+	return false
+}
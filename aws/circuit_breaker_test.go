@@ -0,0 +1,124 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/context_key"
+)
+
+// An SNS client for the stand-in with the circuit breaker of a new query,
+// retrying up to 3 attempts without delay.
+func testCircuitBreakerClient(api *stubAPI, threshold int) *sns.Client {
+	return sns.NewFromConfig(aws.Config{
+		Region:       "us-east-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("AKIDSTUB", "stub", ""),
+		BaseEndpoint: aws.String(api.server.URL),
+		Retryer: func() aws.Retryer {
+			return retry.NewStandard(func(o *retry.StandardOptions) {
+				o.MaxAttempts = 3
+				o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) { return 0, nil })
+			})
+		},
+		APIOptions: []func(*middleware.Stack) error{circuitBreakerAPIOption(stubConnection, &plugin.QueryContext{}, threshold)},
+	})
+}
+
+// The middleware logs with the plugin logger when the breaker trips.
+func testCircuitBreakerContext() context.Context {
+	return context.WithValue(context.Background(), context_key.Logger, hclog.NewNullLogger())
+}
+
+func isCircuitBreakerOpen(err error) bool {
+	var openErr *circuitBreakerOpenError
+	return errors.As(err, &openErr)
+}
+
+func TestCircuitBreakerDisabledByDefault(t *testing.T) {
+	if got := getCircuitBreakerThreshold(awsConfig{}); got != 0 {
+		t.Errorf("expected the circuit breaker to be disabled by default, got threshold %d", got)
+	}
+}
+
+func TestCircuitBreakerAccessDeniedPerService(t *testing.T) {
+	ctx := testCircuitBreakerContext()
+	api := newStubAPI(t)
+	denied := queryErrorResponse(http.StatusForbidden, "AuthorizationError", "denied")
+	api.on("sns", "GetTopicAttributes", denied)
+	api.on("sns", "ListSubscriptions", denied)
+	api.on("sns", "ListPlatformApplications", queryResponse("ListPlatformApplications", `<PlatformApplications/>`))
+	api.on("sns", "ListTopics", queryResponse("ListTopics", `<Topics/>`))
+	svc := testCircuitBreakerClient(api, 2)
+
+	// A successful call of another operation resets the count, so a policy
+	// that denies only some operations doesn't trip the breaker
+	input := &sns.GetTopicAttributesInput{TopicArn: aws.String(testSnsTopicArn + "alpha")}
+	for i := 0; i < 2; i++ {
+		if _, err := svc.GetTopicAttributes(ctx, input); err == nil || isCircuitBreakerOpen(err) {
+			t.Fatalf("GetTopicAttributes %d: unexpected error %v", i, err)
+		}
+		if _, err := svc.ListPlatformApplications(ctx, &sns.ListPlatformApplicationsInput{}); err != nil {
+			t.Fatalf("ListPlatformApplications %d: unexpected error %v", i, err)
+		}
+	}
+
+	// Consecutive denied calls of any operations trip the breaker for the
+	// whole service
+	if _, err := svc.GetTopicAttributes(ctx, input); err == nil || isCircuitBreakerOpen(err) {
+		t.Fatalf("GetTopicAttributes: unexpected error %v", err)
+	}
+	if _, err := svc.ListSubscriptions(ctx, &sns.ListSubscriptionsInput{}); err == nil || isCircuitBreakerOpen(err) {
+		t.Fatalf("ListSubscriptions: unexpected error %v", err)
+	}
+	if _, err := svc.ListTopics(ctx, &sns.ListTopicsInput{}); !isCircuitBreakerOpen(err) {
+		t.Errorf("ListTopics: expected the circuit breaker to be open, got %v", err)
+	}
+	if got := len(api.calls("sns", "ListTopics")); got != 0 {
+		t.Errorf("expected no ListTopics calls, got %d", got)
+	}
+	if got := len(api.calls("sns", "GetTopicAttributes")); got != 3 {
+		t.Errorf("expected 3 GetTopicAttributes calls, got %d", got)
+	}
+}
+
+func TestCircuitBreakerThrottlingAfterRetries(t *testing.T) {
+	ctx := testCircuitBreakerContext()
+	api := newStubAPI(t)
+	throttled := queryErrorResponse(http.StatusBadRequest, "Throttling", "Rate exceeded")
+	api.on("sns", "ListSubscriptions", throttled, throttled, queryResponse("ListSubscriptions", `<Subscriptions/>`))
+	api.on("sns", "ListPlatformApplications", throttled)
+	api.on("sns", "ListTopics", queryResponse("ListTopics", `<Topics/>`))
+	svc := testCircuitBreakerClient(api, 2)
+
+	// Throttled attempts that succeed on retry don't count
+	if _, err := svc.ListSubscriptions(ctx, &sns.ListSubscriptionsInput{}); err != nil {
+		t.Fatalf("ListSubscriptions: unexpected error %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := svc.ListPlatformApplications(ctx, &sns.ListPlatformApplicationsInput{}); err == nil || isCircuitBreakerOpen(err) {
+			t.Fatalf("ListPlatformApplications %d: unexpected error %v", i, err)
+		}
+	}
+	if got := len(api.calls("sns", "ListPlatformApplications")); got != 6 {
+		t.Errorf("expected 3 attempts per ListPlatformApplications call, got %d", got)
+	}
+
+	// Throttling trips the breaker for the whole service
+	if _, err := svc.ListTopics(ctx, &sns.ListTopicsInput{}); !isCircuitBreakerOpen(err) {
+		t.Errorf("ListTopics: expected the circuit breaker to be open, got %v", err)
+	}
+	if got := len(api.calls("sns", "ListTopics")); got != 0 {
+		t.Errorf("expected no ListTopics calls, got %d", got)
+	}
+}
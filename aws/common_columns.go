@@ -117,6 +117,10 @@ func getCommonColumns(ctx context.Context, d *plugin.QueryData, h *plugin.Hydrat
 func getCommonColumnsCacheKey(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
 	region := d.EqualsQualString(matrixKeyRegion)
 	key := fmt.Sprintf("getCommonColumns-%s", region)
+	// Organization connections return different account data per member
+	if accountId := getMatrixItemAccountId(ctx, d); accountId != "" {
+		key = fmt.Sprintf("getCommonColumns-%s-%s", accountId, region)
+	}
 	return key, nil
 }
 
@@ -146,6 +150,13 @@ func getCommonColumnsUncached(ctx context.Context, d *plugin.QueryData, h *plugi
 		Region:    region,
 	}
 
+	// For organization connections the caller identity is always the
+	// management account, so use the member account from the matrix instead.
+	// The partition is shared by every account in the organization.
+	if accountId := getMatrixItemAccountId(ctx, d); accountId != "" {
+		commonColumnData.AccountId = accountId
+	}
+
 	plugin.Logger(ctx).Trace("getCommonColumnsUncached", "status", "starting", "connection_name", d.Connection.Name, "common_column_data", *commonColumnData)
 
 	return commonColumnData, nil
@@ -157,11 +168,19 @@ func getCommonColumnsUncached(ctx context.Context, d *plugin.QueryData, h *plugi
 var getCallerIdentity = plugin.HydrateFunc(getCallerIdentityUncached).Memoize()
 
 // returns details about the IAM user or role whose credentials are used to call the operation
+// for organization connections, this is always the management credentials
 func getCallerIdentityUncached(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	ctx = managementAccountContext(ctx)
 
 	// Trace logging to debug cache and execution flows
 	plugin.Logger(ctx).Trace("getCallerIdentityUncached", "status", "starting", "connection_name", d.Connection.Name)
 
+	// Use the caller identity saved by a previous plugin process, if any
+	cachedCallerIdentity := &sts.GetCallerIdentityOutput{}
+	if readMetadataCache(ctx, d, "getCallerIdentity", cachedCallerIdentity) {
+		return cachedCallerIdentity, nil
+	}
+
 	// get the service connection for the service
 	svc, err := STSClient(ctx, d)
 	if err != nil {
@@ -175,6 +194,8 @@ func getCallerIdentityUncached(ctx context.Context, d *plugin.QueryData, _ *plug
 		return nil, err
 	}
 
+	writeMetadataCache(ctx, d, "getCallerIdentity", callerIdentity)
+
 	plugin.Logger(ctx).Trace("getCallerIdentityUncached", "status", "finished", "connection_name", d.Connection.Name)
 	return callerIdentity, nil
 }
@@ -1,24 +1,99 @@
 package aws
 
 import (
-	"fmt"
 	"strings"
 
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 )
 
 type awsConfig struct {
-	Regions               []string `hcl:"regions,optional"`
-	DefaultRegion         *string  `hcl:"default_region"`
-	Profile               *string  `hcl:"profile"`
-	AccessKey             *string  `hcl:"access_key"`
-	SecretKey             *string  `hcl:"secret_key"`
-	SessionToken          *string  `hcl:"session_token"`
-	MaxErrorRetryAttempts *int     `hcl:"max_error_retry_attempts"`
-	MinErrorRetryDelay    *int     `hcl:"min_error_retry_delay"`
-	IgnoreErrorCodes      []string `hcl:"ignore_error_codes,optional"`
-	EndpointUrl           *string  `hcl:"endpoint_url"`
-	S3ForcePathStyle      *bool    `hcl:"s3_force_path_style"`
+	Regions                 []string                   `hcl:"regions,optional"`
+	ServiceRegions          map[string][]string        `hcl:"service_regions,optional"`
+	RegionSource            *string                    `hcl:"region_source"`
+	RegionCatalogFile       *string                    `hcl:"region_catalog_file"`
+	DefaultRegion           *string                    `hcl:"default_region"`
+	Profile                 *string                    `hcl:"profile"`
+	AccessKey               *string                    `hcl:"access_key"`
+	SecretKey               *string                    `hcl:"secret_key"`
+	SessionToken            *string                    `hcl:"session_token"`
+	RoleArn                 *string                    `hcl:"role_arn"`
+	ExternalId              *string                    `hcl:"external_id"`
+	RoleSessionName         *string                    `hcl:"role_session_name"`
+	DurationSeconds         *int                       `hcl:"duration_seconds"`
+	AssumeRoleChain         []awsAssumeRoleChainConfig `hcl:"assume_role_chain,block"`
+	MFASerial               *string                    `hcl:"mfa_serial"`
+	MFATokenCommand         *string                    `hcl:"mfa_token_command"`
+	MFATokenFile            *string                    `hcl:"mfa_token_file"`
+	WebIdentityTokenFile    *string                    `hcl:"web_identity_token_file"`
+	RolesAnywhere           *awsRolesAnywhereConfig    `hcl:"roles_anywhere,block"`
+	Organization            *awsOrganizationConfig     `hcl:"organization,block"`
+	MaxErrorRetryAttempts   *int                       `hcl:"max_error_retry_attempts"`
+	MinErrorRetryDelay      *int                       `hcl:"min_error_retry_delay"`
+	RetryMode               *string                    `hcl:"retry_mode"`
+	CircuitBreakerThreshold *int                       `hcl:"circuit_breaker_threshold"`
+	IgnoreErrorCodes        []string                   `hcl:"ignore_error_codes,optional"`
+	LogIgnoredErrors        *bool                      `hcl:"log_ignored_errors"`
+	EndpointUrl             *string                    `hcl:"endpoint_url"`
+	Endpoints               map[string]string          `hcl:"endpoints,optional"`
+	S3ForcePathStyle        *bool                      `hcl:"s3_force_path_style"`
+	UseFIPSEndpoint         *bool                      `hcl:"use_fips_endpoint"`
+	UseDualStackEndpoint    *bool                      `hcl:"use_dualstack_endpoint"`
+	HTTPProxy               *string                    `hcl:"http_proxy"`
+	NoProxy                 []string                   `hcl:"no_proxy,optional"`
+	CABundle                *string                    `hcl:"ca_bundle"`
+	InsecureSkipVerify      *bool                      `hcl:"insecure_skip_verify"`
+	RateLimiters            []awsRateLimiterConfig     `hcl:"rate_limiter,block"`
+	CassetteMode            *string                    `hcl:"cassette_mode"`
+	CassetteDir             *string                    `hcl:"cassette_dir"`
+	RedactAccountIds        *bool                      `hcl:"redact_account_ids"`
+	MetadataCacheTTL        *int                       `hcl:"metadata_cache_ttl"`
+	MetadataCacheDir        *string                    `hcl:"metadata_cache_dir"`
+}
+
+// awsAssumeRoleChainConfig is an intermediate role assumed, in order, before
+// the final role_arn of the connection. Each role is assumed using the
+// credentials of the previous one in the chain.
+type awsAssumeRoleChainConfig struct {
+	RoleArn         string  `hcl:"role_arn"`
+	ExternalId      *string `hcl:"external_id"`
+	RoleSessionName *string `hcl:"role_session_name"`
+	DurationSeconds *int    `hcl:"duration_seconds"`
+}
+
+// awsRolesAnywhereConfig gets credentials from IAM Roles Anywhere using an
+// X.509 certificate and its private key (both PEM files).
+type awsRolesAnywhereConfig struct {
+	Certificate      string  `hcl:"certificate"`
+	CertificateChain *string `hcl:"certificate_chain"`
+	PrivateKey       string  `hcl:"private_key"`
+	TrustAnchorArn   string  `hcl:"trust_anchor_arn"`
+	ProfileArn       string  `hcl:"profile_arn"`
+	RoleArn          string  `hcl:"role_arn"`
+	DurationSeconds  *int    `hcl:"duration_seconds"`
+}
+
+// awsOrganizationConfig turns the connection into a fan-out across the member
+// accounts of an AWS Organization. The connection credentials are used as the
+// management credentials to list accounts and assume member_role_name in each.
+type awsOrganizationConfig struct {
+	MemberRoleName        string   `hcl:"member_role_name"`
+	MemberRoleExternalId  *string  `hcl:"member_role_external_id"`
+	MemberRoleSessionName *string  `hcl:"member_role_session_name"`
+	IncludeAccounts       []string `hcl:"include_accounts,optional"`
+	ExcludeAccounts       []string `hcl:"exclude_accounts,optional"`
+}
+
+// awsRateLimiterConfig limits the rate of API calls made by the connection.
+// A block named after one of the plugin default limiters (e.g. "aws_iam")
+// inherits its service, action and rates, so only the values to change need
+// to be set. Any other name adds a new limiter, which requires service. Either
+// way it replaces the default limiter with the same service and action.
+type awsRateLimiterConfig struct {
+	Name       string   `hcl:"name,label"`
+	Service    *string  `hcl:"service"`
+	Action     *string  `hcl:"action"`
+	FillRate   *float64 `hcl:"fill_rate"`
+	BucketSize *int     `hcl:"bucket_size"`
 }
 
 func ConfigInstance() interface{} {
@@ -32,15 +107,15 @@ func GetConfig(connection *plugin.Connection) awsConfig {
 	}
 	config, _ := connection.Config.(awsConfig)
 
-	if config.Regions != nil {
-		if len(config.Regions) == 0 {
-			// Setting "regions = []" in the connection config is not valid
-			errorMessage := fmt.Sprintf("connection %s has invalid value for \"regions\", it must contain at least 1 region.", connection.Name)
-			panic(errorMessage)
-		}
+	// Invalid values (e.g. "regions = []") are reported by
+	// validateConnectionConfig when the connection is used
+	for i, r := range config.Regions {
+		config.Regions[i] = NormalizeRegion(r)
+	}
 
-		for i, r := range config.Regions {
-			config.Regions[i] = NormalizeRegion(r)
+	for _, regions := range config.ServiceRegions {
+		for i, r := range regions {
+			regions[i] = NormalizeRegion(r)
 		}
 	}
 
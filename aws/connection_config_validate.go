@@ -0,0 +1,319 @@
+package aws
+
+// Connection config validation
+//
+// Mistakes in the connection config (e.g. a typo in a region, or credentials
+// from two different sources) would otherwise fail later with errors from the
+// AWS SDK or STS that don't mention the config. The config is checked before
+// the first client or region matrix of a connection is built, and all the
+// problems found are returned in a single error.
+//
+// Implementation notes:
+// - The config is checked as soon as it changes, by the
+//   ConnectionConfigChangedFunc of the plugin, which logs the problems found.
+//   The SDK has no hook for configs loaded at startup, and ignores the error
+//   of the callback, so the check also runs when the connection is first
+//   used, where it fails the query. It is cached per connection once the
+//   config is valid.
+// - Regions are checked against the region catalog of the connection, so
+//   regions that are newer than the plugin can be added with
+//   region_catalog_file.
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// Cached once the config of the connection is valid. Invalid configs are
+// not cached, so each use of the connection returns the error.
+var validateConnectionConfigCached = plugin.HydrateFunc(validateConnectionConfigUncached).Memoize()
+
+// Validate the connection config, returning an error with every problem.
+func validateConnectionConfig(ctx context.Context, d *plugin.QueryData) error {
+	_, err := validateConnectionConfigCached(ctx, d, nil)
+	return err
+}
+
+func validateConnectionConfigUncached(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	catalog, err := getRegionCatalog(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	if err := getConnectionConfigError(d.Connection, catalog); err != nil {
+		plugin.Logger(ctx).Error("validateConnectionConfigUncached", "connection_name", d.Connection.Name, "error", err)
+		return nil, err
+	}
+	return true, nil
+}
+
+// Check the config of a connection when it changes, then clear the caches of
+// the connection like the default SDK callback does.
+func connectionConfigChanged(ctx context.Context, p *plugin.Plugin, old *plugin.Connection, new *plugin.Connection) error {
+	awsSpcConfig := GetConfig(new)
+	path := ""
+	if awsSpcConfig.RegionCatalogFile != nil {
+		path = *awsSpcConfig.RegionCatalogFile
+	}
+	catalog, err := loadRegionCatalog(path)
+	if err == nil {
+		err = getConnectionConfigError(new, catalog)
+	}
+	if err != nil {
+		plugin.Logger(ctx).Error("connectionConfigChanged", "connection_name", new.Name, "error", err)
+	}
+
+	if err := p.ClearConnectionCache(ctx, new.Name); err != nil {
+		return err
+	}
+	return p.ClearQueryCache(ctx, new.Name)
+}
+
+// Get an error with every problem of the connection config, or nil if valid.
+func getConnectionConfigError(connection *plugin.Connection, catalog *regionCatalog) error {
+	if problems := getConnectionConfigProblems(GetConfig(connection), catalog); len(problems) > 0 {
+		return fmt.Errorf("connection %s has an invalid config: %s", connection.Name, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Check the connection config, returning a description of each problem.
+func getConnectionConfigProblems(awsSpcConfig awsConfig, catalog *regionCatalog) []string {
+	var problems []string
+
+	// Regions
+	if awsSpcConfig.Regions != nil && len(awsSpcConfig.Regions) == 0 {
+		problems = append(problems, `"regions" must contain at least 1 region`)
+	}
+	problems = append(problems, getRegionPatternsProblems("regions", awsSpcConfig.Regions, catalog)...)
+
+	services := map[string]bool{}
+	for _, p := range catalog.Partitions {
+		for service := range p.Services {
+			services[normalizeEndpointServiceKey(service)] = true
+		}
+	}
+	serviceIds := make([]string, 0, len(awsSpcConfig.ServiceRegions))
+	for service := range awsSpcConfig.ServiceRegions {
+		serviceIds = append(serviceIds, service)
+	}
+	sort.Strings(serviceIds)
+	for _, service := range serviceIds {
+		if !services[normalizeEndpointServiceKey(service)] {
+			problems = append(problems, fmt.Sprintf("unknown service %q in \"service_regions\", keys must be service endpoint IDs, e.g. ec2", service))
+			continue
+		}
+		problems = append(problems, getRegionPatternsProblems(fmt.Sprintf("service_regions.%s", service), awsSpcConfig.ServiceRegions[service], catalog)...)
+	}
+
+	if awsSpcConfig.DefaultRegion != nil {
+		if problem := getRegionProblem("default_region", NormalizeRegion(*awsSpcConfig.DefaultRegion), catalog); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+
+	if awsSpcConfig.RegionSource != nil {
+		if s := getRegionSource(awsSpcConfig); s != regionSourceEC2 && s != regionSourceAccount {
+			problems = append(problems, fmt.Sprintf("invalid value %q for \"region_source\", it must be %q or %q", *awsSpcConfig.RegionSource, regionSourceEC2, regionSourceAccount))
+		}
+	}
+
+	// Credentials
+	problems = append(problems, getCredentialsConfigProblems(awsSpcConfig)...)
+
+	// Error codes
+	for _, pattern := range awsSpcConfig.IgnoreErrorCodes {
+		if _, err := path.Match(pattern, ""); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid pattern %q in \"ignore_error_codes\": %v", pattern, err))
+		}
+	}
+
+	return problems
+}
+
+// Check a list of region patterns (e.g. regions or service_regions). Every
+// pattern must match a region, and the regions must be in a single
+// partition.
+func getRegionPatternsProblems(name string, patterns []string, catalog *regionCatalog) []string {
+	var problems []string
+	partitions := map[string]bool{}
+	for _, pattern := range patterns {
+		exclusion := strings.HasPrefix(pattern, "!")
+		regionPattern := strings.TrimPrefix(pattern, "!")
+
+		if !strings.ContainsAny(regionPattern, "*?[") {
+			if problem := getRegionProblem(name, regionPattern, catalog); problem != "" {
+				problems = append(problems, problem)
+			} else if !exclusion {
+				partitions[getRegionCatalogPartition(regionPattern, catalog)] = true
+			}
+			continue
+		}
+
+		if _, err := path.Match(regionPattern, ""); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid pattern %q in %q: %v", pattern, name, err))
+			continue
+		}
+		var matchedPartitions []string
+		for _, p := range catalog.Partitions {
+			for region := range p.Regions {
+				if ok, _ := path.Match(regionPattern, region); ok {
+					matchedPartitions = append(matchedPartitions, p.Partition)
+					break
+				}
+			}
+		}
+		if len(matchedPartitions) == 0 {
+			problems = append(problems, fmt.Sprintf("pattern %q in %q does not match any known region", pattern, name))
+			continue
+		}
+		// Patterns like "*" match every partition, and don't choose one
+		if !exclusion && len(matchedPartitions) == 1 {
+			partitions[matchedPartitions[0]] = true
+		}
+	}
+
+	if len(partitions) > 1 {
+		var ids []string
+		for id := range partitions {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		problems = append(problems, fmt.Sprintf("%q has regions in the %s partitions, but a connection can only query one partition", name, strings.Join(ids, " and ")))
+	}
+	return problems
+}
+
+// Check a region is in the catalog, suggesting the nearest known region if
+// not. Regions of partitions without a region list in the catalog can't be
+// checked, and are accepted.
+func getRegionProblem(name string, region string, catalog *regionCatalog) string {
+	if getRegionCatalogPartition(region, catalog) != "" {
+		return ""
+	}
+	if p, ok := catalog.partition(awsPartitionFromRegion(region)); !ok || len(p.Regions) == 0 {
+		return ""
+	}
+
+	problem := fmt.Sprintf("unknown region %q in %q", region, name)
+	if suggestion := getNearestRegion(region, catalog); suggestion != "" {
+		problem += fmt.Sprintf(", did you mean %q?", suggestion)
+	}
+	return problem
+}
+
+// Get the partition of a region in the catalog, or "" if unknown.
+func getRegionCatalogPartition(region string, catalog *regionCatalog) string {
+	for _, p := range catalog.Partitions {
+		if _, ok := p.Regions[region]; ok {
+			return p.Partition
+		}
+	}
+	return ""
+}
+
+// Get the region of the catalog with the smallest edit distance to the given
+// one, if it's close enough to be a typo.
+func getNearestRegion(region string, catalog *regionCatalog) string {
+	nearest := ""
+	nearestDistance := 4
+	for _, p := range catalog.Partitions {
+		for _, candidate := range p.regionIds() {
+			if distance := getEditDistance(region, candidate); distance < nearestDistance {
+				nearest = candidate
+				nearestDistance = distance
+			}
+		}
+	}
+	return nearest
+}
+
+// Levenshtein distance between two strings.
+func getEditDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// Check that the credentials come from a single source.
+func getCredentialsConfigProblems(awsSpcConfig awsConfig) []string {
+	var problems []string
+
+	if awsSpcConfig.AccessKey != nil && awsSpcConfig.SecretKey == nil {
+		problems = append(problems, "partial credentials found in connection config, missing: secret_key")
+	} else if awsSpcConfig.SecretKey != nil && awsSpcConfig.AccessKey == nil {
+		problems = append(problems, "partial credentials found in connection config, missing: access_key")
+	}
+	if awsSpcConfig.SessionToken != nil && awsSpcConfig.AccessKey == nil {
+		problems = append(problems, "session_token requires access_key and secret_key to be set in the connection config")
+	}
+
+	// Sources of the base credentials, only one can be used
+	var sources []string
+	if awsSpcConfig.Profile != nil {
+		sources = append(sources, "profile")
+	}
+	if awsSpcConfig.AccessKey != nil {
+		sources = append(sources, "access_key")
+	}
+	if awsSpcConfig.WebIdentityTokenFile != nil {
+		sources = append(sources, "web_identity_token_file")
+	}
+	if awsSpcConfig.RolesAnywhere != nil {
+		sources = append(sources, "roles_anywhere")
+	}
+	if len(sources) > 1 {
+		problems = append(problems, fmt.Sprintf("%s cannot be combined, use only one source of credentials", strings.Join(sources, " and ")))
+	}
+
+	if awsSpcConfig.WebIdentityTokenFile != nil {
+		if awsSpcConfig.RoleArn == nil {
+			problems = append(problems, "web_identity_token_file requires role_arn to be set in the connection config")
+		}
+		if len(awsSpcConfig.AssumeRoleChain) > 0 {
+			problems = append(problems, "web_identity_token_file cannot be combined with assume_role_chain")
+		}
+	}
+
+	if awsSpcConfig.MFASerial != nil {
+		if awsSpcConfig.MFATokenCommand == nil && awsSpcConfig.MFATokenFile == nil {
+			problems = append(problems, "mfa_serial requires mfa_token_command or mfa_token_file to be set in the connection config")
+		} else if awsSpcConfig.MFATokenCommand != nil && awsSpcConfig.MFATokenFile != nil {
+			problems = append(problems, "mfa_token_command and mfa_token_file cannot be combined")
+		}
+		if awsSpcConfig.WebIdentityTokenFile != nil || awsSpcConfig.RolesAnywhere != nil {
+			problems = append(problems, "mfa_serial cannot be combined with web_identity_token_file or roles_anywhere")
+		}
+	} else if awsSpcConfig.MFATokenCommand != nil || awsSpcConfig.MFATokenFile != nil {
+		problems = append(problems, "mfa_token_command and mfa_token_file require mfa_serial to be set in the connection config")
+	}
+
+	if awsSpcConfig.RoleArn == nil {
+		if awsSpcConfig.ExternalId != nil {
+			problems = append(problems, "external_id requires role_arn to be set in the connection config")
+		}
+		if awsSpcConfig.DurationSeconds != nil && awsSpcConfig.MFASerial == nil {
+			problems = append(problems, "duration_seconds requires role_arn or mfa_serial to be set in the connection config")
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,145 @@
+package aws
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestConnectionConfigProblems(t *testing.T) {
+	tests := []struct {
+		name   string
+		config awsConfig
+		// Substrings of the expected problems, in order. Empty if valid.
+		problems []string
+	}{
+		{
+			name:   "valid",
+			config: awsConfig{Regions: []string{"us-east-1", "eu-*", "!eu-west-3"}, AccessKey: aws.String("AKID"), SecretKey: aws.String("secret")},
+		},
+		{
+			name:   "all regions",
+			config: awsConfig{Regions: []string{"*"}, Profile: aws.String("dev")},
+		},
+		{
+			name:     "empty regions",
+			config:   awsConfig{Regions: []string{}},
+			problems: []string{`"regions" must contain at least 1 region`},
+		},
+		{
+			name:     "region typo",
+			config:   awsConfig{Regions: []string{"us-est-1"}},
+			problems: []string{`unknown region "us-est-1" in "regions", did you mean "us-east-1"?`},
+		},
+		{
+			name:     "unknown partition",
+			config:   awsConfig{Regions: []string{"xx-*"}},
+			problems: []string{`pattern "xx-*" in "regions" does not match any known region`},
+		},
+		{
+			name:     "invalid pattern",
+			config:   awsConfig{Regions: []string{"us-[east-1"}},
+			problems: []string{`invalid pattern "us-[east-1" in "regions"`},
+		},
+		{
+			name:     "mixed partitions",
+			config:   awsConfig{Regions: []string{"us-east-1", "us-gov-*"}},
+			problems: []string{`"regions" has regions in the aws and aws-us-gov partitions`},
+		},
+		{
+			name:   "excluded region of another partition",
+			config: awsConfig{Regions: []string{"us-east-1", "!us-gov-west-1"}},
+		},
+		{
+			name:     "default region typo",
+			config:   awsConfig{DefaultRegion: aws.String("eu-west-11")},
+			problems: []string{`unknown region "eu-west-11" in "default_region", did you mean "eu-west-1"?`},
+		},
+		{
+			name:     "service regions",
+			config:   awsConfig{ServiceRegions: map[string][]string{"sns": {"ap-soth-1"}, "nosuchservice": {"us-east-1"}}},
+			problems: []string{`unknown service "nosuchservice"`, `unknown region "ap-soth-1" in "service_regions.sns", did you mean "ap-south-1"?`},
+		},
+		{
+			name:     "region source",
+			config:   awsConfig{RegionSource: aws.String("organizations")},
+			problems: []string{`invalid value "organizations" for "region_source"`},
+		},
+		{
+			name:     "profile and access key",
+			config:   awsConfig{Profile: aws.String("dev"), AccessKey: aws.String("AKID"), SecretKey: aws.String("secret")},
+			problems: []string{"profile and access_key cannot be combined"},
+		},
+		{
+			name:     "partial credentials",
+			config:   awsConfig{AccessKey: aws.String("AKID"), SessionToken: aws.String("token")},
+			problems: []string{"missing: secret_key"},
+		},
+		{
+			name:     "web identity without role",
+			config:   awsConfig{WebIdentityTokenFile: aws.String("/token"), AssumeRoleChain: []awsAssumeRoleChainConfig{{RoleArn: "arn:aws:iam::111122223333:role/a"}}},
+			problems: []string{"requires role_arn", "cannot be combined with assume_role_chain"},
+		},
+		{
+			name:     "external id without role",
+			config:   awsConfig{ExternalId: aws.String("id")},
+			problems: []string{"external_id requires role_arn"},
+		},
+		{
+			name:   "mfa session",
+			config: awsConfig{MFASerial: aws.String("arn:aws:iam::111122223333:mfa/a"), MFATokenFile: aws.String("/token"), DurationSeconds: aws.Int(3600)},
+		},
+		{
+			name:     "mfa without token",
+			config:   awsConfig{MFASerial: aws.String("arn:aws:iam::111122223333:mfa/a"), RoleArn: aws.String("arn:aws:iam::111122223333:role/a")},
+			problems: []string{"mfa_serial requires mfa_token_command or mfa_token_file"},
+		},
+		{
+			name:     "mfa token without serial",
+			config:   awsConfig{MFATokenCommand: aws.String("echo 123456")},
+			problems: []string{"require mfa_serial"},
+		},
+		{
+			name:     "ignore error codes",
+			config:   awsConfig{IgnoreErrorCodes: []string{"AccessDenied*", "Unauthorized[Operation"}},
+			problems: []string{`invalid pattern "Unauthorized[Operation" in "ignore_error_codes"`},
+		},
+	}
+
+	catalog := getEmbeddedRegionCatalog()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			problems := getConnectionConfigProblems(test.config, catalog)
+			if len(problems) != len(test.problems) {
+				t.Fatalf("expected %d problems, got %q", len(test.problems), problems)
+			}
+			for i, want := range test.problems {
+				if !strings.Contains(problems[i], want) {
+					t.Errorf("expected problem %q to contain %q", problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestConnectionConfigValidation(t *testing.T) {
+	api := newMultiRegionStubAPI(t)
+	_, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_sns_topic",
+		Columns: []string{"topic_arn"},
+		Regions: []string{"us-est-1"},
+		Config:  `profile = "dev"`,
+	})
+	if err == nil {
+		t.Fatalf("expected the query to fail")
+	}
+	for _, want := range []string{"us-est-1", "did you mean", "profile and access_key cannot be combined"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected the error to contain %q, got %v", want, err)
+		}
+	}
+	if got := len(api.calls("sns", "ListTopics")); got != 0 {
+		t.Errorf("expected no ListTopics calls, got %d", got)
+	}
+}
@@ -137,6 +137,10 @@ func streamCostAndUsage(ctx context.Context, d *plugin.QueryData, params *costex
 		plugin.Logger(ctx).Error("streamCostAndUsage", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 	// List call
 	for {
 		output, err := svc.GetCostAndUsage(ctx, params)
@@ -17,14 +17,15 @@ func shouldIgnoreErrors(notFoundErrors []string) plugin.ErrorPredicateWithContex
 		// If the get or list hydrate functions have an overriding IgnoreConfig
 		// defined using the shouldIgnoreErrors function, then it should
 		// also check for errors in the "ignore_error_codes" config argument
-		allErrors := append(notFoundErrors, awsConfig.IgnoreErrorCodes...)
 		var ae smithy.APIError
 		if errors.As(err, &ae) {
-			// Added to support regex in not found errors
-			for _, pattern := range allErrors {
-				if ok, _ := path.Match(pattern, ae.ErrorCode()); ok {
-					return true
-				}
+			if matchesErrorCode(notFoundErrors, ae.ErrorCode()) {
+				recordIgnoredError(ctx, d, err, ignoredErrorReasonNotFound)
+				return true
+			}
+			if matchesErrorCode(awsConfig.IgnoreErrorCodes, ae.ErrorCode()) {
+				recordIgnoredError(ctx, d, err, ignoredErrorReasonIgnoreErrorCodes)
+				return true
 			}
 		}
 		return false
@@ -40,13 +41,9 @@ func shouldIgnoreErrorPluginDefault() plugin.ErrorPredicateWithContext {
 
 		awsConfig := GetConfig(d.Connection)
 		var ae smithy.APIError
-		if errors.As(err, &ae) {
-			// Added to support regex in not found errors
-			for _, pattern := range awsConfig.IgnoreErrorCodes {
-				if ok, _ := path.Match(pattern, ae.ErrorCode()); ok {
-					return true
-				}
-			}
+		if errors.As(err, &ae) && matchesErrorCode(awsConfig.IgnoreErrorCodes, ae.ErrorCode()) {
+			recordIgnoredError(ctx, d, err, ignoredErrorReasonIgnoreErrorCodes)
+			return true
 		}
 		return false
 	}
@@ -56,3 +53,14 @@ func hasIgnoredErrorCodes(connection *plugin.Connection) bool {
 	awsConfig := GetConfig(connection)
 	return len(awsConfig.IgnoreErrorCodes) > 0
 }
+
+// matchesErrorCode returns true if the error code matches any of the patterns.
+// Patterns support glob syntax, e.g. "AccessDenied*".
+func matchesErrorCode(patterns []string, code string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, code); ok {
+			return true
+		}
+	}
+	return false
+}
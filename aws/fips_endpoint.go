@@ -0,0 +1,115 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// FIPS endpoints
+//
+// With use_fips_endpoint, the AWS SDK builds a FIPS hostname for every
+// service (e.g. ec2-fips.us-east-1.amazonaws.com), whether the service has a
+// FIPS endpoint in the region or not. Requests to a hostname that doesn't
+// exist fail with a confusing DNS error. Instead, the region catalog is used
+// to check for a FIPS endpoint before calling a service:
+// - Regional services are skipped in the regions they have no FIPS endpoint
+//   in. They are dropped from the region matrix (see
+//   listRegionsForServiceWithFipsSkips), a warning is logged once for each
+//   service and region, and each query of a table records the regions it
+//   skipped in aws_ignored_error.
+// - Global services without a FIPS endpoint fail with a
+//   FIPSEndpointNotAvailable error, since the table would otherwise return no
+//   data at all. It can be ignored with ignore_error_codes.
+//
+// Requests are never sent to the non-FIPS endpoint instead. As a guard for
+// calls outside the region matrix, the hostname of each request is checked
+// against the FIPS endpoints in the region catalog before it is sent.
+
+// Error code for calls to a service without a FIPS endpoint in the region,
+// outside the region matrix.
+const fipsEndpointNotAvailableErrorCode = "FIPSEndpointNotAvailable"
+
+// Check if FIPS endpoints are used, from use_fips_endpoint or the
+// AWS_USE_FIPS_ENDPOINT environment variable (the config has precedence).
+func useFIPSEndpoint(awsSpcConfig awsConfig) bool {
+	if awsSpcConfig.UseFIPSEndpoint != nil {
+		return *awsSpcConfig.UseFIPSEndpoint
+	}
+	return strings.EqualFold(os.Getenv("AWS_USE_FIPS_ENDPOINT"), "true")
+}
+
+// Build the API option that fails requests that are not sent to a FIPS
+// endpoint. Services with a custom endpoint (endpoint_url or endpoints) are
+// not checked.
+func fipsEndpointAPIOption(catalog *regionCatalog, awsSpcConfig awsConfig) func(*middleware.Stack) error {
+	customEndpoints := map[string]bool{}
+	for service := range awsSpcConfig.Endpoints {
+		customEndpoints[normalizeEndpointServiceKey(service)] = true
+	}
+	return func(stack *middleware.Stack) error {
+		return stack.Finalize.Add(middleware.FinalizeMiddlewareFunc("SteampipeFIPSEndpointCheck", func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+			service := awsmiddleware.GetServiceID(ctx)
+			req, ok := in.Request.(*smithyhttp.Request)
+			if !ok || customEndpoints[normalizeEndpointServiceKey(service)] {
+				return next.HandleFinalize(ctx, in)
+			}
+			if hostname := req.URL.Hostname(); !catalog.isFipsHostname(hostname) {
+				return middleware.FinalizeOutput{}, middleware.Metadata{}, &smithy.GenericAPIError{
+					Code:    fipsEndpointNotAvailableErrorCode,
+					Message: fmt.Sprintf("%s has no FIPS endpoint in %s (%s is not a known FIPS endpoint), and use_fips_endpoint does not fall back to non-FIPS endpoints", service, awsmiddleware.GetRegion(ctx), hostname),
+					Fault:   smithy.FaultClient,
+				}
+			}
+			return next.HandleFinalize(ctx, in)
+		}), middleware.After)
+	}
+}
+
+// Check if a service can be called in a region with use_fips_endpoint, i.e.
+// it has a FIPS endpoint in the region. Services are not checked if FIPS
+// endpoints are not used, or the service has a custom endpoint (endpoint_url
+// or endpoints). Services in partitions missing from the region catalog are
+// left to the hostname check.
+func hasFipsEndpointForServiceRegion(catalog *regionCatalog, awsSpcConfig awsConfig, serviceID string, region string) bool {
+	if !useFIPSEndpoint(awsSpcConfig) || awsSpcConfig.EndpointUrl != nil {
+		return true
+	}
+	for service := range awsSpcConfig.Endpoints {
+		if normalizeEndpointServiceKey(service) == normalizeEndpointServiceKey(serviceID) {
+			return true
+		}
+	}
+	partition, ok := catalog.partition(awsPartitionFromRegion(region))
+	return !ok || partition.hasFipsEndpoint(serviceID, region)
+}
+
+// Build the error for a service without a FIPS endpoint in a region.
+func newFipsEndpointNotAvailableError(serviceID string, region string) *smithy.GenericAPIError {
+	return &smithy.GenericAPIError{
+		Code:    fipsEndpointNotAvailableErrorCode,
+		Message: fmt.Sprintf("%s has no FIPS endpoint in %s, and use_fips_endpoint does not fall back to non-FIPS endpoints", serviceID, region),
+		Fault:   smithy.FaultClient,
+	}
+}
+
+// Services and regions already skipped, so the warning is only logged once.
+var fipsSkippedServiceRegions sync.Map
+
+// Log a warning the first time a service is skipped in a region for a
+// connection.
+func warnFipsSkippedServiceRegion(ctx context.Context, connectionName string, serviceID string, region string) {
+	key := connectionName + "/" + serviceID + "/" + region
+	if _, warned := fipsSkippedServiceRegions.LoadOrStore(key, true); !warned {
+		plugin.Logger(ctx).Warn("warnFipsSkippedServiceRegion", "connection_name", connectionName, "service", serviceID, "region", region, "status", "skipped, the service has no FIPS endpoint in the region and use_fips_endpoint does not fall back to non-FIPS endpoints")
+	}
+}
@@ -0,0 +1,148 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/context_key"
+)
+
+func TestIsFipsHostname(t *testing.T) {
+	catalog := getEmbeddedRegionCatalog()
+	tests := []struct {
+		hostname string
+		expected bool
+	}{
+		{"ec2-fips.us-east-1.amazonaws.com", true},
+		{"iam-fips.amazonaws.com", true},
+		// GovCloud endpoints of some services are FIPS endpoints
+		{"sts.us-gov-west-1.amazonaws.com", true},
+		{"ec2.us-gov-west-1.amazonaws.com", true},
+		{"mybucket.s3-fips.us-east-1.amazonaws.com", true},
+		{"ec2.us-east-1.amazonaws.com", false},
+		{"ec2-fips.ap-south-1.amazonaws.com", false},
+		{"amazonaws.com", false},
+	}
+	for _, test := range tests {
+		if got := catalog.isFipsHostname(test.hostname); got != test.expected {
+			t.Errorf("isFipsHostname(%q): expected %v, got %v", test.hostname, test.expected, got)
+		}
+	}
+}
+
+func TestHasFipsEndpointForServiceRegion(t *testing.T) {
+	catalog := getEmbeddedRegionCatalog()
+	fips := awsConfig{UseFIPSEndpoint: aws.Bool(true)}
+	tests := []struct {
+		name         string
+		awsSpcConfig awsConfig
+		service      string
+		region       string
+		expected     bool
+	}{
+		{"fips endpoint", fips, "ec2", "us-east-1", true},
+		{"no fips endpoint in region", fips, "ec2", "ap-south-1", false},
+		{"govcloud service default", fips, "ec2", "us-gov-west-1", true},
+		{"global service", fips, "iam", "us-east-1", true},
+		{"global service without fips endpoint", fips, "cloudfront", "us-east-1", false},
+		{"fips disabled", awsConfig{}, "ec2", "ap-south-1", true},
+		{"custom endpoint", awsConfig{UseFIPSEndpoint: aws.Bool(true), Endpoints: map[string]string{"EC2": "https://localhost:4566"}}, "ec2", "ap-south-1", true},
+		{"endpoint url", awsConfig{UseFIPSEndpoint: aws.Bool(true), EndpointUrl: aws.String("https://localhost:4566")}, "ec2", "ap-south-1", true},
+	}
+	for _, test := range tests {
+		if got := hasFipsEndpointForServiceRegion(catalog, test.awsSpcConfig, test.service, test.region); got != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestRecordFipsSkippedRegion(t *testing.T) {
+	ignoredErrorLogs.Delete("test")
+	ctx := context.WithValue(context.Background(), context_key.Logger, hclog.NewNullLogger())
+	d := &plugin.QueryData{Connection: &plugin.Connection{Name: "test"}, Table: &plugin.Table{Name: "aws_ec2_instance"}}
+	recordFipsSkippedRegion(ctx, d, "ec2", "ap-south-1")
+
+	ignoredErrors := getIgnoredErrors("test")
+	if len(ignoredErrors) != 1 {
+		t.Fatalf("expected 1 ignored error, got %v", ignoredErrors)
+	}
+	e := ignoredErrors[0]
+	if e.TableName != "aws_ec2_instance" || e.Region != "ap-south-1" || e.ErrorCode != fipsEndpointNotAvailableErrorCode || e.Reason != ignoredErrorReasonNoFipsEndpoint {
+		t.Errorf("expected a %s error for aws_ec2_instance in ap-south-1, got %+v", fipsEndpointNotAvailableErrorCode, e)
+	}
+}
+
+// Run a request to the URL through the FIPS endpoint check.
+func runFipsEndpointCheck(t *testing.T, awsSpcConfig awsConfig, service string, url string) error {
+	stack := middleware.NewStack("test", smithyhttp.NewStackRequest)
+	if err := fipsEndpointAPIOption(getEmbeddedRegionCatalog(), awsSpcConfig)(stack); err != nil {
+		t.Fatalf("fipsEndpointAPIOption: %v", err)
+	}
+	err := stack.Initialize.Add(middleware.InitializeMiddlewareFunc("SetURL", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+		return next.HandleInitialize(awsmiddleware.SetServiceID(ctx, service), in)
+	}), middleware.Before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stack.Build.Add(middleware.BuildMiddlewareFunc("SetURL", func(ctx context.Context, in middleware.BuildInput, next middleware.BuildHandler) (middleware.BuildOutput, middleware.Metadata, error) {
+		req := in.Request.(*smithyhttp.Request)
+		var err error
+		req.URL, err = req.URL.Parse(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return next.HandleBuild(ctx, in)
+	}), middleware.After)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := middleware.DecorateHandler(middleware.HandlerFunc(func(context.Context, interface{}) (interface{}, middleware.Metadata, error) {
+		return nil, middleware.Metadata{}, nil
+	}), stack)
+	_, _, err = handler.Handle(context.Background(), nil)
+	return err
+}
+
+func TestFipsEndpointAPIOption(t *testing.T) {
+	if err := runFipsEndpointCheck(t, awsConfig{}, "EC2", "https://ec2-fips.us-east-1.amazonaws.com/"); err != nil {
+		t.Errorf("expected no error for a FIPS endpoint, got %v", err)
+	}
+
+	err := runFipsEndpointCheck(t, awsConfig{}, "EC2", "https://ec2-fips.ap-south-1.amazonaws.com/")
+	var ae smithy.APIError
+	if !errors.As(err, &ae) || ae.ErrorCode() != fipsEndpointNotAvailableErrorCode {
+		t.Errorf("expected %s error for a region without a FIPS endpoint, got %v", fipsEndpointNotAvailableErrorCode, err)
+	}
+
+	// Custom endpoints are not checked
+	awsSpcConfig := awsConfig{Endpoints: map[string]string{"ec2": "https://localhost:4566"}}
+	if err := runFipsEndpointCheck(t, awsSpcConfig, "EC2", "https://localhost:4566/"); err != nil {
+		t.Errorf("expected no error for a custom endpoint, got %v", err)
+	}
+}
+
+func TestFipsEndpointWithEndpointUrl(t *testing.T) {
+	// All calls go to endpoint_url, so FIPS hostnames are not checked
+	api := newMultiRegionStubAPI(t)
+	_, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_sns_topic",
+		Columns: []string{"topic_arn"},
+		Regions: []string{"ap-south-1"},
+		Config:  `use_fips_endpoint = true`,
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if got := listTopicsRegions(api); got != "ap-south-1" {
+		t.Errorf("expected ListTopics in ap-south-1, got %s", got)
+	}
+}
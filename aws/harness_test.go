@@ -0,0 +1,374 @@
+package aws
+
+// Offline test harness for tables
+//
+// The harness runs a query for any table in the plugin TableMap in-process,
+// through the same Execute path Steampipe uses, with the connection pointed
+// at an httptest stand-in for the AWS APIs (endpoint_url). Tests register
+// canned responses per service and operation, run the query and assert on the
+// rows and columns returned, so list, get, hydrate, pagination and ignore
+// error behaviour can be tested without an AWS account.
+//
+// Operations are identified from the request:
+// - The service is the signing name in the credential scope of the
+//   Authorization header (e.g. "sns", "ec2", "iam").
+// - JSON protocol services use the operation from the X-Amz-Target header
+//   (e.g. "ListAccounts"), and query protocol services (including EC2) use the
+//   Action form parameter.
+// - REST services (e.g. S3, Lambda) use the method and path, e.g.
+//   "GET /2015-03-31/functions/".
+//
+// sts:GetCallerIdentity and ec2:DescribeRegions (us-east-1 only) are stubbed
+// by default, since every regional query needs them. Requests without a
+// canned response fail the test.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/anywhere"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+const (
+	stubAccountId  = "123456789012"
+	stubConnection = "aws_test"
+)
+
+// stubRequest is a request received by the stand-in.
+type stubRequest struct {
+	Service   string
+	Operation string
+	// Signing region of the request.
+	Region string
+	Header http.Header
+	Path   string
+	// Form parameters for query protocol requests.
+	Params url.Values
+	Body   []byte
+}
+
+// stubResponse is a canned response.
+type stubResponse struct {
+	Status int
+	Header map[string]string
+	Body   string
+}
+
+// stubAPI is an httptest stand-in for the AWS APIs.
+type stubAPI struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu        sync.Mutex
+	handlers  map[string]func(stubRequest) stubResponse
+	requests  []stubRequest
+	unhandled []string
+}
+
+var authorizationScopeRegexp = regexp.MustCompile(`Credential=[^/]+/[^/]+/([^/]+)/([^/]+)/aws4_request`)
+
+func newStubAPI(t *testing.T) *stubAPI {
+	s := &stubAPI{t: t, handlers: map[string]func(stubRequest) stubResponse{}}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(func() {
+		s.server.Close()
+		for _, r := range s.unhandled {
+			t.Errorf("stubAPI: no canned response for %s", r)
+		}
+	})
+
+	s.on("sts", "GetCallerIdentity", queryResponse("GetCallerIdentity", `<Arn>arn:aws:iam::`+stubAccountId+`:user/test</Arn><UserId>AIDATEST</UserId><Account>`+stubAccountId+`</Account>`))
+	s.on("ec2", "DescribeRegions", ec2Response("DescribeRegions", `<regionInfo><item><regionName>us-east-1</regionName><regionEndpoint>ec2.us-east-1.amazonaws.com</regionEndpoint><optInStatus>opt-in-not-required</optInStatus></item></regionInfo>`))
+	return s
+}
+
+// Register a canned response for an operation. With several responses, each
+// call gets the next one and the last one is repeated, e.g. for pages.
+func (s *stubAPI) on(service string, operation string, responses ...stubResponse) {
+	var calls int32
+	s.onFunc(service, operation, func(stubRequest) stubResponse {
+		i := int(atomic.AddInt32(&calls, 1)) - 1
+		if i >= len(responses) {
+			i = len(responses) - 1
+		}
+		return responses[i]
+	})
+}
+
+// Register a function building the response for an operation.
+func (s *stubAPI) onFunc(service string, operation string, f func(stubRequest) stubResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[service+":"+operation] = f
+}
+
+// The requests received for an operation, in order.
+func (s *stubAPI) calls(service string, operation string) []stubRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []stubRequest
+	for _, r := range s.requests {
+		if r.Service == service && r.Operation == operation {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+func (s *stubAPI) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	req := stubRequest{Header: r.Header, Path: r.URL.Path, Body: body}
+	if m := authorizationScopeRegexp.FindStringSubmatch(r.Header.Get("Authorization")); m != nil {
+		req.Region = m[1]
+		req.Service = m[2]
+	}
+
+	var errorResponse func(int, string, string) stubResponse
+	switch {
+	case r.Header.Get("X-Amz-Target") != "":
+		target := r.Header.Get("X-Amz-Target")
+		req.Operation = target[strings.LastIndex(target, ".")+1:]
+		errorResponse = jsonErrorResponse
+	case strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded"):
+		req.Params, _ = url.ParseQuery(string(body))
+		req.Operation = req.Params.Get("Action")
+		errorResponse = queryErrorResponse
+		if req.Service == "ec2" {
+			errorResponse = ec2ErrorResponse
+		}
+	default:
+		req.Operation = r.Method + " " + r.URL.Path
+		errorResponse = restXMLErrorResponse
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	handler, ok := s.handlers[req.Service+":"+req.Operation]
+	if !ok {
+		s.unhandled = append(s.unhandled, req.Service+":"+req.Operation)
+	}
+	s.mu.Unlock()
+
+	resp := errorResponse(http.StatusNotImplemented, "StubNotImplemented", "no canned response for "+req.Service+":"+req.Operation)
+	if ok {
+		resp = handler(req)
+	}
+	for k, v := range resp.Header {
+		w.Header().Set(k, v)
+	}
+	status := resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(resp.Body))
+}
+
+// A query protocol (e.g. IAM, SNS, STS) response. The result is the XML
+// content of the <Action>Result element.
+func queryResponse(action string, result string) stubResponse {
+	return stubResponse{
+		Header: map[string]string{"Content-Type": "text/xml"},
+		Body:   fmt.Sprintf(`<%sResponse><%sResult>%s</%sResult><ResponseMetadata><RequestId>stub</RequestId></ResponseMetadata></%sResponse>`, action, action, result, action, action),
+	}
+}
+
+// An EC2 protocol response. The result is the XML content of the
+// <Action>Response element.
+func ec2Response(action string, result string) stubResponse {
+	return stubResponse{
+		Header: map[string]string{"Content-Type": "text/xml"},
+		Body:   fmt.Sprintf(`<%sResponse><requestId>stub</requestId>%s</%sResponse>`, action, result, action),
+	}
+}
+
+// A JSON protocol response.
+func jsonResponse(v interface{}) stubResponse {
+	data, _ := json.Marshal(v)
+	return stubResponse{
+		Header: map[string]string{"Content-Type": "application/x-amz-json-1.1"},
+		Body:   string(data),
+	}
+}
+
+func queryErrorResponse(status int, code string, message string) stubResponse {
+	return stubResponse{
+		Status: status,
+		Header: map[string]string{"Content-Type": "text/xml"},
+		Body:   fmt.Sprintf(`<ErrorResponse><Error><Type>Sender</Type><Code>%s</Code><Message>%s</Message></Error><RequestId>stub</RequestId></ErrorResponse>`, code, message),
+	}
+}
+
+func ec2ErrorResponse(status int, code string, message string) stubResponse {
+	return stubResponse{
+		Status: status,
+		Header: map[string]string{"Content-Type": "text/xml"},
+		Body:   fmt.Sprintf(`<Response><Errors><Error><Code>%s</Code><Message>%s</Message></Error></Errors><RequestID>stub</RequestID></Response>`, code, message),
+	}
+}
+
+func jsonErrorResponse(status int, code string, message string) stubResponse {
+	return stubResponse{
+		Status: status,
+		Header: map[string]string{"Content-Type": "application/x-amz-json-1.1", "X-Amzn-Errortype": code},
+		Body:   fmt.Sprintf(`{"__type":%q,"message":%q}`, code, message),
+	}
+}
+
+func restXMLErrorResponse(status int, code string, message string) stubResponse {
+	return stubResponse{
+		Status: status,
+		Header: map[string]string{"Content-Type": "application/xml"},
+		Body:   fmt.Sprintf(`<Error><Code>%s</Code><Message>%s</Message><RequestId>stub</RequestId></Error>`, code, message),
+	}
+}
+
+// tableQuery is a query to run against a table.
+type tableQuery struct {
+	Table string
+	// Columns to return. Defaults to all columns of the table.
+	Columns []string
+	// Equality quals, by column. Quals of JSON columns are JSON text.
+	Quals map[string]string
+	// Regions for the connection. Defaults to us-east-1.
+	Regions []string
+	// Additional connection config (HCL).
+	Config string
+}
+
+var tableQueryCallId int64
+
+// Run a query against a new plugin instance, with the connection pointed at
+// the stand-in. Returns the rows, with column values converted to Go types
+// (JSON columns are decoded).
+func runTableQuery(t *testing.T, api *stubAPI, q tableQuery) ([]map[string]interface{}, error) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	table, ok := Plugin(ctx).TableMap[q.Table]
+	if !ok {
+		t.Fatalf("table %s is not in the plugin TableMap", q.Table)
+	}
+	columns := q.Columns
+	if len(columns) == 0 {
+		for _, c := range table.Columns {
+			columns = append(columns, c.Name)
+		}
+	}
+
+	server := plugin.Server(&plugin.ServeOpts{PluginFunc: Plugin})
+
+	regions := q.Regions
+	if len(regions) == 0 {
+		regions = []string{"us-east-1"}
+	}
+	regionsConfig, _ := json.Marshal(regions)
+	config := fmt.Sprintf(`
+regions                  = %s
+access_key               = "AKIDSTUB"
+secret_key               = "stub"
+endpoint_url             = %q
+s3_force_path_style      = true
+max_error_retry_attempts = 1
+%s
+`, regionsConfig, api.server.URL, q.Config)
+	_, err := server.SetAllConnectionConfigs(&proto.SetAllConnectionConfigsRequest{
+		Configs: []*proto.ConnectionConfig{{
+			Connection:      stubConnection,
+			Plugin:          "hub.steampipe.io/plugins/turbot/aws@latest",
+			PluginShortName: "aws",
+			Config:          config,
+		}},
+		MaxCacheSizeMb: 16,
+	})
+	if err != nil {
+		t.Fatalf("SetAllConnectionConfigs: %v", err)
+	}
+
+	quals := map[string]*proto.Quals{}
+	for column, value := range q.Quals {
+		qualValue := &proto.QualValue{Value: &proto.QualValue_StringValue{StringValue: value}}
+		// Postgres passes quals of JSON columns as jsonb
+		for _, c := range table.Columns {
+			if c.Name == column && c.Type == proto.ColumnType_JSON {
+				qualValue = &proto.QualValue{Value: &proto.QualValue_JsonbValue{JsonbValue: value}}
+			}
+		}
+		quals[column] = &proto.Quals{Quals: []*proto.Qual{{
+			FieldName: column,
+			Operator:  &proto.Qual_StringValue{StringValue: "="},
+			Value:     qualValue,
+		}}}
+	}
+
+	req := &proto.ExecuteRequest{
+		Table:        q.Table,
+		QueryContext: &proto.QueryContext{Columns: columns, Quals: quals},
+		CallId:       fmt.Sprintf("test-%d", atomic.AddInt64(&tableQueryCallId, 1)),
+		ExecuteConnectionData: map[string]*proto.ExecuteConnectionData{
+			stubConnection: {CacheEnabled: false},
+		},
+	}
+	stream := anywhere.NewLocalPluginStream(ctx)
+	server.CallExecuteAsync(req, stream)
+
+	var rows []map[string]interface{}
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			return rows, err
+		}
+		if resp == nil || resp.Row == nil {
+			return rows, nil
+		}
+		row := map[string]interface{}{}
+		for name, column := range resp.Row.Columns {
+			row[name] = columnValue(column)
+		}
+		rows = append(rows, row)
+	}
+}
+
+func columnValue(c *proto.Column) interface{} {
+	switch v := c.Value.(type) {
+	case *proto.Column_DoubleValue:
+		return v.DoubleValue
+	case *proto.Column_IntValue:
+		return v.IntValue
+	case *proto.Column_StringValue:
+		return v.StringValue
+	case *proto.Column_BoolValue:
+		return v.BoolValue
+	case *proto.Column_JsonValue:
+		var res interface{}
+		if err := json.NewDecoder(bytes.NewReader(v.JsonValue)).Decode(&res); err != nil {
+			return string(v.JsonValue)
+		}
+		return res
+	case *proto.Column_TimestampValue:
+		return v.TimestampValue.AsTime()
+	case *proto.Column_IpAddrValue:
+		return v.IpAddrValue
+	case *proto.Column_CidrRangeValue:
+		return v.CidrRangeValue
+	case *proto.Column_LtreeValue:
+		return v.LtreeValue
+	}
+	return nil
+}
@@ -0,0 +1,356 @@
+package aws
+
+// HTTP cassettes
+//
+// Record mode wraps the HTTP client of a connection so every request and
+// response is written to a cassette directory, one JSON file per distinct
+// request. Replay mode serves the responses from the cassette directory
+// instead of calling AWS, so queries can be reproduced without credentials or
+// network access, e.g. to share a problem with maintainers or to write
+// deterministic tests for hydrate functions.
+//
+// Implementation notes:
+// - Cassettes never contain credentials. Authorization, session token and
+//   signature headers (and presigned query parameters) are dropped, and only
+//   the request headers needed to identify a request are kept.
+// - Credentials in response bodies (e.g. from sts:AssumeRole) are always
+//   replaced, and requests to the EC2 instance metadata service are never
+//   recorded.
+// - With redact_account_ids, every 12 digit account ID in the URL, headers and
+//   bodies is replaced with a fake account ID derived from a hash of the real
+//   one. The same account always gets the same fake ID, so ARNs and account
+//   columns stay consistent across responses.
+// - Requests are matched on method, host, path, query, X-Amz-Target and body,
+//   after sanitizing. In record mode the plugin gets the real responses (and
+//   so sends real account IDs in follow up requests, which must stay intact
+//   since they are signed), and the requests are redacted to build the key.
+//   In replay mode the plugin only ever sees the redacted responses, so its
+//   requests already carry the fake account IDs and are matched as they are.
+// - A request missing from the cassette fails immediately in replay mode and
+//   is not retried.
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+const (
+	cassetteModeRecord = "record"
+	cassetteModeReplay = "replay"
+)
+
+// Request headers kept in the cassette. Everything else (including the
+// Authorization and X-Amz-Security-Token headers) is dropped.
+var cassetteRequestHeaders = []string{"Content-Type", "X-Amz-Target"}
+
+// Response headers dropped from the cassette.
+var cassetteSkippedResponseHeaders = []string{"Set-Cookie", "X-Amz-Id-2", "X-Amz-Request-Id", "X-Amzn-Requestid", "Date"}
+
+// Query parameters used by presigned requests, dropped from the cassette.
+var cassetteSkippedQueryParams = []string{"X-Amz-Algorithm", "X-Amz-Credential", "X-Amz-Date", "X-Amz-Expires", "X-Amz-Security-Token", "X-Amz-Signature", "X-Amz-SignedHeaders"}
+
+// Runs of digits, of which the ones with exactly 12 digits are treated as
+// account IDs. Word boundaries are not enough, since account IDs in URL
+// encoded ARNs follow letters (e.g. "%3A123456789012").
+var digitsRegexp = regexp.MustCompile(`\d+`)
+
+// Credentials returned by STS, SSO and similar APIs, in XML and JSON bodies.
+var credentialsXMLRegexp = regexp.MustCompile(`(?i)(<(?:SecretAccessKey|SessionToken)>)[^<]*(</)`)
+var credentialsJSONRegexp = regexp.MustCompile(`(?i)("(?:SecretAccessKey|SessionToken|Token)"\s*:\s*")[^"]*(")`)
+
+// Hosts of the EC2 instance metadata service, which are not recorded.
+var cassetteSkippedHosts = []string{"169.254.169.254", "[fd00:ec2::254]"}
+
+// cassetteInteraction is a recorded request / response pair, as stored in the
+// cassette directory.
+type cassetteInteraction struct {
+	Request  cassetteRequest  `json:"request"`
+	Response cassetteResponse `json:"response"`
+}
+
+type cassetteRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// Bodies are stored as text when possible, so cassettes are easy to read and
+// edit. Binary bodies (e.g. S3 objects) are stored base64 encoded.
+type cassetteResponse struct {
+	StatusCode int                 `json:"status_code"`
+	Headers    map[string][]string `json:"headers,omitempty"`
+	Body       string              `json:"body,omitempty"`
+	BodyBase64 string              `json:"body_base64,omitempty"`
+}
+
+// cassetteHTTPClient records or replays the requests of a connection.
+type cassetteHTTPClient struct {
+	// The client used to send requests in record mode. Unused in replay mode.
+	client           aws.HTTPClient
+	mode             string
+	dir              string
+	redactAccountIds bool
+}
+
+// cassetteMissError is returned in replay mode for requests that are not in
+// the cassette. It is not retryable.
+type cassetteMissError struct {
+	method string
+	url    string
+	file   string
+}
+
+func (e *cassetteMissError) Error() string {
+	return fmt.Sprintf("no recorded response for %s %s (expected %s)", e.method, e.url, e.file)
+}
+
+func (e *cassetteMissError) RetryableError() bool { return false }
+
+func newCassetteHTTPClient(client aws.HTTPClient, mode string, dir string, redactAccountIds bool) (*cassetteHTTPClient, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("cassette_mode requires cassette_dir to be set in the connection config")
+	}
+	switch mode {
+	case cassetteModeRecord:
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("unable to create cassette_dir: %v", err)
+		}
+	case cassetteModeReplay:
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("unable to read cassette_dir: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("invalid value for \"cassette_mode\", it must be %q or %q", cassetteModeRecord, cassetteModeReplay)
+	}
+	return &cassetteHTTPClient{client: client, mode: mode, dir: dir, redactAccountIds: redactAccountIds}, nil
+}
+
+func (c *cassetteHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if c.mode == cassetteModeReplay {
+		recordedReq := c.sanitizeRequest(req, body, false)
+		file := filepath.Join(c.dir, cassetteInteractionKey(recordedReq)+".json")
+		return c.replay(req, recordedReq, file)
+	}
+
+	for _, host := range cassetteSkippedHosts {
+		if req.URL.Host == host || strings.HasPrefix(req.URL.Host, host+":") {
+			return c.client.Do(req)
+		}
+	}
+
+	recordedReq := c.sanitizeRequest(req, body, c.redactAccountIds)
+	file := filepath.Join(c.dir, cassetteInteractionKey(recordedReq)+".json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := cassetteInteraction{
+		Request:  recordedReq,
+		Response: c.sanitizeResponse(resp, respBody),
+	}
+	data, err := json.MarshalIndent(interaction, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(file, data, 0600); err != nil {
+		return nil, fmt.Errorf("unable to write cassette: %v", err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	return resp, nil
+}
+
+func (c *cassetteHTTPClient) replay(req *http.Request, recordedReq cassetteRequest, file string) (*http.Response, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, &cassetteMissError{method: recordedReq.Method, url: recordedReq.URL, file: file}
+		}
+		return nil, err
+	}
+	var interaction cassetteInteraction
+	if err := json.Unmarshal(data, &interaction); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %v", file, err)
+	}
+	return interaction.Response.toHTTPResponse(req), nil
+}
+
+func (c *cassetteHTTPClient) sanitizeRequest(req *http.Request, body []byte, redactAccountIds bool) cassetteRequest {
+	u := *req.URL
+	query := u.Query()
+	for _, p := range cassetteSkippedQueryParams {
+		query.Del(p)
+	}
+	u.RawQuery = query.Encode()
+
+	headers := map[string]string{}
+	for _, h := range cassetteRequestHeaders {
+		if v := req.Header.Get(h); v != "" {
+			headers[h] = v
+		}
+	}
+
+	recorded := cassetteRequest{
+		Method:  req.Method,
+		URL:     u.String(),
+		Headers: headers,
+		Body:    string(body),
+	}
+	if redactAccountIds {
+		recorded.URL = replaceAccountIds(recorded.URL)
+		recorded.Body = replaceAccountIds(recorded.Body)
+	}
+	return recorded
+}
+
+func (c *cassetteHTTPClient) sanitizeResponse(resp *http.Response, body []byte) cassetteResponse {
+	headers := map[string][]string{}
+	for k, v := range resp.Header {
+		skip := false
+		for _, h := range cassetteSkippedResponseHeaders {
+			if http.CanonicalHeaderKey(h) == k {
+				skip = true
+				break
+			}
+		}
+		if skip {
+			continue
+		}
+		values := make([]string, 0, len(v))
+		for _, value := range v {
+			values = append(values, c.redact(value))
+		}
+		headers[k] = values
+	}
+
+	recorded := cassetteResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    headers,
+	}
+	if utf8.Valid(body) {
+		recorded.Body = string(body)
+		recorded.Body = credentialsXMLRegexp.ReplaceAllString(recorded.Body, "${1}REDACTED${2}")
+		recorded.Body = credentialsJSONRegexp.ReplaceAllString(recorded.Body, "${1}REDACTED${2}")
+		recorded.Body = c.redact(recorded.Body)
+	} else {
+		recorded.BodyBase64 = base64.StdEncoding.EncodeToString(body)
+	}
+	return recorded
+}
+
+func (c *cassetteHTTPClient) redact(s string) string {
+	if !c.redactAccountIds {
+		return s
+	}
+	return replaceAccountIds(s)
+}
+
+// Replace every account ID in s with its fake account ID.
+func replaceAccountIds(s string) string {
+	return digitsRegexp.ReplaceAllStringFunc(s, func(digits string) string {
+		if len(digits) != 12 {
+			return digits
+		}
+		return redactAccountId(digits)
+	})
+}
+
+// Map an account ID to a fake one. The fake ID is derived from a hash of the
+// real one, so it is stable across recordings.
+func redactAccountId(accountId string) string {
+	hash := sha256.Sum256([]byte(accountId))
+	n := new(big.Int).SetBytes(hash[:])
+	n.Mod(n, big.NewInt(1_000_000_000_000))
+	return fmt.Sprintf("%012d", n)
+}
+
+// The file name of a request in the cassette directory.
+func cassetteInteractionKey(req cassetteRequest) string {
+	u, err := url.Parse(req.URL)
+	normalizedURL := req.URL
+	if err == nil {
+		// Sort the query parameters, so the key does not depend on their order.
+		query := u.Query()
+		keys := make([]string, 0, len(query))
+		for k := range query {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, 0, len(keys))
+		for _, k := range keys {
+			parts = append(parts, k+"="+strings.Join(query[k], ","))
+		}
+		normalizedURL = u.Scheme + "://" + u.Host + u.EscapedPath() + "?" + strings.Join(parts, "&")
+	}
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n%s\n%s", req.Method, normalizedURL, req.Headers["X-Amz-Target"], req.Body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (r cassetteResponse) toHTTPResponse(req *http.Request) *http.Response {
+	body := []byte(r.Body)
+	if r.BodyBase64 != "" {
+		// Invalid base64 is served as is, so the plugin gets a parse error
+		// for the response rather than a silently empty body.
+		if decoded, err := base64.StdEncoding.DecodeString(r.BodyBase64); err == nil {
+			body = decoded
+		} else {
+			body = []byte(r.BodyBase64)
+		}
+	}
+
+	header := http.Header{}
+	for k, v := range r.Headers {
+		header[k] = append([]string{}, v...)
+	}
+	// The body may have been rewritten by redaction (or edited by hand), so
+	// the recorded length is no longer valid.
+	header.Del("Content-Length")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,69 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCassetteRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	arn := testSnsTopicArn + "alpha"
+
+	// Record against the stand-in, redacting account IDs.
+	api := newStubAPI(t)
+	api.on("sns", "GetTopicAttributes", snsTopicAttributesResponse("alpha"))
+	recorded, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_sns_topic",
+		Columns: []string{"topic_arn", "display_name", "account_id"},
+		Quals:   map[string]string{"topic_arn": arn},
+		Config:  `cassette_mode = "record"` + "\n" + `cassette_dir = "` + dir + `"` + "\nredact_account_ids = true",
+	})
+	if err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+	if len(recorded) != 1 || recorded[0]["account_id"] != stubAccountId {
+		t.Fatalf("expected the real account ID while recording, got %v", recorded)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) == 0 {
+		t.Fatalf("no interactions recorded")
+	}
+	for _, f := range files {
+		data, _ := os.ReadFile(f)
+		if strings.Contains(string(data), stubAccountId) {
+			t.Errorf("%s contains the account ID", filepath.Base(f))
+		}
+		if strings.Contains(string(data), "AKIDSTUB") {
+			t.Errorf("%s contains the access key", filepath.Base(f))
+		}
+	}
+
+	// Replay with the same endpoint, so the requests match the recording.
+	// The plugin only knows the fake account ID, so the get qual uses it too.
+	fakeArn := strings.Replace(arn, stubAccountId, redactAccountId(stubAccountId), 1)
+	requests := len(api.requests)
+	replayed, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_sns_topic",
+		Columns: []string{"topic_arn", "display_name", "account_id"},
+		Quals:   map[string]string{"topic_arn": fakeArn},
+		Config:  `cassette_mode = "replay"` + "\n" + `cassette_dir = "` + dir + `"`,
+	})
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if len(replayed) != 1 {
+		t.Fatalf("expected 1 row, got %v", replayed)
+	}
+	if got := replayed[0]["display_name"]; got != "alpha display" {
+		t.Errorf("display_name: expected %q, got %q", "alpha display", got)
+	}
+	if got := replayed[0]["account_id"]; got != redactAccountId(stubAccountId) {
+		t.Errorf("account_id: expected the fake account ID, got %q", got)
+	}
+	if len(api.requests) != requests {
+		t.Errorf("expected no requests in replay mode, got %d", len(api.requests)-requests)
+	}
+}
@@ -0,0 +1,616 @@
+package aws
+
+// IAM policy evaluation
+//
+// Evaluates a request (principal, action, resource and request context)
+// against IAM policies locally, following the documented evaluation logic:
+// https://docs.aws.amazon.com/IAM/latest/UserGuide/reference_policies_evaluation-logic.html
+//
+//  1. An explicit deny in any policy denies the request.
+//  2. Service control policies (SCPs) must allow the request at every level
+//     of the organization, from the root to the account.
+//  3. A resource-based policy allowing the principal allows the request in
+//     the same account. Across accounts, both the resource-based policy and
+//     the identity-based policies must allow it.
+//  4. Permissions boundaries and session policies, when present, must allow
+//     the request.
+//  5. Otherwise, identity-based policies must allow the request.
+//
+// Implementation notes:
+// - Policies are in the canonical form of canonical_policy.go, so actions
+//   and condition keys are already lower case.
+// - Boundaries and session policies also limit same account resource-based
+//   policy grants. AWS skips them when the resource policy names the IAM user
+//   or role session ARN directly, so those requests may be reported as
+//   implicitly denied when AWS would allow them.
+// - Condition keys that are not in the request context make the condition
+//   false (true for negated operators, ...IfExists and ForAllValues), and
+//   are returned as missing context values, as the IAM policy simulator
+//   does.
+// - Policy variables (e.g. ${aws:username}) are replaced in resources and
+//   condition values of 2012-10-17 policies.
+
+import (
+	"math"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// Evaluation decisions, the same as the IAM policy simulator.
+const (
+	iamDecisionAllowed      = "allowed"
+	iamDecisionExplicitDeny = "explicitDeny"
+	iamDecisionImplicitDeny = "implicitDeny"
+)
+
+// Policy types of the evaluation.
+const (
+	iamPolicyTypeIdentity            = "identity"
+	iamPolicyTypeResource            = "resource"
+	iamPolicyTypePermissionsBoundary = "permissions_boundary"
+	iamPolicyTypeSession             = "session"
+	iamPolicyTypeServiceControl      = "service_control"
+)
+
+// iamEvaluationPolicy is a policy included in an evaluation.
+type iamEvaluationPolicy struct {
+	Type string
+	// ARN of managed policies, or the name of inline policies.
+	Id string
+	// ARN of the user, group, role or organization target the policy is
+	// attached to. Empty for policies given with the request.
+	Source string
+	Policy Policy
+}
+
+// iamEvaluationInput is a request to evaluate, with the policies that apply.
+type iamEvaluationInput struct {
+	// ARNs matching the principal in resource-based policies, e.g. the role
+	// session and the role it was assumed from.
+	PrincipalArns    []string
+	PrincipalAccount string
+	// Action in service:Action format, case insensitive.
+	Action   string
+	Resource string
+	// Account of the resource. Empty if the same as the principal.
+	ResourceAccount string
+	// Request context values, by lower case condition key.
+	Context map[string][]string
+
+	Identity            []iamEvaluationPolicy
+	PermissionsBoundary *iamEvaluationPolicy
+	Session             []iamEvaluationPolicy
+	ResourcePolicies    []iamEvaluationPolicy
+	// SCPs of each level of the organization, from the root to the account.
+	// Nil if SCPs don't apply to the principal.
+	ServiceControl [][]iamEvaluationPolicy
+}
+
+// iamEvaluationStatement is a statement that applies to the request.
+type iamEvaluationStatement struct {
+	PolicyType     string `json:"policy_type"`
+	PolicyId       string `json:"policy_id,omitempty"`
+	Source         string `json:"source,omitempty"`
+	StatementIndex int    `json:"statement_index"`
+	Sid            string `json:"sid,omitempty"`
+	Effect         string `json:"effect"`
+}
+
+// iamEvaluationResult is the decision for a request.
+type iamEvaluationResult struct {
+	Decision string
+	// Decision of each policy type included in the evaluation.
+	DecisionDetails      map[string]string
+	MatchedStatements    []iamEvaluationStatement
+	MissingContextValues []string
+}
+
+type iamEvaluator struct {
+	input   iamEvaluationInput
+	action  string
+	missing map[string]bool
+	matched []iamEvaluationStatement
+}
+
+// Evaluate a request against its policies.
+func evaluateIamPolicies(input iamEvaluationInput) iamEvaluationResult {
+	e := &iamEvaluator{
+		input:   input,
+		action:  strings.ToLower(input.Action),
+		missing: map[string]bool{},
+	}
+
+	details := map[string]string{}
+	details[iamPolicyTypeIdentity] = e.evaluatePolicies(input.Identity, false)
+	if input.PermissionsBoundary != nil {
+		details[iamPolicyTypePermissionsBoundary] = e.evaluatePolicies([]iamEvaluationPolicy{*input.PermissionsBoundary}, false)
+	}
+	if len(input.Session) > 0 {
+		details[iamPolicyTypeSession] = e.evaluatePolicies(input.Session, false)
+	}
+	if len(input.ResourcePolicies) > 0 {
+		details[iamPolicyTypeResource] = e.evaluatePolicies(input.ResourcePolicies, true)
+	}
+	if input.ServiceControl != nil {
+		// Every level must allow the request
+		decision := iamDecisionAllowed
+		for _, level := range input.ServiceControl {
+			switch e.evaluatePolicies(level, false) {
+			case iamDecisionExplicitDeny:
+				decision = iamDecisionExplicitDeny
+			case iamDecisionImplicitDeny:
+				if decision == iamDecisionAllowed {
+					decision = iamDecisionImplicitDeny
+				}
+			}
+		}
+		details[iamPolicyTypeServiceControl] = decision
+	}
+
+	result := iamEvaluationResult{
+		Decision:          getIamDecision(input, details),
+		DecisionDetails:   details,
+		MatchedStatements: e.matched,
+	}
+	for key := range e.missing {
+		result.MissingContextValues = append(result.MissingContextValues, key)
+	}
+	sort.Strings(result.MissingContextValues)
+	return result
+}
+
+// Combine the decisions of each policy type.
+func getIamDecision(input iamEvaluationInput, details map[string]string) string {
+	for _, decision := range details {
+		if decision == iamDecisionExplicitDeny {
+			return iamDecisionExplicitDeny
+		}
+	}
+	if d, ok := details[iamPolicyTypeServiceControl]; ok && d != iamDecisionAllowed {
+		return iamDecisionImplicitDeny
+	}
+	for _, t := range []string{iamPolicyTypePermissionsBoundary, iamPolicyTypeSession} {
+		if d, ok := details[t]; ok && d != iamDecisionAllowed {
+			return iamDecisionImplicitDeny
+		}
+	}
+
+	resourceAllowed := details[iamPolicyTypeResource] == iamDecisionAllowed
+	sameAccount := input.ResourceAccount == "" || input.ResourceAccount == input.PrincipalAccount
+	if resourceAllowed && sameAccount {
+		return iamDecisionAllowed
+	}
+	if details[iamPolicyTypeIdentity] != iamDecisionAllowed {
+		return iamDecisionImplicitDeny
+	}
+	if !sameAccount && !resourceAllowed {
+		return iamDecisionImplicitDeny
+	}
+	return iamDecisionAllowed
+}
+
+// Evaluate a set of policies of the same type.
+func (e *iamEvaluator) evaluatePolicies(policies []iamEvaluationPolicy, checkPrincipal bool) string {
+	allowed, denied := false, false
+	for _, p := range policies {
+		substitute := p.Policy.Version == "2012-10-17"
+		for i, s := range p.Policy.Statements {
+			if !e.statementApplies(s, checkPrincipal, substitute) {
+				continue
+			}
+			e.matched = append(e.matched, iamEvaluationStatement{
+				PolicyType:     p.Type,
+				PolicyId:       p.Id,
+				Source:         p.Source,
+				StatementIndex: i,
+				Sid:            s.Sid,
+				Effect:         s.Effect,
+			})
+			switch s.Effect {
+			case "Allow":
+				allowed = true
+			case "Deny":
+				denied = true
+			}
+		}
+	}
+	if denied {
+		return iamDecisionExplicitDeny
+	}
+	if allowed {
+		return iamDecisionAllowed
+	}
+	return iamDecisionImplicitDeny
+}
+
+func (e *iamEvaluator) statementApplies(s Statement, checkPrincipal bool, substitute bool) bool {
+	return e.matchActions(s) &&
+		e.matchResources(s, substitute) &&
+		(!checkPrincipal || e.matchPrincipals(s)) &&
+		e.matchConditions(s.Condition, substitute)
+}
+
+func (e *iamEvaluator) matchActions(s Statement) bool {
+	if len(s.Action) > 0 {
+		return matchIamActions(s.Action, e.action)
+	}
+	if len(s.NotAction) > 0 {
+		return !matchIamActions(s.NotAction, e.action)
+	}
+	return false
+}
+
+// Check if an action matches any of the (lower case) action patterns.
+func matchIamActions(patterns []string, action string) bool {
+	action = strings.ToLower(action)
+	for _, pattern := range patterns {
+		if matchIamWildcard(pattern, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resources are optional in some resource-based policies, e.g. trust
+// policies, and then match any resource.
+func (e *iamEvaluator) matchResources(s Statement, substitute bool) bool {
+	if len(s.Resource) > 0 {
+		return e.matchResourcePatterns(s.Resource, substitute)
+	}
+	if len(s.NotResource) > 0 {
+		return !e.matchResourcePatterns(s.NotResource, substitute)
+	}
+	return true
+}
+
+func (e *iamEvaluator) matchResourcePatterns(patterns []string, substitute bool) bool {
+	for _, pattern := range patterns {
+		if substitute {
+			var ok bool
+			if pattern, ok = e.substituteVariables(pattern); !ok {
+				continue
+			}
+		}
+		if matchIamWildcard(pattern, e.input.Resource) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *iamEvaluator) matchPrincipals(s Statement) bool {
+	if len(s.Principal) > 0 {
+		return e.matchPrincipal(s.Principal)
+	}
+	if len(s.NotPrincipal) > 0 {
+		return !e.matchPrincipal(s.NotPrincipal)
+	}
+	return false
+}
+
+func (e *iamEvaluator) matchPrincipal(principal Principal) bool {
+	for principalType, values := range principal {
+		for _, value := range getIamConditionValues(values) {
+			if value == "*" {
+				return true
+			}
+			if principalType != "AWS" {
+				// Service, Federated and CanonicalUser principals match by name
+				for _, p := range e.input.PrincipalArns {
+					if value == p {
+						return true
+					}
+				}
+				continue
+			}
+			// An account ID or root ARN matches every principal of the account
+			if value == e.input.PrincipalAccount {
+				return true
+			}
+			if a, err := arn.Parse(value); err == nil && a.Service == "iam" && a.Resource == "root" && a.AccountID == e.input.PrincipalAccount {
+				return true
+			}
+			for _, p := range e.input.PrincipalArns {
+				if value == p {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// All the operators and keys of a condition block must match.
+func (e *iamEvaluator) matchConditions(conditions map[string]interface{}, substitute bool) bool {
+	// Check every condition, so all the missing context values are found
+	matched := true
+	for operator, block := range conditions {
+		keys, ok := block.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, values := range keys {
+			if !e.matchCondition(operator, strings.ToLower(key), getIamConditionValues(values), substitute) {
+				matched = false
+			}
+		}
+	}
+	return matched
+}
+
+func (e *iamEvaluator) matchCondition(operator string, key string, policyValues []string, substitute bool) bool {
+	op := strings.ToLower(operator)
+	set := ""
+	for _, prefix := range []string{"forallvalues:", "foranyvalue:"} {
+		if strings.HasPrefix(op, prefix) {
+			set = strings.TrimSuffix(prefix, ":")
+			op = strings.TrimPrefix(op, prefix)
+		}
+	}
+	ifExists := strings.HasSuffix(op, "ifexists")
+	op = strings.TrimSuffix(op, "ifexists")
+
+	requestValues, present := e.input.Context[key]
+	if op == "null" {
+		for _, v := range policyValues {
+			if strings.EqualFold(v, "true") != present {
+				return true
+			}
+		}
+		return false
+	}
+	if !present {
+		if ifExists || set == "forallvalues" {
+			return true
+		}
+		e.missing[key] = true
+		return isNegatedIamConditionOperator(op)
+	}
+
+	if substitute {
+		var values []string
+		for _, v := range policyValues {
+			if v, ok := e.substituteVariables(v); ok {
+				values = append(values, v)
+			}
+		}
+		policyValues = values
+	}
+
+	negated := isNegatedIamConditionOperator(op)
+	matchValue := func(requestValue string) bool {
+		for _, policyValue := range policyValues {
+			if matchIamConditionValue(op, requestValue, policyValue) {
+				return !negated
+			}
+		}
+		return negated
+	}
+
+	switch set {
+	case "forallvalues":
+		for _, v := range requestValues {
+			if !matchValue(v) {
+				return false
+			}
+		}
+		return true
+	case "foranyvalue":
+		for _, v := range requestValues {
+			if matchValue(v) {
+				return true
+			}
+		}
+		return false
+	}
+	// A negated operator must not match any of the request values
+	if negated {
+		for _, v := range requestValues {
+			if !matchValue(v) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, v := range requestValues {
+		if matchValue(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func isNegatedIamConditionOperator(op string) bool {
+	return strings.Contains(op, "not")
+}
+
+// Check a request value against a policy value for an operator, ignoring
+// the negation of the operator (e.g. StringNotEquals compares as
+// StringEquals).
+func matchIamConditionValue(op string, requestValue string, policyValue string) bool {
+	switch op {
+	case "stringequals", "stringnotequals", "binaryequals":
+		return requestValue == policyValue
+	case "stringequalsignorecase", "stringnotequalsignorecase":
+		return strings.EqualFold(requestValue, policyValue)
+	case "stringlike", "stringnotlike":
+		return matchIamWildcard(policyValue, requestValue)
+	case "bool":
+		return strings.EqualFold(requestValue, policyValue)
+	case "arnequals", "arnlike", "arnnotequals", "arnnotlike":
+		return matchIamArn(policyValue, requestValue)
+	case "ipaddress", "notipaddress":
+		return matchIamIpAddress(policyValue, requestValue)
+	}
+
+	if strings.HasPrefix(op, "numeric") {
+		r, err1 := strconv.ParseFloat(requestValue, 64)
+		p, err2 := strconv.ParseFloat(policyValue, 64)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		return compareIamValues(strings.TrimPrefix(op, "numeric"), r, p)
+	}
+	if strings.HasPrefix(op, "date") {
+		r, ok1 := parseIamDate(requestValue)
+		p, ok2 := parseIamDate(policyValue)
+		if !ok1 || !ok2 {
+			return false
+		}
+		return compareIamValues(strings.TrimPrefix(op, "date"), float64(r.UnixNano()), float64(p.UnixNano()))
+	}
+
+	// Unknown operators never match
+	return false
+}
+
+func compareIamValues(comparison string, r float64, p float64) bool {
+	switch comparison {
+	case "equals", "notequals":
+		return r == p
+	case "lessthan":
+		return r < p
+	case "lessthanequals":
+		return r <= p
+	case "greaterthan":
+		return r > p
+	case "greaterthanequals":
+		return r >= p
+	}
+	return false
+}
+
+// Dates are ISO 8601 (e.g. 2024-01-01T00:00:00Z, or just the date) or epoch
+// seconds.
+func parseIamDate(value string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05Z0700", "2006-01-02T15:04Z07:00", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)), true
+	}
+	return time.Time{}, false
+}
+
+// Policy values may be a single IP address or a CIDR block.
+func matchIamIpAddress(policyValue string, requestValue string) bool {
+	ip := net.ParseIP(requestValue)
+	if ip == nil {
+		return false
+	}
+	if !strings.Contains(policyValue, "/") {
+		p := net.ParseIP(policyValue)
+		return p != nil && p.Equal(ip)
+	}
+	_, network, err := net.ParseCIDR(policyValue)
+	return err == nil && network.Contains(ip)
+}
+
+// Each of the six colon separated parts of an ARN is matched separately, so
+// wildcards don't match across parts.
+func matchIamArn(pattern string, value string) bool {
+	patternParts := strings.SplitN(pattern, ":", 6)
+	valueParts := strings.SplitN(value, ":", 6)
+	if len(patternParts) != 6 || len(valueParts) != 6 {
+		return matchIamWildcard(pattern, value)
+	}
+	for i := range patternParts {
+		if !matchIamWildcard(patternParts[i], valueParts[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Characters escaped by the ${*}, ${?} and ${$} policy variables, matched
+// literally rather than as wildcards.
+const (
+	iamLiteralStar     = '\uE000'
+	iamLiteralQuestion = '\uE001'
+)
+
+// Match a value against a pattern with * (any characters) and ? (any single
+// character) wildcards.
+func matchIamWildcard(pattern string, value string) bool {
+	p, v := []rune(pattern), []rune(value)
+	pi, vi := 0, 0
+	star, starV := -1, 0
+	for vi < len(v) {
+		switch {
+		case pi < len(p) && p[pi] == '*':
+			star, starV = pi, vi
+			pi++
+		case pi < len(p) && (p[pi] == '?' || p[pi] == v[vi] ||
+			(p[pi] == iamLiteralStar && v[vi] == '*') || (p[pi] == iamLiteralQuestion && v[vi] == '?')):
+			pi++
+			vi++
+		case star >= 0:
+			pi = star + 1
+			starV++
+			vi = starV
+		default:
+			return false
+		}
+	}
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+	return pi == len(p)
+}
+
+var iamPolicyVariableRegexp = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// Replace the policy variables in a value with request context values.
+// Returns false if a variable is not in the request context and has no
+// default, in which case the value doesn't match anything.
+func (e *iamEvaluator) substituteVariables(value string) (string, bool) {
+	ok := true
+	result := iamPolicyVariableRegexp.ReplaceAllStringFunc(value, func(match string) string {
+		variable := strings.TrimSpace(match[2 : len(match)-1])
+		switch variable {
+		case "*":
+			return string(iamLiteralStar)
+		case "?":
+			return string(iamLiteralQuestion)
+		case "$":
+			return "$"
+		}
+
+		key, defaultValue, hasDefault := strings.Cut(variable, ",")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if values, present := e.input.Context[key]; present && len(values) > 0 {
+			return values[0]
+		}
+		if hasDefault {
+			return strings.Trim(strings.TrimSpace(defaultValue), "'")
+		}
+		e.missing[key] = true
+		ok = false
+		return match
+	})
+	return result, ok
+}
+
+// Condition values and principals are []string in canonical policies, but
+// may also be decoded from JSON.
+func getIamConditionValues(values interface{}) []string {
+	switch v := values.(type) {
+	case []string:
+		return v
+	case string:
+		return []string{v}
+	case []interface{}:
+		s, _ := toSliceOfStrings(v)
+		return s
+	}
+	return nil
+}
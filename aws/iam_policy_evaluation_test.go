@@ -0,0 +1,266 @@
+package aws
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchIamWildcard(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		value    string
+		expected bool
+	}{
+		{"*", "anything", true},
+		{"s3:get*", "s3:getobject", true},
+		{"s3:get*", "s3:putobject", false},
+		{"s3:?etobject", "s3:getobject", true},
+		{"arn:aws:s3:::bucket/*", "arn:aws:s3:::bucket/a/b", true},
+		{"arn:aws:s3:::bucket/*/b", "arn:aws:s3:::bucket/a/c", false},
+		{"a*b*c", "aXXbYYc", true},
+		{"a*b*c", "aXXbYY", false},
+		{"abc", "abcd", false},
+		{"", "", true},
+	}
+	for _, test := range tests {
+		if got := matchIamWildcard(test.pattern, test.value); got != test.expected {
+			t.Errorf("matchIamWildcard(%q, %q): expected %v, got %v", test.pattern, test.value, test.expected, got)
+		}
+	}
+}
+
+// Parse a policy with a single statement.
+func testIamPolicy(t *testing.T, policyType string, statements string) iamEvaluationPolicy {
+	t.Helper()
+	policy, err := canonicalPolicy(`{"Version": "2012-10-17", "Statement": [` + statements + `]}`)
+	if err != nil {
+		t.Fatalf("canonicalPolicy: %v", err)
+	}
+	return iamEvaluationPolicy{Type: policyType, Id: policyType, Policy: policy.(Policy)}
+}
+
+func TestEvaluateIamPolicies(t *testing.T) {
+	const (
+		principal = "arn:aws:iam::111111111111:user/alice"
+		object    = "arn:aws:s3:::data/alice/report.csv"
+	)
+	allowS3 := `{"Effect": "Allow", "Action": "S3:Get*", "Resource": "arn:aws:s3:::data/*"}`
+
+	tests := []struct {
+		name     string
+		action   string
+		resource string
+		context  map[string][]string
+		// Policies by type, built with testIamPolicy
+		identity        string
+		boundary        string
+		session         string
+		resourcePolicy  string
+		resourceAccount string
+		serviceControl  []string
+		expected        string
+		missing         string
+	}{
+		{name: "identity allow", action: "s3:GetObject", identity: allowS3, expected: iamDecisionAllowed},
+		{name: "no policies", action: "s3:GetObject", expected: iamDecisionImplicitDeny},
+		{name: "other action", action: "s3:PutObject", identity: allowS3, expected: iamDecisionImplicitDeny},
+		{name: "other resource", action: "s3:GetObject", resource: "arn:aws:s3:::logs/a", identity: allowS3, expected: iamDecisionImplicitDeny},
+		{
+			name:     "explicit deny",
+			action:   "s3:GetObject",
+			identity: allowS3 + `, {"Effect": "Deny", "Action": "s3:*", "Resource": "*"}`,
+			expected: iamDecisionExplicitDeny,
+		},
+		{
+			name:     "not action",
+			action:   "s3:GetObject",
+			identity: `{"Effect": "Allow", "NotAction": "iam:*", "Resource": "*"}`,
+			expected: iamDecisionAllowed,
+		},
+		{
+			name:     "not action excluded",
+			action:   "iam:CreateUser",
+			identity: `{"Effect": "Allow", "NotAction": "iam:*", "Resource": "*"}`,
+			expected: iamDecisionImplicitDeny,
+		},
+		{
+			name:     "not resource deny",
+			action:   "s3:GetObject",
+			identity: allowS3 + `, {"Effect": "Deny", "Action": "s3:*", "NotResource": "arn:aws:s3:::data/bob/*"}`,
+			expected: iamDecisionExplicitDeny,
+		},
+		{
+			name:     "policy variable",
+			action:   "s3:GetObject",
+			context:  map[string][]string{"aws:username": {"alice"}},
+			identity: `{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::data/${aws:username}/*"}`,
+			expected: iamDecisionAllowed,
+		},
+		{
+			name:     "missing policy variable",
+			action:   "s3:GetObject",
+			identity: `{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::data/${aws:username}/*"}`,
+			expected: iamDecisionImplicitDeny,
+			missing:  "aws:username",
+		},
+		{
+			name:     "permissions boundary",
+			action:   "s3:GetObject",
+			identity: allowS3,
+			boundary: `{"Effect": "Allow", "Action": "ec2:*", "Resource": "*"}`,
+			expected: iamDecisionImplicitDeny,
+		},
+		{
+			name:     "session policy",
+			action:   "s3:GetObject",
+			identity: allowS3,
+			session:  `{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "*"}`,
+			expected: iamDecisionAllowed,
+		},
+		{
+			name:           "scp allowed at every level",
+			action:         "s3:GetObject",
+			identity:       allowS3,
+			serviceControl: []string{`{"Effect": "Allow", "Action": "*", "Resource": "*"}`, `{"Effect": "Allow", "Action": "s3:*", "Resource": "*"}`},
+			expected:       iamDecisionAllowed,
+		},
+		{
+			name:           "scp not allowed at a level",
+			action:         "s3:GetObject",
+			identity:       allowS3,
+			serviceControl: []string{`{"Effect": "Allow", "Action": "*", "Resource": "*"}`, `{"Effect": "Allow", "Action": "ec2:*", "Resource": "*"}`},
+			expected:       iamDecisionImplicitDeny,
+		},
+		{
+			name:           "resource policy in the same account",
+			action:         "s3:GetObject",
+			resourcePolicy: `{"Effect": "Allow", "Principal": {"AWS": "111111111111"}, "Action": "s3:GetObject", "Resource": "arn:aws:s3:::data/*"}`,
+			expected:       iamDecisionAllowed,
+		},
+		{
+			name:            "resource policy across accounts",
+			action:          "s3:GetObject",
+			resourcePolicy:  `{"Effect": "Allow", "Principal": {"AWS": "arn:aws:iam::111111111111:user/alice"}, "Action": "s3:GetObject", "Resource": "*"}`,
+			resourceAccount: "222222222222",
+			expected:        iamDecisionImplicitDeny,
+		},
+		{
+			name:            "resource and identity policy across accounts",
+			action:          "s3:GetObject",
+			identity:        allowS3,
+			resourcePolicy:  `{"Effect": "Allow", "Principal": {"AWS": "arn:aws:iam::111111111111:root"}, "Action": "s3:GetObject", "Resource": "*"}`,
+			resourceAccount: "222222222222",
+			expected:        iamDecisionAllowed,
+		},
+		{
+			name:           "resource policy for another principal",
+			action:         "s3:GetObject",
+			resourcePolicy: `{"Effect": "Allow", "Principal": {"AWS": "arn:aws:iam::111111111111:user/bob"}, "Action": "s3:GetObject", "Resource": "*"}`,
+			expected:       iamDecisionImplicitDeny,
+		},
+		{
+			name:           "resource policy not principal deny",
+			action:         "s3:GetObject",
+			identity:       allowS3,
+			resourcePolicy: `{"Effect": "Deny", "NotPrincipal": {"AWS": "arn:aws:iam::111111111111:user/bob"}, "Action": "s3:*", "Resource": "*"}`,
+			expected:       iamDecisionExplicitDeny,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := iamEvaluationInput{
+				PrincipalArns:    []string{principal},
+				PrincipalAccount: "111111111111",
+				Action:           test.action,
+				Resource:         test.resource,
+				ResourceAccount:  test.resourceAccount,
+				Context:          test.context,
+			}
+			if input.Resource == "" {
+				input.Resource = object
+			}
+			if test.identity != "" {
+				input.Identity = []iamEvaluationPolicy{testIamPolicy(t, iamPolicyTypeIdentity, test.identity)}
+			}
+			if test.boundary != "" {
+				boundary := testIamPolicy(t, iamPolicyTypePermissionsBoundary, test.boundary)
+				input.PermissionsBoundary = &boundary
+			}
+			if test.session != "" {
+				input.Session = []iamEvaluationPolicy{testIamPolicy(t, iamPolicyTypeSession, test.session)}
+			}
+			if test.resourcePolicy != "" {
+				input.ResourcePolicies = []iamEvaluationPolicy{testIamPolicy(t, iamPolicyTypeResource, test.resourcePolicy)}
+			}
+			for _, scp := range test.serviceControl {
+				input.ServiceControl = append(input.ServiceControl, []iamEvaluationPolicy{testIamPolicy(t, iamPolicyTypeServiceControl, scp)})
+			}
+
+			result := evaluateIamPolicies(input)
+			if result.Decision != test.expected {
+				t.Errorf("expected %s, got %s (details %v)", test.expected, result.Decision, result.DecisionDetails)
+			}
+			if got := strings.Join(result.MissingContextValues, ","); got != test.missing {
+				t.Errorf("expected missing context values %q, got %q", test.missing, got)
+			}
+		})
+	}
+}
+
+func TestEvaluateIamConditions(t *testing.T) {
+	tests := []struct {
+		condition string
+		context   map[string][]string
+		expected  bool
+		missing   string
+	}{
+		{`{"StringEquals": {"aws:PrincipalTag/team": "data"}}`, map[string][]string{"aws:principaltag/team": {"data"}}, true, ""},
+		{`{"StringEquals": {"aws:PrincipalTag/team": "data"}}`, map[string][]string{"aws:principaltag/team": {"Data"}}, false, ""},
+		{`{"StringEqualsIgnoreCase": {"aws:PrincipalTag/team": "data"}}`, map[string][]string{"aws:principaltag/team": {"Data"}}, true, ""},
+		{`{"StringEquals": {"aws:PrincipalTag/team": "data"}}`, nil, false, "aws:principaltag/team"},
+		{`{"StringNotEquals": {"aws:PrincipalTag/team": "data"}}`, nil, true, "aws:principaltag/team"},
+		{`{"StringNotEquals": {"aws:PrincipalTag/team": ["data", "ml"]}}`, map[string][]string{"aws:principaltag/team": {"ml"}}, false, ""},
+		{`{"StringEqualsIfExists": {"aws:PrincipalTag/team": "data"}}`, nil, true, ""},
+		{`{"StringLike": {"s3:prefix": "home/*"}}`, map[string][]string{"s3:prefix": {"home/alice"}}, true, ""},
+		{`{"StringNotLike": {"s3:prefix": "home/*"}}`, map[string][]string{"s3:prefix": {"home/alice"}}, false, ""},
+		{`{"Bool": {"aws:MultiFactorAuthPresent": true}}`, map[string][]string{"aws:multifactorauthpresent": {"true"}}, true, ""},
+		{`{"Bool": {"aws:SecureTransport": "false"}}`, map[string][]string{"aws:securetransport": {"true"}}, false, ""},
+		{`{"NumericLessThan": {"aws:MultiFactorAuthAge": 3600}}`, map[string][]string{"aws:multifactorauthage": {"600"}}, true, ""},
+		{`{"NumericGreaterThanEquals": {"s3:max-keys": "10"}}`, map[string][]string{"s3:max-keys": {"9"}}, false, ""},
+		{`{"DateGreaterThan": {"aws:CurrentTime": "2024-01-01T00:00:00Z"}}`, map[string][]string{"aws:currenttime": {"2024-06-01T12:00:00Z"}}, true, ""},
+		{`{"DateLessThan": {"aws:CurrentTime": "2024-01-01"}}`, map[string][]string{"aws:currenttime": {"1717243200"}}, false, ""},
+		{`{"IpAddress": {"aws:SourceIp": ["10.0.0.0/8", "192.0.2.1"]}}`, map[string][]string{"aws:sourceip": {"10.1.2.3"}}, true, ""},
+		{`{"IpAddress": {"aws:SourceIp": "192.0.2.1"}}`, map[string][]string{"aws:sourceip": {"192.0.2.1"}}, true, ""},
+		{`{"NotIpAddress": {"aws:SourceIp": "10.0.0.0/8"}}`, map[string][]string{"aws:sourceip": {"10.1.2.3"}}, false, ""},
+		{`{"ArnLike": {"aws:SourceArn": "arn:aws:sns:*:111111111111:*"}}`, map[string][]string{"aws:sourcearn": {"arn:aws:sns:us-east-1:111111111111:topic"}}, true, ""},
+		{`{"ArnLike": {"aws:SourceArn": "arn:aws:sns:*:111111111111"}}`, map[string][]string{"aws:sourcearn": {"arn:aws:sns:us-east-1:111111111111:topic"}}, false, ""},
+		{`{"ForAllValues:StringEquals": {"aws:TagKeys": ["env", "team"]}}`, map[string][]string{"aws:tagkeys": {"env"}}, true, ""},
+		{`{"ForAllValues:StringEquals": {"aws:TagKeys": ["env", "team"]}}`, map[string][]string{"aws:tagkeys": {"env", "owner"}}, false, ""},
+		{`{"ForAllValues:StringEquals": {"aws:TagKeys": ["env"]}}`, nil, true, ""},
+		{`{"ForAnyValue:StringEquals": {"aws:TagKeys": ["env"]}}`, map[string][]string{"aws:tagkeys": {"owner", "env"}}, true, ""},
+		{`{"ForAnyValue:StringEquals": {"aws:TagKeys": ["env"]}}`, nil, false, "aws:tagkeys"},
+		{`{"Null": {"aws:TokenIssueTime": "true"}}`, nil, true, ""},
+		{`{"Null": {"aws:TokenIssueTime": "false"}}`, nil, false, ""},
+		{`{"StringEquals": {"s3:prefix": "${aws:username}/"}}`, map[string][]string{"s3:prefix": {"alice/"}, "aws:username": {"alice"}}, true, ""},
+		{`{"StringEquals": {"aws:PrincipalTag/team": "data", "aws:RequestedRegion": "eu-west-1"}}`, map[string][]string{"aws:principaltag/team": {"data"}}, false, "aws:requestedregion"},
+	}
+
+	for _, test := range tests {
+		identity := testIamPolicy(t, iamPolicyTypeIdentity, `{"Effect": "Allow", "Action": "*", "Resource": "*", "Condition": `+test.condition+`}`)
+		result := evaluateIamPolicies(iamEvaluationInput{
+			PrincipalArns:    []string{"arn:aws:iam::111111111111:user/alice"},
+			PrincipalAccount: "111111111111",
+			Action:           "s3:ListBucket",
+			Resource:         "arn:aws:s3:::data",
+			Context:          test.context,
+			Identity:         []iamEvaluationPolicy{identity},
+		})
+		if got := result.Decision == iamDecisionAllowed; got != test.expected {
+			t.Errorf("%s with %v: expected %v, got %v", test.condition, test.context, test.expected, got)
+		}
+		if got := strings.Join(result.MissingContextValues, ","); got != test.missing {
+			t.Errorf("%s with %v: expected missing context values %q, got %q", test.condition, test.context, test.missing, got)
+		}
+	}
+}
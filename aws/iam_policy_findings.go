@@ -0,0 +1,408 @@
+package aws
+
+// IAM policy findings
+//
+// Security checks for IAM policies in canonical form, for managed, inline,
+// trust and resource-based policies alike. Checks only look at the policy
+// document, so they don't know what the policy is attached to: e.g. a
+// wildcard principal is only reported for policies with a Principal element.
+//
+// Implementation notes:
+// - Actions are matched with the same wildcard rules as policy evaluation,
+//   including NotAction.
+// - Privilege escalation checks look for actions (or combinations of
+//   actions) that let a principal grant itself more permissions, whatever the
+//   statements that allow them. Actions denied on all resources without
+//   conditions are left out. Findings for actions allowed by a single
+//   statement have its index and Sid.
+// - Sids are not used in policy evaluation, nor referenced by anything else,
+//   so there is no way to tell from the document if a Sid is used. Unused Sids
+//   are read as Sids that don't identify their statement: generated
+//   placeholders (placeholder_sid) and Sids shared by several statements
+//   (duplicate_sid).
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Severities of findings.
+const (
+	iamFindingSeverityHigh   = "high"
+	iamFindingSeverityMedium = "medium"
+	iamFindingSeverityLow    = "low"
+)
+
+// iamPolicyFinding is an issue found in a policy by a check.
+type iamPolicyFinding struct {
+	CheckId     string
+	Severity    string
+	Title       string
+	Description string
+	Remediation string
+	// Nil for findings about the whole policy.
+	StatementIndex *int
+	Sid            string
+	// Actions involved in the finding, e.g. a privilege escalation.
+	Actions []string
+}
+
+type iamPolicyCheck struct {
+	Id          string
+	Severity    string
+	Title       string
+	Remediation string
+	check       func(policy Policy) []iamPolicyFinding
+}
+
+// iamPolicyChecks is the library of checks, in the order they run.
+var iamPolicyChecks = []iamPolicyCheck{
+	{
+		Id:          "full_admin",
+		Severity:    iamFindingSeverityHigh,
+		Title:       "Statement allows all actions on all resources",
+		Remediation: "Grant only the actions and resources needed, or use an AWS managed job function policy.",
+		check:       checkIamPolicyFullAdmin,
+	},
+	{
+		Id:          "pass_role_all_resources",
+		Severity:    iamFindingSeverityHigh,
+		Title:       "Statement allows iam:PassRole on all resources",
+		Remediation: "Restrict the Resource element to the ARNs of the roles to pass, and add an iam:PassedToService condition.",
+		check:       checkIamPolicyPassRoleAllResources,
+	},
+	{
+		Id:          "privilege_escalation",
+		Severity:    iamFindingSeverityHigh,
+		Title:       "Policy allows actions that can escalate privileges",
+		Remediation: "Remove the actions, restrict them to specific resources, or add a permissions boundary to the principals of the policy.",
+		check:       checkIamPolicyPrivilegeEscalation,
+	},
+	{
+		Id:          "wildcard_principal_without_condition",
+		Severity:    iamFindingSeverityHigh,
+		Title:       "Statement allows any principal without a condition",
+		Remediation: "Name the principals in the Principal element, or add a condition such as aws:PrincipalOrgID or aws:SourceAccount.",
+		check:       checkIamPolicyWildcardPrincipal,
+	},
+	{
+		Id:          "allow_not_principal",
+		Severity:    iamFindingSeverityHigh,
+		Title:       "Statement allows NotPrincipal",
+		Remediation: "Use Principal with the principals to allow instead of NotPrincipal.",
+		check:       checkIamPolicyAllowNotPrincipal,
+	},
+	{
+		Id:          "allow_not_action",
+		Severity:    iamFindingSeverityMedium,
+		Title:       "Statement allows NotAction",
+		Remediation: "Use Action with the actions to allow, since NotAction also allows new actions of every service.",
+		check:       checkIamPolicyAllowNotAction,
+	},
+	{
+		Id:          "allow_not_resource",
+		Severity:    iamFindingSeverityMedium,
+		Title:       "Statement allows NotResource",
+		Remediation: "Use Resource with the resources to allow, since NotResource also allows new resources.",
+		check:       checkIamPolicyAllowNotResource,
+	},
+	{
+		Id:          "duplicate_sid",
+		Severity:    iamFindingSeverityLow,
+		Title:       "Sid is used by more than one statement",
+		Remediation: "Give each statement a unique Sid. Resource-based policies of some services reject duplicate Sids.",
+		check:       checkIamPolicyDuplicateSid,
+	},
+	{
+		Id:          "placeholder_sid",
+		Severity:    iamFindingSeverityLow,
+		Title:       "Sid is a generated placeholder",
+		Remediation: "Replace the Sid with a name that describes the purpose of the statement, or remove it.",
+		check:       checkIamPolicyPlaceholderSid,
+	},
+	{
+		Id:          "deprecated_version",
+		Severity:    iamFindingSeverityLow,
+		Title:       "Policy uses the 2008-10-17 version",
+		Remediation: `Set "Version": "2012-10-17". The 2008-10-17 version, also used when Version is missing, doesn't support policy variables.`,
+		check:       checkIamPolicyDeprecatedVersion,
+	},
+}
+
+// Check a policy with all the checks of the library.
+func getIamPolicyFindings(policy Policy) []iamPolicyFinding {
+	var findings []iamPolicyFinding
+	for _, c := range iamPolicyChecks {
+		for _, finding := range c.check(policy) {
+			finding.CheckId = c.Id
+			finding.Title = c.Title
+			finding.Remediation = c.Remediation
+			finding.Severity = c.Severity
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+//// CHECKS
+
+func checkIamPolicyFullAdmin(policy Policy) []iamPolicyFinding {
+	return getIamStatementFindings(policy, func(s Statement) string {
+		if s.Effect == "Allow" && isIamAllActions(s) && isIamAllResources(s) {
+			return "The statement grants full administrator access."
+		}
+		return ""
+	})
+}
+
+func checkIamPolicyPassRoleAllResources(policy Policy) []iamPolicyFinding {
+	return getIamStatementFindings(policy, func(s Statement) string {
+		if s.Effect != "Allow" || isIamAllActions(s) || !isIamAllResources(s) || !matchIamStatementAction(s, "iam:passrole") {
+			return ""
+		}
+		if hasIamConditionKey(s, "iam:passedtoservice") {
+			return ""
+		}
+		return "The statement allows passing any role, including roles with more permissions, to any service."
+	})
+}
+
+// Actions, or combinations of actions, that allow a principal to escalate its
+// privileges, e.g. by attaching policies to itself or passing a role to a
+// service it controls.
+var iamPrivilegeEscalations = [][]string{
+	{"iam:createpolicyversion"},
+	{"iam:setdefaultpolicyversion"},
+	{"iam:createaccesskey"},
+	{"iam:createloginprofile"},
+	{"iam:updateloginprofile"},
+	{"iam:attachuserpolicy"},
+	{"iam:attachgrouppolicy"},
+	{"iam:attachrolepolicy"},
+	{"iam:putuserpolicy"},
+	{"iam:putgrouppolicy"},
+	{"iam:putrolepolicy"},
+	{"iam:addusertogroup"},
+	{"iam:updateassumerolepolicy", "sts:assumerole"},
+	{"iam:passrole", "ec2:runinstances"},
+	{"iam:passrole", "lambda:createfunction", "lambda:invokefunction"},
+	{"iam:passrole", "lambda:createfunction", "lambda:createeventsourcemapping"},
+	{"lambda:updatefunctioncode"},
+	{"iam:passrole", "glue:createdevendpoint"},
+	{"glue:updatedevendpoint"},
+	{"iam:passrole", "cloudformation:createstack"},
+	{"iam:passrole", "datapipeline:createpipeline", "datapipeline:putpipelinedefinition"},
+}
+
+func checkIamPolicyPrivilegeEscalation(policy Policy) []iamPolicyFinding {
+	// Full administrator access is already reported
+	for _, s := range policy.Statements {
+		if s.Effect == "Allow" && isIamAllActions(s) && isIamAllResources(s) {
+			return nil
+		}
+	}
+
+	var findings []iamPolicyFinding
+	for _, actions := range iamPrivilegeEscalations {
+		statements := map[int]bool{}
+		allowed := true
+		for _, action := range actions {
+			indexes := getIamStatementsAllowingAction(policy, action)
+			if len(indexes) == 0 {
+				allowed = false
+				break
+			}
+			for _, i := range indexes {
+				statements[i] = true
+			}
+		}
+		if !allowed {
+			continue
+		}
+
+		var indexes []int
+		for i := range statements {
+			indexes = append(indexes, i)
+		}
+		sort.Ints(indexes)
+		finding := iamPolicyFinding{
+			Description: fmt.Sprintf("Statements %s allow %s.", strings.Trim(fmt.Sprint(indexes), "[]"), strings.Join(actions, ", ")),
+			Actions:     actions,
+		}
+		if len(indexes) == 1 {
+			index := indexes[0]
+			finding.Description = fmt.Sprintf("Statement %d allows %s.", index, strings.Join(actions, ", "))
+			finding.StatementIndex = &index
+			finding.Sid = policy.Statements[index].Sid
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+func checkIamPolicyWildcardPrincipal(policy Policy) []iamPolicyFinding {
+	return getIamStatementFindings(policy, func(s Statement) string {
+		if s.Effect != "Allow" || len(s.Condition) > 0 {
+			return ""
+		}
+		for principalType, values := range s.Principal {
+			for _, value := range getIamConditionValues(values) {
+				if value == "*" {
+					return fmt.Sprintf("The statement allows any %s principal, including principals of other accounts.", principalType)
+				}
+			}
+		}
+		return ""
+	})
+}
+
+func checkIamPolicyAllowNotPrincipal(policy Policy) []iamPolicyFinding {
+	return getIamStatementFindings(policy, func(s Statement) string {
+		if s.Effect == "Allow" && len(s.NotPrincipal) > 0 {
+			return "The statement allows every principal not in the NotPrincipal element, including principals of other accounts."
+		}
+		return ""
+	})
+}
+
+func checkIamPolicyAllowNotAction(policy Policy) []iamPolicyFinding {
+	return getIamStatementFindings(policy, func(s Statement) string {
+		if s.Effect == "Allow" && len(s.NotAction) > 0 {
+			return fmt.Sprintf("The statement allows every action except %s.", strings.Join(s.NotAction, ", "))
+		}
+		return ""
+	})
+}
+
+func checkIamPolicyAllowNotResource(policy Policy) []iamPolicyFinding {
+	return getIamStatementFindings(policy, func(s Statement) string {
+		if s.Effect == "Allow" && len(s.NotResource) > 0 {
+			return fmt.Sprintf("The statement allows every resource except %s.", strings.Join(s.NotResource, ", "))
+		}
+		return ""
+	})
+}
+
+func checkIamPolicyDuplicateSid(policy Policy) []iamPolicyFinding {
+	var findings []iamPolicyFinding
+	first := map[string]int{}
+	for i, s := range policy.Statements {
+		if s.Sid == "" {
+			continue
+		}
+		j, ok := first[s.Sid]
+		if !ok {
+			first[s.Sid] = i
+			continue
+		}
+		index := i
+		findings = append(findings, iamPolicyFinding{
+			Description:    fmt.Sprintf("Statement %d has the same Sid.", j),
+			StatementIndex: &index,
+			Sid:            s.Sid,
+		})
+	}
+	return findings
+}
+
+var iamPlaceholderSidRegexp = regexp.MustCompile(`^(VisualEditor|Stmt)\d*$`)
+
+func checkIamPolicyPlaceholderSid(policy Policy) []iamPolicyFinding {
+	return getIamStatementFindings(policy, func(s Statement) string {
+		if iamPlaceholderSidRegexp.MatchString(s.Sid) {
+			return fmt.Sprintf("The Sid %s was generated by the policy editor or generator.", s.Sid)
+		}
+		return ""
+	})
+}
+
+func checkIamPolicyDeprecatedVersion(policy Policy) []iamPolicyFinding {
+	switch policy.Version {
+	case "2008-10-17":
+		return []iamPolicyFinding{{Description: "The policy version is 2008-10-17."}}
+	case "":
+		return []iamPolicyFinding{{Description: "The policy has no Version, and defaults to 2008-10-17."}}
+	}
+	return nil
+}
+
+//// UTILITY FUNCTIONS
+
+// Run a check on each statement of a policy, with a finding for each
+// statement the check returns a description for.
+func getIamStatementFindings(policy Policy, check func(s Statement) string) []iamPolicyFinding {
+	var findings []iamPolicyFinding
+	for i, s := range policy.Statements {
+		description := check(s)
+		if description == "" {
+			continue
+		}
+		index := i
+		findings = append(findings, iamPolicyFinding{
+			Description:    description,
+			StatementIndex: &index,
+			Sid:            s.Sid,
+		})
+	}
+	return findings
+}
+
+// Get the indexes of the Allow statements that apply to an action, unless a
+// Deny statement denies it on all resources without conditions.
+func getIamStatementsAllowingAction(policy Policy, action string) []int {
+	var indexes []int
+	for i, s := range policy.Statements {
+		if !matchIamStatementAction(s, action) {
+			continue
+		}
+		if s.Effect == "Deny" && isIamAllResources(s) && len(s.Condition) == 0 {
+			return nil
+		}
+		if s.Effect == "Allow" {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// Check if the Action or NotAction element of a statement applies to an action.
+func matchIamStatementAction(s Statement, action string) bool {
+	_, ok := getIamStatementActionPattern(s, action)
+	return ok
+}
+
+func isIamAllActions(s Statement) bool {
+	for _, action := range s.Action {
+		if action == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// NotResource statements apply to all resources but the ones listed.
+func isIamAllResources(s Statement) bool {
+	if len(s.NotResource) > 0 {
+		return true
+	}
+	for _, resource := range s.Resource {
+		if resource == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Condition keys are lower case in canonical form.
+func hasIamConditionKey(s Statement, key string) bool {
+	for _, values := range s.Condition {
+		if keys, ok := values.(map[string]interface{}); ok {
+			if _, ok := keys[key]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
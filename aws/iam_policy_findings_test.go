@@ -0,0 +1,131 @@
+package aws
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestGetIamPolicyFindings(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		expected []string
+	}{
+		{
+			"least privilege",
+			`{"Version": "2012-10-17", "Statement": {"Sid": "ReadData", "Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::data/*"}}`,
+			nil,
+		},
+		{
+			"full admin",
+			`{"Version": "2012-10-17", "Statement": {"Effect": "Allow", "Action": "*", "Resource": "*"}}`,
+			[]string{"full_admin high 0"},
+		},
+		{
+			"pass role",
+			`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": "iam:PassRole", "Resource": "*"}, {"Effect": "Allow", "Action": "iam:Pass*", "Resource": "*", "Condition": {"StringEquals": {"iam:PassedToService": "ec2.amazonaws.com"}}}, {"Effect": "Allow", "Action": "iam:PassRole", "Resource": "arn:aws:iam::123456789012:role/app"}]}`,
+			[]string{"pass_role_all_resources high 0"},
+		},
+		{
+			"privilege escalation across statements",
+			`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": "ec2:RunInstances", "Resource": "*"}, {"Effect": "Allow", "Action": "iam:PassRole", "Resource": "arn:aws:iam::123456789012:role/admin"}, {"Effect": "Allow", "Action": "iam:Attach*Policy", "Resource": "*"}]}`,
+			[]string{"privilege_escalation high 2 iam:attachuserpolicy", "privilege_escalation high 2 iam:attachgrouppolicy", "privilege_escalation high 2 iam:attachrolepolicy", "privilege_escalation high - iam:passrole ec2:runinstances"},
+		},
+		{
+			"privilege escalation denied",
+			`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": ["iam:PutUserPolicy", "iam:PutRolePolicy"], "Resource": "arn:aws:iam::123456789012:role/app"}, {"Effect": "Deny", "Action": "iam:PutUser*", "Resource": "*"}]}`,
+			[]string{"privilege_escalation high 0 iam:putrolepolicy"},
+		},
+		{
+			"wildcard principal",
+			`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::data/*"}, {"Effect": "Allow", "Principal": {"AWS": "*"}, "Action": "s3:GetObject", "Resource": "arn:aws:s3:::data/*", "Condition": {"StringEquals": {"aws:PrincipalOrgID": "o-stub"}}}, {"Effect": "Deny", "Principal": "*", "Action": "s3:*", "Resource": "arn:aws:s3:::data/*"}]}`,
+			[]string{"wildcard_principal_without_condition high 0"},
+		},
+		{
+			"not elements",
+			`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "NotAction": "iam:*", "Resource": "arn:aws:s3:::data"}, {"Effect": "Allow", "NotPrincipal": {"AWS": "arn:aws:iam::123456789012:root"}, "Action": "s3:GetObject", "NotResource": "arn:aws:s3:::data/private/*"}, {"Effect": "Deny", "NotAction": "s3:*", "Resource": "*"}]}`,
+			[]string{"allow_not_principal high 1", "allow_not_action medium 0", "allow_not_resource medium 1"},
+		},
+		{
+			"sids",
+			`{"Version": "2012-10-17", "Statement": [{"Sid": "VisualEditor0", "Effect": "Allow", "Action": "s3:ListBucket", "Resource": "arn:aws:s3:::data"}, {"Sid": "Data", "Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::data/*"}, {"Sid": "Data", "Effect": "Allow", "Action": "s3:PutObject", "Resource": "arn:aws:s3:::data/*"}]}`,
+			[]string{"duplicate_sid low 2", "placeholder_sid low 0"},
+		},
+		{
+			// Sids are unused if they don't identify their statement
+			"unused sids",
+			`{"Version": "2012-10-17", "Statement": [{"Sid": "Stmt1700000000000", "Effect": "Allow", "Action": "s3:ListBucket", "Resource": "arn:aws:s3:::data"}, {"Sid": "ReadData", "Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::data/*"}, {"Effect": "Allow", "Action": "s3:PutObject", "Resource": "arn:aws:s3:::data/*"}, {"Effect": "Deny", "Action": "s3:DeleteObject", "Resource": "arn:aws:s3:::data/*"}, {"Sid": "Stmt1700000000000", "Effect": "Allow", "Action": "s3:GetBucketLocation", "Resource": "arn:aws:s3:::data"}]}`,
+			[]string{"duplicate_sid low 4", "placeholder_sid low 0", "placeholder_sid low 4"},
+		},
+		{
+			"deprecated version",
+			`{"Version": "2008-10-17", "Statement": {"Sid": "ReadData", "Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::data/*"}}`,
+			[]string{"deprecated_version low -"},
+		},
+		{
+			"missing version",
+			`{"Statement": {"Sid": "ReadData", "Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::data/*"}}`,
+			[]string{"deprecated_version low -"},
+		},
+	}
+	for _, test := range tests {
+		policy, err := canonicalPolicy(test.policy)
+		if err != nil {
+			t.Fatalf("%s: invalid policy: %v", test.name, err)
+		}
+		var actual []string
+		for _, finding := range getIamPolicyFindings(policy.(Policy)) {
+			index := "-"
+			if finding.StatementIndex != nil {
+				index = fmt.Sprint(*finding.StatementIndex)
+			}
+			f := fmt.Sprintf("%s %s %s", finding.CheckId, finding.Severity, index)
+			for _, action := range finding.Actions {
+				f += " " + action
+			}
+			actual = append(actual, f)
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestAwsIamPolicyFinding(t *testing.T) {
+	api := newStubAPI(t)
+	rows, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_iam_policy_finding",
+		Columns: []string{"check_id", "severity", "title", "description", "remediation", "statement_index", "sid", "actions"},
+		Quals: map[string]string{
+			"policy": `{"Version": "2012-10-17", "Statement": [{"Sid": "Stmt1", "Effect": "Allow", "Action": ["iam:PassRole", "lambda:CreateFunction", "lambda:InvokeFunction"], "Resource": "*"}]}`,
+		},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	findings := map[string]map[string]interface{}{}
+	for _, row := range rows {
+		findings[row["check_id"].(string)] = row
+	}
+	if len(rows) != 3 || findings["pass_role_all_resources"] == nil || findings["privilege_escalation"] == nil || findings["placeholder_sid"] == nil {
+		t.Fatalf("unexpected findings %v", rows)
+	}
+	escalation := findings["privilege_escalation"]
+	expected := map[string]interface{}{
+		"severity":        iamFindingSeverityHigh,
+		"description":     "Statement 0 allows iam:passrole, lambda:createfunction, lambda:invokefunction.",
+		"statement_index": int64(0),
+		"sid":             "Stmt1",
+		"actions":         []interface{}{"iam:passrole", "lambda:createfunction", "lambda:invokefunction"},
+	}
+	for column, value := range expected {
+		if !reflect.DeepEqual(escalation[column], value) {
+			t.Errorf("expected %s %v, got %v", column, value, escalation[column])
+		}
+	}
+	if findings["placeholder_sid"]["statement_index"] != int64(0) || findings["placeholder_sid"]["sid"] != "Stmt1" || findings["placeholder_sid"]["remediation"] == nil {
+		t.Errorf("unexpected finding %v", findings["placeholder_sid"])
+	}
+}
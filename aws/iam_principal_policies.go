@@ -0,0 +1,389 @@
+package aws
+
+// IAM principal policies
+//
+// Loads the policies that apply to an IAM user or role, for offline policy
+// evaluation: the inline and managed policies of the principal (and of the
+// groups of a user), its permissions boundary, and the service control
+// policies (SCPs) of its account.
+//
+// Implementation notes:
+// - Role sessions (arn:aws:sts::<account>:assumed-role/<role>/<session>) use
+//   the policies of their role.
+// - Only principals in the account of the connection can be loaded, since
+//   their policies are read with the IAM API of the connection.
+// - SCPs can only be read from the management account, or a delegated
+//   administrator for Organizations. Other accounts can't tell which SCPs
+//   apply, and SCPs are reported as not evaluated.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	organizationsTypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/aws/smithy-go"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// iamPrincipalPolicies are the policies of an IAM user or role.
+type iamPrincipalPolicies struct {
+	// ARNs identifying the principal in resource-based policies.
+	PrincipalArns []string
+	// user, role or assumed-role.
+	PrincipalType string
+	AccountId     string
+	// Name of the user or role.
+	Name                string
+	Identity            []iamEvaluationPolicy
+	PermissionsBoundary *iamEvaluationPolicy
+}
+
+// iamPolicyLoader loads IAM policies, reusing the documents of managed
+// policies attached more than once (e.g. to a user and its group).
+type iamPolicyLoader struct {
+	svc             *iam.Client
+	managedPolicies map[string]*Policy
+}
+
+// Returns nil if IAM is not available in the partition of the connection.
+func newIamPolicyLoader(ctx context.Context, d *plugin.QueryData) (*iamPolicyLoader, error) {
+	svc, err := IAMClient(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	if svc == nil {
+		return nil, nil
+	}
+	return &iamPolicyLoader{svc: svc, managedPolicies: map[string]*Policy{}}, nil
+}
+
+// Load the policies of a user, role or role session ARN.
+func (l *iamPolicyLoader) getPrincipalPolicies(ctx context.Context, principalArn string) (*iamPrincipalPolicies, error) {
+	a, err := arn.Parse(principalArn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid principal ARN %q: %v", principalArn, err)
+	}
+
+	parts := strings.Split(a.Resource, "/")
+	switch {
+	case a.Service == "iam" && parts[0] == "user":
+		return l.getUserPolicies(ctx, a, parts[len(parts)-1])
+	case a.Service == "iam" && parts[0] == "role":
+		return l.getRolePolicies(ctx, a, parts[len(parts)-1], principalArn)
+	case a.Service == "sts" && parts[0] == "assumed-role" && len(parts) == 3:
+		return l.getRolePolicies(ctx, a, parts[1], principalArn)
+	}
+	return nil, fmt.Errorf("unsupported principal ARN %q, it must be an IAM user, IAM role or role session ARN", principalArn)
+}
+
+func (l *iamPolicyLoader) getUserPolicies(ctx context.Context, a arn.ARN, userName string) (*iamPrincipalPolicies, error) {
+	user, err := l.svc.GetUser(ctx, &iam.GetUserInput{UserName: aws.String(userName)})
+	if err != nil {
+		return nil, err
+	}
+	userArn := aws.ToString(user.User.Arn)
+	p := &iamPrincipalPolicies{
+		PrincipalArns: []string{userArn},
+		PrincipalType: "user",
+		AccountId:     a.AccountID,
+		Name:          userName,
+	}
+	if user.User.PermissionsBoundary != nil {
+		if p.PermissionsBoundary, err = l.getPermissionsBoundary(ctx, aws.ToString(user.User.PermissionsBoundary.PermissionsBoundaryArn), userArn); err != nil {
+			return nil, err
+		}
+	}
+
+	// Inline policies
+	inlinePaginator := iam.NewListUserPoliciesPaginator(l.svc, &iam.ListUserPoliciesInput{UserName: aws.String(userName)})
+	for inlinePaginator.HasMorePages() {
+		output, err := inlinePaginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range output.PolicyNames {
+			policy, err := l.svc.GetUserPolicy(ctx, &iam.GetUserPolicyInput{UserName: aws.String(userName), PolicyName: aws.String(name)})
+			if err != nil {
+				return nil, err
+			}
+			if err := p.addInlinePolicy(name, userArn, policy.PolicyDocument); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Managed policies
+	attachedPaginator := iam.NewListAttachedUserPoliciesPaginator(l.svc, &iam.ListAttachedUserPoliciesInput{UserName: aws.String(userName)})
+	for attachedPaginator.HasMorePages() {
+		output, err := attachedPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, attached := range output.AttachedPolicies {
+			if err := l.addManagedPolicy(ctx, p, aws.ToString(attached.PolicyArn), userArn); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Policies of the groups of the user
+	groupsPaginator := iam.NewListGroupsForUserPaginator(l.svc, &iam.ListGroupsForUserInput{UserName: aws.String(userName)})
+	for groupsPaginator.HasMorePages() {
+		output, err := groupsPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range output.Groups {
+			if err := l.addGroupPolicies(ctx, p, aws.ToString(group.GroupName), aws.ToString(group.Arn)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return p, nil
+}
+
+func (l *iamPolicyLoader) addGroupPolicies(ctx context.Context, p *iamPrincipalPolicies, groupName string, groupArn string) error {
+	inlinePaginator := iam.NewListGroupPoliciesPaginator(l.svc, &iam.ListGroupPoliciesInput{GroupName: aws.String(groupName)})
+	for inlinePaginator.HasMorePages() {
+		output, err := inlinePaginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, name := range output.PolicyNames {
+			policy, err := l.svc.GetGroupPolicy(ctx, &iam.GetGroupPolicyInput{GroupName: aws.String(groupName), PolicyName: aws.String(name)})
+			if err != nil {
+				return err
+			}
+			if err := p.addInlinePolicy(name, groupArn, policy.PolicyDocument); err != nil {
+				return err
+			}
+		}
+	}
+
+	attachedPaginator := iam.NewListAttachedGroupPoliciesPaginator(l.svc, &iam.ListAttachedGroupPoliciesInput{GroupName: aws.String(groupName)})
+	for attachedPaginator.HasMorePages() {
+		output, err := attachedPaginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, attached := range output.AttachedPolicies {
+			if err := l.addManagedPolicy(ctx, p, aws.ToString(attached.PolicyArn), groupArn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (l *iamPolicyLoader) getRolePolicies(ctx context.Context, a arn.ARN, roleName string, principalArn string) (*iamPrincipalPolicies, error) {
+	role, err := l.svc.GetRole(ctx, &iam.GetRoleInput{RoleName: aws.String(roleName)})
+	if err != nil {
+		return nil, err
+	}
+	roleArn := aws.ToString(role.Role.Arn)
+	p := &iamPrincipalPolicies{
+		PrincipalArns: []string{roleArn},
+		PrincipalType: "role",
+		AccountId:     a.AccountID,
+		Name:          roleName,
+	}
+	if a.Service == "sts" {
+		p.PrincipalArns = []string{principalArn, roleArn}
+		p.PrincipalType = "assumed-role"
+	}
+	if role.Role.PermissionsBoundary != nil {
+		if p.PermissionsBoundary, err = l.getPermissionsBoundary(ctx, aws.ToString(role.Role.PermissionsBoundary.PermissionsBoundaryArn), roleArn); err != nil {
+			return nil, err
+		}
+	}
+
+	inlinePaginator := iam.NewListRolePoliciesPaginator(l.svc, &iam.ListRolePoliciesInput{RoleName: aws.String(roleName)})
+	for inlinePaginator.HasMorePages() {
+		output, err := inlinePaginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range output.PolicyNames {
+			policy, err := l.svc.GetRolePolicy(ctx, &iam.GetRolePolicyInput{RoleName: aws.String(roleName), PolicyName: aws.String(name)})
+			if err != nil {
+				return nil, err
+			}
+			if err := p.addInlinePolicy(name, roleArn, policy.PolicyDocument); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	attachedPaginator := iam.NewListAttachedRolePoliciesPaginator(l.svc, &iam.ListAttachedRolePoliciesInput{RoleName: aws.String(roleName)})
+	for attachedPaginator.HasMorePages() {
+		output, err := attachedPaginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, attached := range output.AttachedPolicies {
+			if err := l.addManagedPolicy(ctx, p, aws.ToString(attached.PolicyArn), roleArn); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return p, nil
+}
+
+func (p *iamPrincipalPolicies) addInlinePolicy(name string, source string, document *string) error {
+	policy, err := decodeIamPolicyDocument(aws.ToString(document))
+	if err != nil {
+		return fmt.Errorf("inline policy %s of %s: %v", name, source, err)
+	}
+	p.Identity = append(p.Identity, iamEvaluationPolicy{Type: iamPolicyTypeIdentity, Id: name, Source: source, Policy: *policy})
+	return nil
+}
+
+func (l *iamPolicyLoader) addManagedPolicy(ctx context.Context, p *iamPrincipalPolicies, policyArn string, source string) error {
+	policy, err := l.getManagedPolicy(ctx, policyArn)
+	if err != nil {
+		return err
+	}
+	p.Identity = append(p.Identity, iamEvaluationPolicy{Type: iamPolicyTypeIdentity, Id: policyArn, Source: source, Policy: *policy})
+	return nil
+}
+
+func (l *iamPolicyLoader) getPermissionsBoundary(ctx context.Context, policyArn string, source string) (*iamEvaluationPolicy, error) {
+	policy, err := l.getManagedPolicy(ctx, policyArn)
+	if err != nil {
+		return nil, err
+	}
+	return &iamEvaluationPolicy{Type: iamPolicyTypePermissionsBoundary, Id: policyArn, Source: source, Policy: *policy}, nil
+}
+
+// Get the default version of a managed policy.
+func (l *iamPolicyLoader) getManagedPolicy(ctx context.Context, policyArn string) (*Policy, error) {
+	if policy, ok := l.managedPolicies[policyArn]; ok {
+		return policy, nil
+	}
+	output, err := l.svc.GetPolicy(ctx, &iam.GetPolicyInput{PolicyArn: aws.String(policyArn)})
+	if err != nil {
+		return nil, err
+	}
+	version, err := l.svc.GetPolicyVersion(ctx, &iam.GetPolicyVersionInput{PolicyArn: aws.String(policyArn), VersionId: output.Policy.DefaultVersionId})
+	if err != nil {
+		return nil, err
+	}
+	policy, err := decodeIamPolicyDocument(aws.ToString(version.PolicyVersion.Document))
+	if err != nil {
+		return nil, fmt.Errorf("managed policy %s: %v", policyArn, err)
+	}
+	l.managedPolicies[policyArn] = policy
+	return policy, nil
+}
+
+// IAM returns policy documents URL encoded.
+func decodeIamPolicyDocument(document string) (*Policy, error) {
+	decoded, err := url.QueryUnescape(document)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := canonicalPolicy(decoded)
+	if err != nil {
+		return nil, err
+	}
+	p := policy.(Policy)
+	return &p, nil
+}
+
+// Get the SCPs that apply to an account, for each level of the organization
+// from the root to the account. Returns nil if SCPs don't apply to the
+// account (it's not in an organization, or is the management account), and
+// false if the SCPs can't be read with the credentials of the connection.
+func getIamServiceControlPolicies(ctx context.Context, d *plugin.QueryData, accountId string) ([][]iamEvaluationPolicy, bool, error) {
+	svc, err := OrganizationClient(ctx, d)
+	if err != nil {
+		return nil, false, err
+	}
+	// Organizations is not available in the partition, so there are no SCPs
+	if svc == nil {
+		return nil, true, nil
+	}
+
+	org, err := svc.DescribeOrganization(ctx, &organizations.DescribeOrganizationInput{})
+	if err != nil {
+		return getIamServiceControlPoliciesError(ctx, err)
+	}
+	if aws.ToString(org.Organization.MasterAccountId) == accountId || org.Organization.FeatureSet != organizationsTypes.OrganizationFeatureSetAll {
+		return nil, true, nil
+	}
+
+	// Find the targets from the account up to the root
+	targets := []string{accountId}
+	for child := accountId; ; {
+		output, err := svc.ListParents(ctx, &organizations.ListParentsInput{ChildId: aws.String(child)})
+		if err != nil {
+			return getIamServiceControlPoliciesError(ctx, err)
+		}
+		if len(output.Parents) == 0 {
+			break
+		}
+		child = aws.ToString(output.Parents[0].Id)
+		targets = append([]string{child}, targets...)
+		if output.Parents[0].Type == organizationsTypes.ParentTypeRoot {
+			break
+		}
+	}
+
+	documents := map[string]*Policy{}
+	var levels [][]iamEvaluationPolicy
+	for _, target := range targets {
+		var level []iamEvaluationPolicy
+		paginator := organizations.NewListPoliciesForTargetPaginator(svc, &organizations.ListPoliciesForTargetInput{
+			TargetId: aws.String(target),
+			Filter:   organizationsTypes.PolicyTypeServiceControlPolicy,
+		})
+		for paginator.HasMorePages() {
+			output, err := paginator.NextPage(ctx)
+			if err != nil {
+				return getIamServiceControlPoliciesError(ctx, err)
+			}
+			for _, summary := range output.Policies {
+				id := aws.ToString(summary.Id)
+				if _, ok := documents[id]; !ok {
+					policy, err := svc.DescribePolicy(ctx, &organizations.DescribePolicyInput{PolicyId: summary.Id})
+					if err != nil {
+						return getIamServiceControlPoliciesError(ctx, err)
+					}
+					p, err := canonicalPolicy(aws.ToString(policy.Policy.Content))
+					if err != nil {
+						return nil, false, fmt.Errorf("service control policy %s: %v", id, err)
+					}
+					document := p.(Policy)
+					documents[id] = &document
+				}
+				level = append(level, iamEvaluationPolicy{Type: iamPolicyTypeServiceControl, Id: aws.ToString(summary.Arn), Source: target, Policy: *documents[id]})
+			}
+		}
+		levels = append(levels, level)
+	}
+	return levels, true, nil
+}
+
+func getIamServiceControlPoliciesError(ctx context.Context, err error) ([][]iamEvaluationPolicy, bool, error) {
+	var ae smithy.APIError
+	if errors.As(err, &ae) {
+		switch {
+		case ae.ErrorCode() == "AWSOrganizationsNotInUseException":
+			return nil, true, nil
+		case strings.HasPrefix(ae.ErrorCode(), "AccessDenied"):
+			plugin.Logger(ctx).Debug("getIamServiceControlPolicies", "status", "not_evaluated", "error", err)
+			return nil, false, nil
+		}
+	}
+	plugin.Logger(ctx).Error("getIamServiceControlPolicies", "api_error", err)
+	return nil, false, err
+}
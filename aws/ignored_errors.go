@@ -0,0 +1,148 @@
+package aws
+
+// Ignored errors
+//
+// Errors matched by the ignore configs (the not found error codes of a table,
+// and the ignore_error_codes of the connection) return no data instead of
+// failing the query. Regions skipped by a table because the service has no
+// FIPS endpoint in them (with use_fips_endpoint) are recorded the same way. Each ignored error is recorded in memory with its table,
+// region, hydrate function and error code, and is exposed by the
+// aws_ignored_error table. With log_ignored_errors, each one is also logged
+// at WARN.
+//
+// Implementation notes:
+// - The SDK does not pass the hydrate function to the ignore predicates. The
+//   service and operation of the failed call are taken from the error, and
+//   matched with the service and action tags of the hydrate functions of the
+//   table.
+// - Steampipe doesn't expose an ID of the query to plugins, so errors are
+//   not grouped by query. The errors of a query are the ones recorded for its
+//   tables since it started (see the time column).
+// - Only the last ignoredErrorsMaxRecords errors of a connection are kept.
+// - Queries served from the Steampipe cache make no API calls, and record no
+//   errors.
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+const ignoredErrorsMaxRecords = 10000
+
+// Why an error was ignored.
+const (
+	ignoredErrorReasonNotFound         = "not_found"
+	ignoredErrorReasonIgnoreErrorCodes = "ignore_error_codes"
+	ignoredErrorReasonNoFipsEndpoint   = "no_fips_endpoint"
+)
+
+type ignoredError struct {
+	TableName       string
+	Region          string
+	AccountId       string
+	HydrateFunction string
+	Service         string
+	Operation       string
+	ErrorCode       string
+	ErrorMessage    string
+	Reason          string
+	Time            time.Time
+}
+
+// ignoredErrorLog is the ignored errors of a connection, oldest first.
+type ignoredErrorLog struct {
+	mu     sync.Mutex
+	errors []ignoredError
+}
+
+// Ignored errors by connection name, for the life of the plugin process.
+var ignoredErrorLogs sync.Map
+
+func getIgnoredErrorLog(connectionName string) *ignoredErrorLog {
+	if i, ok := ignoredErrorLogs.Load(connectionName); ok {
+		return i.(*ignoredErrorLog)
+	}
+	i, _ := ignoredErrorLogs.LoadOrStore(connectionName, &ignoredErrorLog{})
+	return i.(*ignoredErrorLog)
+}
+
+func (l *ignoredErrorLog) add(e ignoredError) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.errors) >= ignoredErrorsMaxRecords {
+		l.errors = l.errors[1:]
+	}
+	l.errors = append(l.errors, e)
+}
+
+// Get a snapshot of the ignored errors of a connection, oldest first.
+func getIgnoredErrors(connectionName string) []ignoredError {
+	l := getIgnoredErrorLog(connectionName)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]ignoredError(nil), l.errors...)
+}
+
+// Record an error ignored by an ignore config of the table or connection.
+func recordIgnoredError(ctx context.Context, d *plugin.QueryData, err error, reason string) {
+	e := ignoredError{
+		Region:       d.EqualsQualString(matrixKeyRegion),
+		AccountId:    getMatrixItemAccountId(ctx, d),
+		Reason:       reason,
+		ErrorMessage: err.Error(),
+		Time:         time.Now(),
+	}
+	if d.Table != nil {
+		e.TableName = d.Table.Name
+	}
+	var ae smithy.APIError
+	if errors.As(err, &ae) {
+		e.ErrorCode = ae.ErrorCode()
+		e.ErrorMessage = ae.ErrorMessage()
+	}
+	var oe *smithy.OperationError
+	if errors.As(err, &oe) {
+		e.Service = oe.ServiceID
+		e.Operation = oe.OperationName
+		if d.Table != nil {
+			functions := getTableHydrateFunctionsByTags(d.Table)[hydrateTagsKey(getServiceTagForServiceID(oe.ServiceID), oe.OperationName)]
+			e.HydrateFunction = strings.Join(functions, ",")
+		}
+	}
+
+	addIgnoredError(ctx, d, e, err)
+}
+
+// Record a region skipped by the table because the service has no FIPS
+// endpoint in it. This is recorded when the region matrix is built, so once
+// per query rather than per API call.
+func recordFipsSkippedRegion(ctx context.Context, d *plugin.QueryData, serviceID string, region string) {
+	err := newFipsEndpointNotAvailableError(serviceID, region)
+	e := ignoredError{
+		Region:       region,
+		ErrorCode:    err.Code,
+		ErrorMessage: err.Message,
+		Reason:       ignoredErrorReasonNoFipsEndpoint,
+		Time:         time.Now(),
+	}
+	if d.Table != nil {
+		e.TableName = d.Table.Name
+	}
+	addIgnoredError(ctx, d, e, err)
+}
+
+func addIgnoredError(ctx context.Context, d *plugin.QueryData, e ignoredError, err error) {
+	getIgnoredErrorLog(d.Connection.Name).add(e)
+
+	awsSpcConfig := GetConfig(d.Connection)
+	if awsSpcConfig.LogIgnoredErrors != nil && *awsSpcConfig.LogIgnoredErrors {
+		plugin.Logger(ctx).Warn("recordIgnoredError", "connection_name", d.Connection.Name, "table", e.TableName, "region", e.Region, "hydrate_function", e.HydrateFunction, "error_code", e.ErrorCode, "reason", e.Reason, "error", err)
+	}
+}
@@ -0,0 +1,233 @@
+package aws
+
+// Metadata disk cache
+//
+// The caller identity (and so the partition and account), the region list of
+// the account and the default region of a connection are looked up again
+// after every plugin restart. With metadata_cache_ttl set, these lookups are
+// also saved to disk, so a restarted plugin reuses them until they expire.
+//
+// Implementation notes:
+// - Entries are keyed by a hash of the connection name, its config, the AWS_*
+//   environment variables, the modification time of the shared AWS config
+//   and credentials files and the access key ID of long-term credentials.
+//   Changing the credentials of a connection (in any of these places) changes
+//   the key, so old entries are never used.
+// - Temporary credentials (assumed roles, SSO, instance profiles) get a new
+//   access key ID each session, so they are identified by the settings they
+//   are resolved from rather than by the key. A credential_process or
+//   instance role that starts returning credentials for another identity,
+//   with the same settings, keeps the old entries until they expire.
+// - The files contain account IDs and ARNs, but no credentials, and are only
+//   readable by the current user.
+// - The disk cache is best effort. Errors reading or writing it are logged,
+//   and the lookup is made as if there was no cache.
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+type metadataCacheEntry struct {
+	Expires time.Time       `json:"expires"`
+	Value   json.RawMessage `json:"value"`
+}
+
+// Get the metadata cache TTL of the connection, zero if disabled.
+func getMetadataCacheTTL(awsSpcConfig awsConfig) time.Duration {
+	if awsSpcConfig.MetadataCacheTTL == nil || *awsSpcConfig.MetadataCacheTTL <= 0 {
+		return 0
+	}
+	return time.Duration(*awsSpcConfig.MetadataCacheTTL) * time.Second
+}
+
+// Get the metadata cache directory of the connection, by default in the user
+// cache directory.
+func getMetadataCacheDir(awsSpcConfig awsConfig) (string, error) {
+	if awsSpcConfig.MetadataCacheDir != nil && *awsSpcConfig.MetadataCacheDir != "" {
+		return *awsSpcConfig.MetadataCacheDir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "steampipe-plugin-aws", "metadata"), nil
+}
+
+// Build the identity of the connection credentials, used as the cache key.
+func getMetadataCacheIdentity(connectionName string, awsSpcConfig awsConfig, accessKeyId string) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "connection=%s\n", connectionName)
+	fmt.Fprintf(h, "access_key_id=%s\n", accessKeyId)
+
+	configJSON, err := json.Marshal(awsSpcConfig)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(h, "config=%s\n", configJSON)
+
+	var env []string
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, "AWS_") {
+			env = append(env, kv)
+		}
+	}
+	sort.Strings(env)
+	for _, kv := range env {
+		fmt.Fprintf(h, "env=%s\n", kv)
+	}
+
+	sharedFiles := []string{config.DefaultSharedConfigFilename(), config.DefaultSharedCredentialsFilename()}
+	if f := os.Getenv("AWS_CONFIG_FILE"); f != "" {
+		sharedFiles = append(sharedFiles, f)
+	}
+	if f := os.Getenv("AWS_SHARED_CREDENTIALS_FILE"); f != "" {
+		sharedFiles = append(sharedFiles, f)
+	}
+	for _, f := range sharedFiles {
+		if info, err := os.Stat(f); err == nil {
+			fmt.Fprintf(h, "file=%s %d %d\n", f, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Get the access key ID of the connection credentials if they are long-term,
+// or "" for temporary credentials. Metadata lookups always use the management
+// credentials of the connection.
+func getMetadataCacheAccessKeyId(ctx context.Context, d *plugin.QueryData) (string, error) {
+	ctx = managementAccountContext(ctx)
+	cfg, err := getBaseClientForAccount(ctx, d)
+	if err != nil {
+		return "", err
+	}
+	if cfg.Credentials == nil {
+		return "", nil
+	}
+	creds, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return "", err
+	}
+	if creds.CanExpire {
+		return "", nil
+	}
+	return creds.AccessKeyID, nil
+}
+
+// Get the path of the cache file for a lookup, or "" if the metadata cache
+// is disabled for the connection.
+func getMetadataCachePath(ctx context.Context, d *plugin.QueryData, name string) (string, error) {
+	awsSpcConfig := GetConfig(d.Connection)
+	if getMetadataCacheTTL(awsSpcConfig) == 0 {
+		return "", nil
+	}
+	dir, err := getMetadataCacheDir(awsSpcConfig)
+	if err != nil {
+		return "", err
+	}
+	accessKeyId, err := getMetadataCacheAccessKeyId(ctx, d)
+	if err != nil {
+		return "", err
+	}
+	identity, err := getMetadataCacheIdentity(d.Connection.Name, awsSpcConfig, accessKeyId)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s-%s.json", identity, name)), nil
+}
+
+// Read a lookup from the metadata cache into v. Returns false if the
+// metadata cache is disabled, or the lookup is not cached or has expired.
+func readMetadataCache(ctx context.Context, d *plugin.QueryData, name string, v interface{}) bool {
+	path, err := getMetadataCachePath(ctx, d, name)
+	if err != nil {
+		plugin.Logger(ctx).Warn("readMetadataCache", "connection_name", d.Connection.Name, "name", name, "path_error", err)
+		return false
+	}
+	if path == "" {
+		return false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			plugin.Logger(ctx).Warn("readMetadataCache", "connection_name", d.Connection.Name, "name", name, "read_error", err)
+		}
+		return false
+	}
+	var entry metadataCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		plugin.Logger(ctx).Warn("readMetadataCache", "connection_name", d.Connection.Name, "name", name, "decode_error", err)
+		return false
+	}
+	if time.Now().After(entry.Expires) {
+		plugin.Logger(ctx).Debug("readMetadataCache", "connection_name", d.Connection.Name, "name", name, "status", "expired")
+		return false
+	}
+	if err := json.Unmarshal(entry.Value, v); err != nil {
+		plugin.Logger(ctx).Warn("readMetadataCache", "connection_name", d.Connection.Name, "name", name, "decode_error", err)
+		return false
+	}
+
+	plugin.Logger(ctx).Debug("readMetadataCache", "connection_name", d.Connection.Name, "name", name, "status", "hit")
+	return true
+}
+
+// Write a lookup to the metadata cache, if enabled for the connection.
+func writeMetadataCache(ctx context.Context, d *plugin.QueryData, name string, v interface{}) {
+	path, err := getMetadataCachePath(ctx, d, name)
+	if err != nil {
+		plugin.Logger(ctx).Warn("writeMetadataCache", "connection_name", d.Connection.Name, "name", name, "path_error", err)
+		return
+	}
+	if path == "" {
+		return
+	}
+
+	if err := writeMetadataCacheFile(path, getMetadataCacheTTL(GetConfig(d.Connection)), v); err != nil {
+		plugin.Logger(ctx).Warn("writeMetadataCache", "connection_name", d.Connection.Name, "name", name, "write_error", err)
+	}
+}
+
+func writeMetadataCacheFile(path string, ttl time.Duration, v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(metadataCacheEntry{Expires: time.Now().Add(ttl), Value: value})
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	// Write to a temporary file and rename it, so concurrent plugin processes
+	// never read a partial file
+	f, err := os.CreateTemp(dir, filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
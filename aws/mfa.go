@@ -0,0 +1,140 @@
+package aws
+
+// MFA credentials
+//
+// Roles (and users) whose policies require MFA need a token code from the
+// MFA device of the user when the session is created. Steampipe can't prompt
+// for it, so mfa_serial is combined with either:
+// - mfa_token_command, a shell command printing the current token code (e.g.
+//   from a password manager or a desktop prompt).
+// - mfa_token_file, a file containing the current token code.
+//
+// With role_arn (or assume_role_chain), the first role is assumed with the
+// MFA device. Otherwise, sts:GetSessionToken creates an MFA session for the
+// credentials of the connection.
+//
+// Implementation notes:
+// - The session is kept in a credentials cache in the memoized base client,
+//   so the token is only read when the session expires, not per query.
+// - Token codes can only be used once, and a session lasts at most 36 hours
+//   (12 hours by default) for GetSessionToken, or the duration_seconds of the
+//   role, so long running services will need a new token regularly.
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// Allow time for commands that wait for the user, e.g. a desktop prompt.
+const mfaTokenCommandTimeout = 2 * time.Minute
+
+// Get a function returning the current MFA token code, from mfa_token_command
+// or mfa_token_file. It's called each time a new session is created.
+func getMFATokenProvider(awsSpcConfig awsConfig) func() (string, error) {
+	return func() (string, error) {
+		var token []byte
+		if awsSpcConfig.MFATokenCommand != nil {
+			output, err := runMFATokenCommand(*awsSpcConfig.MFATokenCommand)
+			if err != nil {
+				return "", err
+			}
+			token = output
+		} else if awsSpcConfig.MFATokenFile != nil {
+			content, err := os.ReadFile(*awsSpcConfig.MFATokenFile)
+			if err != nil {
+				return "", fmt.Errorf("failed to read mfa_token_file: %w", err)
+			}
+			token = content
+		}
+
+		code := strings.TrimSpace(string(token))
+		if code == "" {
+			return "", fmt.Errorf("no MFA token code found, mfa_token_command or mfa_token_file must return the current code of %s", aws.ToString(awsSpcConfig.MFASerial))
+		}
+		return code, nil
+	}
+}
+
+func runMFATokenCommand(command string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), mfaTokenCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd.exe", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("mfa_token_command failed: %w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("mfa_token_command failed: %w", err)
+	}
+	return output, nil
+}
+
+// MFASessionCredentialsProvider retrieves MFA session credentials from
+// sts:GetSessionToken. It should be wrapped in an aws.CredentialsCache, since
+// every call to Retrieve uses a new token code.
+type MFASessionCredentialsProvider struct {
+	client        *sts.Client
+	serialNumber  string
+	tokenProvider func() (string, error)
+	duration      time.Duration
+}
+
+func (p *MFASessionCredentialsProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	code, err := p.tokenProvider()
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	input := &sts.GetSessionTokenInput{
+		SerialNumber: aws.String(p.serialNumber),
+		TokenCode:    aws.String(code),
+	}
+	if p.duration > 0 {
+		input.DurationSeconds = aws.Int32(int32(p.duration / time.Second))
+	}
+	output, err := p.client.GetSessionToken(ctx, input)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	return aws.Credentials{
+		AccessKeyID:     aws.ToString(output.Credentials.AccessKeyId),
+		SecretAccessKey: aws.ToString(output.Credentials.SecretAccessKey),
+		SessionToken:    aws.ToString(output.Credentials.SessionToken),
+		Source:          "MFASessionCredentialsProvider",
+		CanExpire:       true,
+		Expires:         aws.ToTime(output.Credentials.Expiration),
+	}, nil
+}
+
+// Build a credentials provider for an MFA session of the credentials already
+// resolved in cfg.
+func getMFASessionCredentialsProvider(ctx context.Context, d *plugin.QueryData, cfg aws.Config, awsSpcConfig awsConfig) aws.CredentialsProvider {
+	plugin.Logger(ctx).Debug("getMFASessionCredentialsProvider", "connection_name", d.Connection.Name, "mfa_serial", *awsSpcConfig.MFASerial)
+
+	provider := &MFASessionCredentialsProvider{
+		client:        sts.NewFromConfig(cfg),
+		serialNumber:  *awsSpcConfig.MFASerial,
+		tokenProvider: getMFATokenProvider(awsSpcConfig),
+	}
+	if awsSpcConfig.DurationSeconds != nil {
+		provider.duration = time.Duration(*awsSpcConfig.DurationSeconds) * time.Second
+	}
+	return aws.NewCredentialsCache(provider)
+}
@@ -0,0 +1,89 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const stubSessionCredentials = `<Credentials><AccessKeyId>ASIASTUB</AccessKeyId><SecretAccessKey>stub</SecretAccessKey><SessionToken>stub</SessionToken><Expiration>2099-01-01T00:00:00Z</Expiration></Credentials>`
+
+func TestMFATokenCommandAssumeRole(t *testing.T) {
+	api := newMultiRegionStubAPI(t)
+	api.on("sts", "AssumeRole", queryResponse("AssumeRole", stubSessionCredentials+`<AssumedRoleUser><Arn>arn:aws:sts::`+stubAccountId+`:assumed-role/mfa/steampipe</Arn><AssumedRoleId>AROASTUB:steampipe</AssumedRoleId></AssumedRoleUser>`))
+
+	// The command counts its runs, to check the token is only read once
+	counter := filepath.Join(t.TempDir(), "count")
+	_, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_sns_topic",
+		Columns: []string{"topic_arn"},
+		Regions: []string{"*"},
+		Config: `
+role_arn          = "arn:aws:iam::` + stubAccountId + `:role/mfa"
+mfa_serial        = "arn:aws:iam::` + stubAccountId + `:mfa/test"
+mfa_token_command = "echo run >> ` + counter + ` && echo 123456"
+`,
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if got := listTopicsRegions(api); got != "ap-south-1,us-east-1,us-east-2,us-west-2" {
+		t.Errorf("expected ListTopics in all regions, got %s", got)
+	}
+
+	calls := api.calls("sts", "AssumeRole")
+	if len(calls) != 1 {
+		t.Fatalf("expected 1 AssumeRole call, got %d", len(calls))
+	}
+	if got := calls[0].Params.Get("SerialNumber"); got != "arn:aws:iam::"+stubAccountId+":mfa/test" {
+		t.Errorf("expected the MFA serial number, got %q", got)
+	}
+	if got := calls[0].Params.Get("TokenCode"); got != "123456" {
+		t.Errorf("expected token code 123456, got %q", got)
+	}
+	runs, _ := os.ReadFile(counter)
+	if got := strings.Count(string(runs), "run"); got != 1 {
+		t.Errorf("expected mfa_token_command to run once, got %d", got)
+	}
+}
+
+func TestMFATokenFileSessionToken(t *testing.T) {
+	api := newStubAPI(t)
+	api.on("sts", "GetSessionToken", queryResponse("GetSessionToken", stubSessionCredentials))
+	api.on("sns", "ListTopics", queryResponse("ListTopics", `<Topics/>`))
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("654321\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_sns_topic",
+		Columns: []string{"topic_arn"},
+		Config: `
+mfa_serial       = "arn:aws:iam::` + stubAccountId + `:mfa/test"
+mfa_token_file   = "` + tokenFile + `"
+duration_seconds = 7200
+`,
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	calls := api.calls("sts", "GetSessionToken")
+	if len(calls) != 1 {
+		t.Fatalf("expected 1 GetSessionToken call, got %d", len(calls))
+	}
+	if got := calls[0].Params.Get("TokenCode"); got != "654321" {
+		t.Errorf("expected token code 654321, got %q", got)
+	}
+	if got := calls[0].Params.Get("DurationSeconds"); got != "7200" {
+		t.Errorf("expected a 7200 second session, got %q", got)
+	}
+	// Calls are signed with the MFA session
+	for _, r := range api.calls("sns", "ListTopics") {
+		if !strings.Contains(r.Header.Get("Authorization"), "Credential=ASIASTUB/") {
+			t.Errorf("expected ListTopics to use the MFA session, got %s", r.Header.Get("Authorization"))
+		}
+	}
+}
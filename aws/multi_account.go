@@ -0,0 +1,280 @@
+package aws
+
+// Organization connections
+//
+// A connection with an `organization` block fans out every regional query to
+// the member accounts of an AWS Organization. The connection credentials
+// (profile, keys, role_arn, etc.) are treated as the management (or delegated
+// administrator) credentials. They are used to list the accounts in the
+// organization and then to assume `member_role_name` in each member account.
+//
+// Implementation notes:
+// - The query matrix is account x region. The account is passed through the
+//   matrix item under the `account_id` key.
+// - Account-level tables (e.g. IAM, S3 buckets) have no region matrix. They
+//   use OrganizationAccountMatrix, which is an account only matrix for
+//   organization connections and no matrix otherwise. Tables that stay on the
+//   management account are the Organizations and Cost Explorer tables (which
+//   already cover the whole organization), tables keyed by a single principal
+//   or bucket (e.g. aws_iam_policy_simulator, aws_s3_object) and tables that
+//   don't call AWS at all.
+// - `account_id` quals are applied to the account matrix by the SDK, so only
+//   the matching member accounts are queried.
+// - `account_id` is not a connection key column: the SDK compares those with
+//   a single value per connection, which would skip organization connections
+//   for quals on member accounts. Instead, when there are `account_id` quals,
+//   other connections tag their matrix items with their account, so the SDK
+//   skips them the same way.
+// - Region data (partition, enabled regions, default region) is always
+//   calculated from the management account and shared by all members.
+// - The management account itself is queried with the connection credentials
+//   rather than by assuming the member role.
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/memoize"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/context_key"
+)
+
+const matrixKeyAccount = "account_id"
+
+// Returns true if the connection is configured to fan out to the member
+// accounts of an organization.
+func isOrganizationConnection(connection *plugin.Connection) bool {
+	return GetConfig(connection).Organization != nil
+}
+
+// Extend a region matrix to cover every member account of the organization.
+// Connections without an organization block get the region matrix back
+// unchanged.
+func organizationAccountMatrix(ctx context.Context, d *plugin.QueryData, regionMatrix []map[string]interface{}) []map[string]interface{} {
+	if !isOrganizationConnection(d.Connection) {
+		if accountId := getAccountIdForAccountQuals(ctx, d); accountId != "" {
+			for _, item := range regionMatrix {
+				item[matrixKeyAccount] = accountId
+			}
+		}
+		return regionMatrix
+	}
+
+	accountIds, err := listOrganizationMemberAccountIds(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("organizationAccountMatrix", "connection_name", d.Connection.Name, "list_accounts_error", err)
+		panic(err)
+	}
+
+	matrix := make([]map[string]interface{}, 0, len(accountIds)*len(regionMatrix))
+	for _, accountId := range accountIds {
+		for _, item := range regionMatrix {
+			obj := map[string]interface{}{matrixKeyAccount: accountId}
+			for k, v := range item {
+				obj[k] = v
+			}
+			matrix = append(matrix, obj)
+		}
+	}
+
+	plugin.Logger(ctx).Debug("organizationAccountMatrix", "connection_name", d.Connection.Name, "accounts", len(accountIds), "matrix_size", len(matrix))
+	return matrix
+}
+
+// Matrix for account-level tables, which have no region matrix. Organization
+// connections get an item per member account, other connections no matrix
+// unless the query has `account_id` quals.
+func OrganizationAccountMatrix(ctx context.Context, d *plugin.QueryData) []map[string]interface{} {
+	matrix := organizationAccountMatrix(ctx, d, []map[string]interface{}{{}})
+	if len(matrix[0]) == 0 {
+		return nil
+	}
+	return matrix
+}
+
+// Get the account of a connection that is not an organization connection, if
+// the query has `account_id` quals. Returns an empty string otherwise, or if
+// the account can't be found, in which case the query fails with the error.
+func getAccountIdForAccountQuals(ctx context.Context, d *plugin.QueryData) string {
+	if d.QueryContext == nil || d.QueryContext.UnsafeQuals[matrixKeyAccount] == nil {
+		return ""
+	}
+	commonColumnData, err := getCommonColumnsMemoized(managementAccountContext(ctx), d, nil)
+	if err != nil {
+		plugin.Logger(ctx).Warn("getAccountIdForAccountQuals", "connection_name", d.Connection.Name, "common_columns_error", err)
+		return ""
+	}
+	return commonColumnData.(*awsCommonColumnData).AccountId
+}
+
+// Get the member account ID for the matrix item being processed. Returns an
+// empty string for non-organization connections, or when the call is not
+// running for an account matrix item (e.g. account-level tables).
+func getMatrixItemAccountId(ctx context.Context, d *plugin.QueryData) string {
+	if !isOrganizationConnection(d.Connection) {
+		return ""
+	}
+	matrixItem := plugin.GetMatrixItem(ctx)
+	if matrixItem == nil {
+		return ""
+	}
+	accountId, _ := matrixItem[matrixKeyAccount].(string)
+	return accountId
+}
+
+// Remove the matrix item from the context, so clients created with the
+// returned context always use the management (connection) credentials. This
+// is used for connection-wide lookups (caller identity, region lists) that
+// are cached once per connection and must not depend on which member account
+// happened to trigger them first.
+func managementAccountContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, context_key.MatrixItem, nil)
+}
+
+// List the IDs of the member accounts to query for this connection.
+func listOrganizationMemberAccountIds(ctx context.Context, d *plugin.QueryData) ([]string, error) {
+	i, err := listOrganizationMemberAccountIdsCached(ctx, d, nil)
+	if err != nil {
+		return nil, err
+	}
+	return i.([]string), nil
+}
+
+// The account list for the organization is cached per connection. Accounts
+// added to the organization are picked up when the cache expires.
+var listOrganizationMemberAccountIdsCached = plugin.HydrateFunc(listOrganizationMemberAccountIdsUncached).Memoize()
+
+// List the active accounts in the organization using the management
+// credentials, then filter them by the include_accounts and exclude_accounts
+// patterns. Patterns support the same wildcards as regions ("*" and "?") and
+// are matched against both the account ID and the account name.
+func listOrganizationMemberAccountIdsUncached(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	ctx = managementAccountContext(ctx)
+	orgConfig := GetConfig(d.Connection).Organization
+
+	svc, err := OrganizationClient(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("listOrganizationMemberAccountIdsUncached", "connection_name", d.Connection.Name, "client_error", err)
+		return nil, err
+	}
+	if svc == nil {
+		return nil, fmt.Errorf("organization connections need AWS Organizations, which is not available in the partition of the connection")
+	}
+
+	var accountIds []string
+	paginator := organizations.NewListAccountsPaginator(svc, &organizations.ListAccountsInput{}, func(o *organizations.ListAccountsPaginatorOptions) {
+		o.StopOnDuplicateToken = true
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			plugin.Logger(ctx).Error("listOrganizationMemberAccountIdsUncached", "connection_name", d.Connection.Name, "api_error", err)
+			return nil, err
+		}
+		for _, account := range output.Accounts {
+			if account.Status != types.AccountStatusActive {
+				continue
+			}
+			id, name := aws.ToString(account.Id), aws.ToString(account.Name)
+			if len(orgConfig.IncludeAccounts) > 0 && !matchesAccountPatterns(orgConfig.IncludeAccounts, id, name) {
+				continue
+			}
+			if matchesAccountPatterns(orgConfig.ExcludeAccounts, id, name) {
+				continue
+			}
+			accountIds = append(accountIds, id)
+		}
+	}
+
+	plugin.Logger(ctx).Debug("listOrganizationMemberAccountIdsUncached", "connection_name", d.Connection.Name, "accounts", accountIds)
+	return accountIds, nil
+}
+
+func matchesAccountPatterns(patterns []string, accountId string, accountName string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, accountId); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, accountName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the base AWS config for a member account of the organization. This is
+// the management base config with credentials for the member role.
+func getBaseClientForMemberAccount(ctx context.Context, d *plugin.QueryData, accountId string) (*aws.Config, error) {
+	h := &plugin.HydrateData{Item: accountId}
+	i, err := getBaseClientForMemberAccountCached(ctx, d, h)
+	if err != nil {
+		return nil, err
+	}
+	return i.(*aws.Config), nil
+}
+
+// Member base clients are cached per connection and account, with the same
+// long expiration as the management base client since the credentials cache
+// refreshes the assumed role session itself.
+// The member base client needs the management caller identity, which is
+// itself resolved using a base client. That is fine at runtime (the
+// management lookups never carry a member account), but it is a cycle for
+// package variable initialization, so the memoized function is set in init().
+var getBaseClientForMemberAccountCached plugin.HydrateFunc
+
+func init() {
+	getBaseClientForMemberAccountCached = plugin.HydrateFunc(getBaseClientForMemberAccountUncached).Memoize(memoize.WithCacheKeyFunction(getBaseClientForMemberAccountCacheKey), memoize.WithTtl(baseClientCacheTtl))
+}
+
+func getBaseClientForMemberAccountCacheKey(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	accountId := h.Item.(string)
+	key := fmt.Sprintf("getBaseClientForMemberAccount-%s", accountId)
+	return key, nil
+}
+
+func getBaseClientForMemberAccountUncached(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	accountId := h.Item.(string)
+	ctx = managementAccountContext(ctx)
+
+	plugin.Logger(ctx).Debug("getBaseClientForMemberAccountUncached", "connection_name", d.Connection.Name, "account_id", accountId, "status", "starting")
+
+	baseCfg, err := getBaseClientForAccountCached(ctx, d, nil)
+	if err != nil {
+		return nil, err
+	}
+	cfg := baseCfg.(*aws.Config).Copy()
+
+	// The management account is queried directly with the connection
+	// credentials. Everything else assumes the member role.
+	commonColumnData, err := getCommonColumns(ctx, d, nil)
+	if err != nil {
+		return nil, err
+	}
+	managementData := commonColumnData.(*awsCommonColumnData)
+	if managementData.AccountId == accountId {
+		return &cfg, nil
+	}
+
+	orgConfig := GetConfig(d.Connection).Organization
+	roleArn := fmt.Sprintf("arn:%s:iam::%s:role/%s", managementData.Partition, accountId, orgConfig.MemberRoleName)
+	provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), roleArn, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = defaultRoleSessionName(d.Connection.Name)
+		if orgConfig.MemberRoleSessionName != nil {
+			o.RoleSessionName = *orgConfig.MemberRoleSessionName
+		}
+		if orgConfig.MemberRoleExternalId != nil {
+			o.ExternalID = orgConfig.MemberRoleExternalId
+		}
+	})
+	cfg.Credentials = aws.NewCredentialsCache(provider)
+
+	plugin.Logger(ctx).Debug("getBaseClientForMemberAccountUncached", "connection_name", d.Connection.Name, "account_id", accountId, "role_arn", roleArn, "status", "done")
+	return &cfg, nil
+}
@@ -0,0 +1,135 @@
+package aws
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+const testMemberAccountId = "210987654321"
+
+const testOrganizationConfig = `
+organization {
+  member_role_name = "steampipe"
+}
+`
+
+// Stub an organization with the stub account as management account and one
+// member account. Requests made with the member role credentials use the
+// AKIDMEMBER access key.
+func stubOrganization(api *stubAPI) {
+	api.on("organizations", "ListAccounts", jsonResponse(map[string]interface{}{
+		"Accounts": []map[string]interface{}{
+			{"Id": stubAccountId, "Name": "management", "Status": "ACTIVE"},
+			{"Id": testMemberAccountId, "Name": "member", "Status": "ACTIVE"},
+			{"Id": "333333333333", "Name": "closed", "Status": "SUSPENDED"},
+		},
+	}))
+	api.on("sts", "AssumeRole", queryResponse("AssumeRole", `<Credentials><AccessKeyId>AKIDMEMBER</AccessKeyId><SecretAccessKey>stub</SecretAccessKey><SessionToken>stub</SessionToken><Expiration>2100-01-01T00:00:00Z</Expiration></Credentials>`+
+		`<AssumedRoleUser><Arn>arn:aws:sts::`+testMemberAccountId+`:assumed-role/steampipe/stub</Arn><AssumedRoleId>AROATEST:stub</AssumedRoleId></AssumedRoleUser>`))
+}
+
+// The account of a request, from the access key it was signed with.
+func stubRequestAccountId(r stubRequest) string {
+	if strings.Contains(r.Header.Get("Authorization"), "Credential=AKIDMEMBER/") {
+		return testMemberAccountId
+	}
+	return stubAccountId
+}
+
+func TestAwsIamRoleOrganizationConnection(t *testing.T) {
+	api := newStubAPI(t)
+	stubOrganization(api)
+	api.onFunc("iam", "ListRoles", func(r stubRequest) stubResponse {
+		accountId := stubRequestAccountId(r)
+		return queryResponse("ListRoles", `<Roles><member><RoleName>role-`+accountId+`</RoleName><Arn>arn:aws:iam::`+accountId+`:role/role-`+accountId+`</Arn><RoleId>AROATEST</RoleId><Path>/</Path><CreateDate>2024-01-01T00:00:00Z</CreateDate></member></Roles><IsTruncated>false</IsTruncated>`)
+	})
+
+	rows, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_iam_role",
+		Columns: []string{"name", "account_id"},
+		Config:  testOrganizationConfig,
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i]["name"].(string) < rows[j]["name"].(string) })
+	if len(rows) != 2 {
+		t.Fatalf("expected a role per account, got %v", rows)
+	}
+	for i, accountId := range []string{stubAccountId, testMemberAccountId} {
+		if rows[i]["name"] != "role-"+accountId || rows[i]["account_id"] != accountId {
+			t.Errorf("expected role-%s in account %s, got %v", accountId, accountId, rows[i])
+		}
+	}
+	if got := len(api.calls("sts", "AssumeRole")); got != 1 {
+		t.Errorf("expected the member role to be assumed once, got %d", got)
+	}
+
+	// account_id quals limit the accounts queried
+	api = newStubAPI(t)
+	stubOrganization(api)
+	api.on("iam", "ListRoles", queryResponse("ListRoles", `<Roles/><IsTruncated>false</IsTruncated>`))
+	_, err = runTableQuery(t, api, tableQuery{
+		Table:   "aws_iam_role",
+		Columns: []string{"name", "account_id"},
+		Quals:   map[string]string{"account_id": stubAccountId},
+		Config:  testOrganizationConfig,
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if got := len(api.calls("iam", "ListRoles")); got != 1 {
+		t.Errorf("expected ListRoles in the management account only, got %d calls", got)
+	}
+	if got := len(api.calls("sts", "AssumeRole")); got != 0 {
+		t.Errorf("expected no member role to be assumed, got %d", got)
+	}
+
+	// Quals on a member account only query that account
+	api = newStubAPI(t)
+	stubOrganization(api)
+	api.onFunc("iam", "ListRoles", func(r stubRequest) stubResponse {
+		accountId := stubRequestAccountId(r)
+		return queryResponse("ListRoles", `<Roles><member><RoleName>role-`+accountId+`</RoleName><Arn>arn:aws:iam::`+accountId+`:role/role-`+accountId+`</Arn><RoleId>AROATEST</RoleId><Path>/</Path><CreateDate>2024-01-01T00:00:00Z</CreateDate></member></Roles><IsTruncated>false</IsTruncated>`)
+	})
+	rows, err = runTableQuery(t, api, tableQuery{
+		Table:   "aws_iam_role",
+		Columns: []string{"name", "account_id"},
+		Quals:   map[string]string{"account_id": testMemberAccountId},
+		Config:  testOrganizationConfig,
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["account_id"] != testMemberAccountId {
+		t.Errorf("expected the role of the member account, got %v", rows)
+	}
+	if got := len(api.calls("iam", "ListRoles")); got != 1 {
+		t.Errorf("expected ListRoles in the member account only, got %d calls", got)
+	}
+}
+
+func TestAwsAccountIdQualOtherConnection(t *testing.T) {
+	// Connections that are not organization connections are skipped for
+	// quals on other accounts
+	for table, service := range map[string]string{"aws_iam_role": "iam", "aws_sns_topic": "sns"} {
+		api := newStubAPI(t)
+		rows, err := runTableQuery(t, api, tableQuery{
+			Table:   table,
+			Columns: []string{"account_id"},
+			Quals:   map[string]string{"account_id": testMemberAccountId},
+		})
+		if err != nil {
+			t.Fatalf("%s: query failed: %v", table, err)
+		}
+		if len(rows) != 0 {
+			t.Errorf("%s: expected no rows, got %v", table, rows)
+		}
+		for _, r := range api.requests {
+			if r.Service == service {
+				t.Errorf("%s: expected no %s calls, got %s", table, service, r.Operation)
+			}
+		}
+	}
+}
@@ -29,15 +29,20 @@ package aws
 // - `regions`: List of regions that the user has configured to use in
 //   Steampipe.  Queries will combine results from these regions. But, the regions
 //   config may include wildcard regions (e.g. `us-*`) that will be expanded to
-//   include all enabled regions.
+//   include all enabled regions, and exclusions (e.g. `!ap-*`).
+// - `service_regions`: Map of service endpoint ID to a list of regions, in the
+//   same format as `regions`. It replaces `regions` for that service.
+// - `region_source`: Where the enabled regions of the account come from, either
+//   `ec2` (DescribeRegions, the default) or `account` (account:ListRegions,
+//   which also knows about regions that are being enabled or disabled).
 //
 // Calculated for a connection at runtime:
 // - Query regions: The set of regions that Steampipe will use for a given query.
 //   This is the set of Enabled Regions that match the `regions` config.
 //
 // Query regions is based on these factors:
-// - All regions in the partition
-// - All regions available for service X in the partition
+// - All regions in the partition (from the region catalog, see region_catalog.go)
+// - All regions available for service X in the partition (region catalog)
 // - Hard-coded exclusions (sometimes the definition of service X is wrong)
 // - Filter by regions enabled for this account (e.g. some might not be opted-in)
 // - Filter by configured query `regions` in aws.spc
@@ -67,13 +72,13 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/account"
+	accounttypes "github.com/aws/aws-sdk-go-v2/service/account/types"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 
 	cloudwatchv1 "github.com/aws/aws-sdk-go/service/cloudwatch"
 
-	"github.com/aws/aws-sdk-go/aws/endpoints"
-
 	"github.com/turbot/go-kit/helpers"
 	"github.com/turbot/steampipe-plugin-sdk/v5/logging"
 	"github.com/turbot/steampipe-plugin-sdk/v5/memoize"
@@ -126,8 +131,9 @@ func SupportedRegionMatrixWithExclusions(serviceID string, excludeRegions []stri
 		defer logging.LogTime("SupportedRegionMatrixWithExlusions end")
 		// Default to an empty list of regions
 		matrix := []map[string]interface{}{}
-		// Get the regions enabled for this account
-		queryRegions, err := listQueryRegionsForConnection(ctx, d)
+		// Get the regions enabled for this account, using the service_regions
+		// override for the service if there is one
+		queryRegions, err := listQueryRegionsForService(ctx, d, serviceID)
 		if err != nil {
 			plugin.Logger(ctx).Error("SupportedRegionMatrixWithExclusions", "connection_name", d.Connection.Name, "serviceID", serviceID, "excludeRegions", excludeRegions, "query_regions_error", err)
 			panic(err)
@@ -140,11 +146,19 @@ func SupportedRegionMatrixWithExclusions(serviceID string, excludeRegions []stri
 			serviceRegions = queryRegions
 			plugin.Logger(ctx).Debug("SupportedRegionMatrixWithExclusions", "connection_name", d.Connection.Name, "serviceID", serviceID, "excludeRegions", excludeRegions, "service_regions_using_query_regions", serviceRegions)
 		} else {
-			serviceRegions, err = listRegionsForServiceWithExclusions(ctx, d, serviceID, excludeRegions)
+			var fipsSkippedRegions []string
+			serviceRegions, fipsSkippedRegions, err = listRegionsForServiceWithFipsSkips(ctx, d, serviceID, excludeRegions)
 			if err != nil {
 				plugin.Logger(ctx).Error("SupportedRegionMatrixWithExclusions", "connection_name", d.Connection.Name, "serviceID", serviceID, "excludeRegions", excludeRegions, "service_regions_error", err)
 				panic(err)
 			}
+			// Record the query regions skipped with use_fips_endpoint, so the
+			// missing data shows in aws_ignored_error
+			for _, region := range fipsSkippedRegions {
+				if helpers.StringSliceContains(queryRegions, region) {
+					recordFipsSkippedRegion(ctx, d, serviceID, region)
+				}
+			}
 			plugin.Logger(ctx).Debug("SupportedRegionMatrixWithExclusions", "connection_name", d.Connection.Name, "serviceID", serviceID, "excludeRegions", excludeRegions, "service_regions", serviceRegions)
 		}
 		// Find all regions in both the query regions and the service regions
@@ -154,6 +168,8 @@ func SupportedRegionMatrixWithExclusions(serviceID string, excludeRegions []stri
 				matrix = append(matrix, obj)
 			}
 		}
+		// Organization connections fan out each region to every member account
+		matrix = organizationAccountMatrix(ctx, d, matrix)
 		plugin.Logger(ctx).Debug("SupportedRegionMatrixWithExclusions", "connection_name", d.Connection.Name, "serviceID", serviceID, "excludeRegions", excludeRegions, "matrix", matrix)
 		return matrix
 	}
@@ -198,6 +214,10 @@ func SupportedRegionMatrixWithExclusions(serviceID string, excludeRegions []stri
 //	result = []
 func listQueryRegionsForConnection(ctx context.Context, d *plugin.QueryData) ([]string, error) {
 
+	if err := validateConnectionConfig(ctx, d); err != nil {
+		return nil, err
+	}
+
 	// Retrieve regions list from the AWS plugin steampipe connection config
 	awsSpcConfig := GetConfig(d.Connection)
 
@@ -215,6 +235,27 @@ func listQueryRegionsForConnection(ctx context.Context, d *plugin.QueryData) ([]
 	}
 
 	// PRE: there is a list of regions in the config to match against
+	return listQueryRegionsForPatterns(ctx, d, awsSpcConfig.Regions)
+}
+
+// Calculate the query regions for a service. If the service has an override
+// in the `service_regions` config, it replaces the `regions` config for that
+// service, e.g. to limit an expensive service to a couple of regions.
+func listQueryRegionsForService(ctx context.Context, d *plugin.QueryData, serviceID string) ([]string, error) {
+	if err := validateConnectionConfig(ctx, d); err != nil {
+		return nil, err
+	}
+	awsSpcConfig := GetConfig(d.Connection)
+	patterns, ok := getServiceRegionsConfig(awsSpcConfig, serviceID)
+	if !ok {
+		return listQueryRegionsForConnection(ctx, d)
+	}
+	plugin.Logger(ctx).Debug("listQueryRegionsForService", "connection_name", d.Connection.Name, "serviceID", serviceID, "service_regions", patterns)
+	return listQueryRegionsForPatterns(ctx, d, patterns)
+}
+
+// Filter the enabled regions for the account by a list of region patterns.
+func listQueryRegionsForPatterns(ctx context.Context, d *plugin.QueryData, patterns []string) ([]string, error) {
 
 	// Get information about the regions for this account, considering
 	// the partition, opt-ins, etc.
@@ -235,20 +276,56 @@ func listQueryRegionsForConnection(ctx context.Context, d *plugin.QueryData) ([]
 
 	// Filter to regions that match the patterns in the config.
 	var targetRegions []string
-	for _, pattern := range awsSpcConfig.Regions {
-		for _, validRegion := range maxTargetRegions {
-			if ok, _ := path.Match(pattern, validRegion); ok {
-				targetRegions = append(targetRegions, validRegion)
-			}
+	for _, validRegion := range maxTargetRegions {
+		if matchRegionPatterns(patterns, validRegion) {
+			targetRegions = append(targetRegions, validRegion)
 		}
 	}
 	targetRegions = helpers.StringSliceDistinct(targetRegions)
 
-	plugin.Logger(ctx).Debug("listQueryRegionsForConnection", "connection_name", d.Connection.Name, "targetRegions", targetRegions)
+	plugin.Logger(ctx).Debug("listQueryRegionsForPatterns", "connection_name", d.Connection.Name, "patterns", patterns, "targetRegions", targetRegions)
 
 	return targetRegions, nil
 }
 
+// Returns true if the region matches the region patterns from the config.
+// Patterns starting with "!" exclude the regions they match, and take
+// precedence over the other patterns. If there are only exclusions, all other
+// regions are included, e.g. ["!ap-*"] is every region outside Asia Pacific.
+func matchRegionPatterns(patterns []string, region string) bool {
+	included := true
+	for _, pattern := range patterns {
+		if !strings.HasPrefix(pattern, "!") {
+			included = false
+			break
+		}
+	}
+	for _, pattern := range patterns {
+		if exclude, ok := strings.CutPrefix(pattern, "!"); ok {
+			if matched, _ := path.Match(exclude, region); matched {
+				return false
+			}
+		} else if matched, _ := path.Match(pattern, region); matched {
+			included = true
+		}
+	}
+	return included
+}
+
+// Get the `service_regions` override for a service, if any. Keys are
+// matched on the service endpoint ID, ignoring case, dashes and underscores.
+func getServiceRegionsConfig(awsSpcConfig awsConfig, serviceID string) ([]string, bool) {
+	if serviceID == "" {
+		return nil, false
+	}
+	for service, patterns := range awsSpcConfig.ServiceRegions {
+		if normalizeEndpointServiceKey(service) == normalizeEndpointServiceKey(serviceID) {
+			return patterns, true
+		}
+	}
+	return nil, false
+}
+
 // WAFRegionMatrix returns the general region list, with a special region
 // called "global" added. This is a specific region name used only by the WAF
 // service.
@@ -257,8 +334,11 @@ func listQueryRegionsForConnection(ctx context.Context, d *plugin.QueryData) ([]
 // way to exclude it except by filtering the results.
 func WAFRegionMatrix(ctx context.Context, d *plugin.QueryData) []map[string]interface{} {
 	regionMatrix := CloudWatchRegionsMatrix(ctx, d)
-	matrix := make([]map[string]interface{}, 1, len(regionMatrix)+1)
-	matrix[0] = map[string]interface{}{matrixKeyRegion: "global"}
+	globalMatrix := []map[string]interface{}{{matrixKeyRegion: "global"}}
+	// Organization connections need the global region for every member account
+	globalMatrix = organizationAccountMatrix(ctx, d, globalMatrix)
+	matrix := make([]map[string]interface{}, 0, len(globalMatrix)+len(regionMatrix))
+	matrix = append(matrix, globalMatrix...)
 	matrix = append(matrix, regionMatrix...)
 	return matrix
 }
@@ -275,18 +355,41 @@ var listRegionsForServiceCached = plugin.HydrateFunc(listRegionsForServiceUncach
 // List all regions for a given service in the partition for this connection, but
 // manually exclude any regions in excludeRegions.
 func listRegionsForServiceWithExclusions(ctx context.Context, d *plugin.QueryData, serviceID string, excludeRegions []string) ([]string, error) {
+	serviceRegions, _, err := listRegionsForServiceWithFipsSkips(ctx, d, serviceID, excludeRegions)
+	return serviceRegions, err
+}
+
+// Same as listRegionsForServiceWithExclusions, also returning the regions
+// skipped because the service has no FIPS endpoint in them, with
+// use_fips_endpoint. Services are skipped rather than failing the query.
+func listRegionsForServiceWithFipsSkips(ctx context.Context, d *plugin.QueryData, serviceID string, excludeRegions []string) ([]string, []string, error) {
 	h := &plugin.HydrateData{Item: serviceID}
 	// Get all regions for the service
 	iRegions, err := listRegionsForServiceCached(ctx, d, h)
 	if err != nil {
 		plugin.Logger(ctx).Error("listRegionsForServiceWithExclusions", "connection_name", d.Connection.Name, "serviceID", serviceID, "excludeRegions", excludeRegions, "error", err)
-		return nil, err
+		return nil, nil, err
 	}
-	serviceRegions := iRegions.([]string)
 	// Remove the excluded regions from the valid list
-	serviceRegions = helpers.RemoveFromStringSlice(serviceRegions, excludeRegions...)
-	plugin.Logger(ctx).Debug("listRegionsForServiceWithExclusions", "connection_name", d.Connection.Name, "serviceID", serviceID, "excludeRegions", excludeRegions, "serviceRegions", serviceRegions)
-	return serviceRegions, nil
+	regions := helpers.RemoveFromStringSlice(iRegions.([]string), excludeRegions...)
+
+	catalog, err := getRegionCatalog(ctx, d)
+	if err != nil {
+		return nil, nil, err
+	}
+	awsSpcConfig := GetConfig(d.Connection)
+	serviceRegions := make([]string, 0, len(regions))
+	var fipsSkippedRegions []string
+	for _, region := range regions {
+		if hasFipsEndpointForServiceRegion(catalog, awsSpcConfig, serviceID, region) {
+			serviceRegions = append(serviceRegions, region)
+		} else {
+			warnFipsSkippedServiceRegion(ctx, d.Connection.Name, serviceID, region)
+			fipsSkippedRegions = append(fipsSkippedRegions, region)
+		}
+	}
+	plugin.Logger(ctx).Debug("listRegionsForServiceWithExclusions", "connection_name", d.Connection.Name, "serviceID", serviceID, "excludeRegions", excludeRegions, "serviceRegions", serviceRegions, "fipsSkippedRegions", fipsSkippedRegions)
+	return serviceRegions, fipsSkippedRegions, nil
 }
 
 // getClient is per-region, but Memoize() is per-connection, so a setup
@@ -299,18 +402,14 @@ func listRegionsForServiceCacheKey(ctx context.Context, d *plugin.QueryData, h *
 	return key, nil
 }
 
-// Use the AWS SDK to get a list of regions that the given service (in hydrate
-// data) supports.
+// Use the region catalog to get a list of regions that the given service (in
+// hydrate data) supports.
 // Implementation notes:
-//   - Use AWS SDK v1 because v2 does not expose this data (ugh).
 //   - Use getCommonColumns to get the accurate partition for the account (via
 //     GetCallerIdentity). This is more accurate than guessing from the default
 //     region.
 func listRegionsForServiceUncached(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
 
-	var partitionName string
-	var partition endpoints.Partition
-
 	// Service ID is passed through the hydrate data
 	serviceID := h.Item.(string)
 
@@ -323,45 +422,52 @@ func listRegionsForServiceUncached(ctx context.Context, d *plugin.QueryData, h *
 		plugin.Logger(ctx).Error("listRegionsForServiceUncached", "connection_name", d.Connection.Name, "unable to get partition name", err)
 		return nil, err
 	}
-	partitionName = commonColumnData.(*awsCommonColumnData).Partition
-
-	// Get AWS partition based on the partition name
-	switch partitionName {
-	case endpoints.AwsPartitionID:
-		partition = endpoints.AwsPartition()
-	case endpoints.AwsCnPartitionID:
-		partition = endpoints.AwsCnPartition()
-	case endpoints.AwsIsoPartitionID:
-		partition = endpoints.AwsIsoPartition()
-	case endpoints.AwsUsGovPartitionID:
-		partition = endpoints.AwsUsGovPartition()
-	case endpoints.AwsIsoBPartitionID:
-		partition = endpoints.AwsIsoBPartition()
-	default:
+	partitionName := commonColumnData.(*awsCommonColumnData).Partition
+
+	catalog, err := getRegionCatalog(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	partition, ok := catalog.partition(partitionName)
+	if !ok {
 		err := fmt.Errorf("listRegionsForServiceUncached:: '%s' is an invalid partition", partitionName)
 		plugin.Logger(ctx).Error("listRegionsForServiceUncached", "connection_name", d.Connection.Name, "invalid_partition_error", err)
 		return nil, err
 	}
 
-	var regionsForService []string
-
-	// Get the list of the service regions based on the service ID.  Ultimately,
-	// this is using data from
-	// https://github.com/aws/aws-sdk-go/blob/main/models/endpoints/endpoints.json
-	services := partition.Services()
-	serviceInfo, ok := services[serviceID]
+	// Get the list of the service regions based on the service ID. The
+	// endpoint data has no services for some partitions (e.g. aws-iso-e), so
+	// like getGlobalServiceRegion, assume the service is in the regions known
+	// for the partition, or else its last resort region.
+	serviceRegions, ok := partition.Services[serviceID]
+	if len(partition.Services) == 0 {
+		serviceRegions, ok = partition.regionIds(), true
+		if len(serviceRegions) == 0 {
+			if region, found := awsPartitionLastResortRegions[partitionName]; found {
+				serviceRegions = []string{region}
+			}
+		}
+		plugin.Logger(ctx).Debug("listRegionsForServiceUncached", "connection_name", d.Connection.Name, "partition", partitionName, "serviceID", serviceID, "status", "partition has no services in region catalog", "regions", serviceRegions)
+	}
 	if !ok {
 		err := fmt.Errorf("listRegionsForServiceUncached called with invalid service ID: %s", serviceID)
-		plugin.Logger(ctx).Error("listRegionsForServiceUncached", "connection_name", d.Connection.Name, "partition", partition, "serviceID", serviceID, "error", err)
+		plugin.Logger(ctx).Error("listRegionsForServiceUncached", "connection_name", d.Connection.Name, "partition", partitionName, "serviceID", serviceID, "error", err)
 		return nil, err
 	}
-
-	regions := serviceInfo.Regions()
-	for rs := range regions {
-		regionsForService = append(regionsForService, rs)
+	regionsForService := append([]string{}, serviceRegions...)
+
+	// Limit the service to the regions in its `service_regions` override
+	if patterns, ok := getServiceRegionsConfig(GetConfig(d.Connection), serviceID); ok {
+		var overrideRegions []string
+		for _, region := range regionsForService {
+			if matchRegionPatterns(patterns, region) {
+				overrideRegions = append(overrideRegions, region)
+			}
+		}
+		regionsForService = overrideRegions
 	}
 
-	plugin.Logger(ctx).Debug("listRegionsForServiceUncached", "connection_name", d.Connection.Name, "partition", partition, "serviceID", serviceID, "regionsForService", regionsForService)
+	plugin.Logger(ctx).Debug("listRegionsForServiceUncached", "connection_name", d.Connection.Name, "partition", partitionName, "serviceID", serviceID, "regionsForService", regionsForService)
 	return regionsForService, nil
 }
 
@@ -386,17 +492,15 @@ func listRegionsUncached(ctx context.Context, d *plugin.QueryData, h *plugin.Hyd
 
 	plugin.Logger(ctx).Debug("listRegionsUncached", "status", "starting", "connection_name", d.Connection.Name, "region", clientRegion)
 
-	// If the client region is not AWS commercial (our default) then update
-	// the full region list from a best guess based on the client region.
-	allRegionsForClientPartition := awsCommercialRegions()
-	if strings.HasPrefix(clientRegion, "us-gov") {
-		allRegionsForClientPartition = awsUsGovRegions()
-	} else if strings.HasPrefix(clientRegion, "cn") {
-		allRegionsForClientPartition = awsChinaRegions()
-	} else if strings.HasPrefix(clientRegion, "us-isob") {
-		allRegionsForClientPartition = awsUsIsobRegions()
-	} else if strings.HasPrefix(clientRegion, "us-iso") {
-		allRegionsForClientPartition = awsUsIsoRegions()
+	// Get the full region list of the partition from a best guess based on
+	// the client region.
+	catalog, err := getRegionCatalog(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	allRegionsForClientPartition := []string{}
+	if partition, ok := catalog.partition(awsPartitionFromRegion(clientRegion)); ok {
+		allRegionsForClientPartition = partition.regionIds()
 	}
 
 	// We try to get the accurate region list via an API call below, but as a
@@ -409,6 +513,19 @@ func listRegionsUncached(ctx context.Context, d *plugin.QueryData, h *plugin.Hyd
 		ActiveRegions:   allRegionsForClientPartition,
 	}
 
+	// Get the AWS region list from the Account API (via cache), if it's the
+	// region source of the connection. Fall back to the EC2 API on errors, e.g.
+	// if the user doesn't have access to the account service.
+	if getRegionSource(GetConfig(d.Connection)) == regionSourceAccount {
+		iAccountRegions, err := listRawAccountRegions(ctx, d, h)
+		if err == nil {
+			data = accountRegionsToRegionsData(iAccountRegions.([]accounttypes.Region))
+			plugin.Logger(ctx).Debug("listRegionsUncached", "status", "finished", "connection_name", d.Connection.Name, "source", regionSourceAccount, "data", data)
+			return data, nil
+		}
+		plugin.Logger(ctx).Warn("listRegionsUncached", "connection_name", d.Connection.Name, "source", regionSourceAccount, "regions_error", err)
+	}
+
 	// Get the AWS region list from the EC2 API (via cache)
 	iRegions, err := listRawAwsRegions(ctx, d, h)
 	if err != nil {
@@ -454,12 +571,21 @@ var listRawAwsRegions = plugin.HydrateFunc(listRawAwsRegionsUncached).Memoize()
 func listRawAwsRegionsUncached(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
 	logger := plugin.Logger(ctx)
 
+	// Region data is shared by all accounts of an organization connection
+	ctx = managementAccountContext(ctx)
+
 	clientRegion, err := getDefaultRegion(ctx, d, h)
 	if err != nil {
 		logger.Error("listRawAwsRegionsUncached", "connection_name", d.Connection.Name, "clientRegion", clientRegion, "region_error", err)
 		return nil, err
 	}
 
+	// Use the region list saved by a previous plugin process, if any
+	var cachedRegions []types.Region
+	if readMetadataCache(ctx, d, "listRawAwsRegions", &cachedRegions) {
+		return cachedRegions, nil
+	}
+
 	// Create Session
 	svc, err := EC2LowRetryClientForRegion(ctx, d, clientRegion)
 	if err != nil {
@@ -480,9 +606,84 @@ func listRawAwsRegionsUncached(ctx context.Context, d *plugin.QueryData, h *plug
 
 	logger.Debug("listRawAwsRegionsUncached", "connection_name", d.Connection.Name, "clientRegion", clientRegion, "len(resp.Regions)", len(resp.Regions))
 
+	writeMetadataCache(ctx, d, "listRawAwsRegions", resp.Regions)
+
 	return resp.Regions, nil
 }
 
+// Sources of the enabled regions of the account.
+const (
+	regionSourceEC2     = "ec2"
+	regionSourceAccount = "account"
+)
+
+// Get the region source of the connection, defaults to ec2.
+func getRegionSource(awsSpcConfig awsConfig) string {
+	if awsSpcConfig.RegionSource == nil || *awsSpcConfig.RegionSource == "" {
+		return regionSourceEC2
+	}
+	return strings.ToLower(*awsSpcConfig.RegionSource)
+}
+
+// Regions that are being enabled can't be called yet, and regions that are
+// being disabled may already fail, so only the enabled ones are active.
+func accountRegionsToRegionsData(regions []accounttypes.Region) RegionsData {
+	data := RegionsData{APIRetrivedList: true}
+	for _, region := range regions {
+		data.AllRegions = append(data.AllRegions, *region.RegionName)
+		switch region.RegionOptStatus {
+		case accounttypes.RegionOptStatusEnabled, accounttypes.RegionOptStatusEnabledByDefault:
+			data.ActiveRegions = append(data.ActiveRegions, *region.RegionName)
+		default:
+			data.NotOptedRegions = append(data.NotOptedRegions, *region.RegionName)
+		}
+	}
+	return data
+}
+
+// Cached list of regions of the AWS account from the Account API.
+var listRawAccountRegions = plugin.HydrateFunc(listRawAccountRegionsUncached).Memoize()
+
+// List regions for this AWS account connection, with their opt-in status, by
+// calling the Account ListRegions API.
+func listRawAccountRegionsUncached(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	logger := plugin.Logger(ctx)
+
+	// Region data is shared by all accounts of an organization connection
+	ctx = managementAccountContext(ctx)
+
+	// Use the region list saved by a previous plugin process, if any
+	var cachedRegions []accounttypes.Region
+	if readMetadataCache(ctx, d, "listRawAccountRegions", &cachedRegions) {
+		return cachedRegions, nil
+	}
+
+	svc, err := AccountClient(ctx, d)
+	if err != nil {
+		logger.Error("listRawAccountRegionsUncached", "connection_name", d.Connection.Name, "connnection_error", err)
+		return nil, err
+	}
+
+	var regions []accounttypes.Region
+	paginator := account.NewListRegionsPaginator(svc, &account.ListRegionsInput{MaxResults: aws.Int32(50)}, func(o *account.ListRegionsPaginatorOptions) {
+		o.StopOnDuplicateToken = true
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			logger.Error("listRawAccountRegionsUncached", "connection_name", d.Connection.Name, "api_error", err)
+			return nil, err
+		}
+		regions = append(regions, output.Regions...)
+	}
+
+	logger.Debug("listRawAccountRegionsUncached", "connection_name", d.Connection.Name, "len(regions)", len(regions))
+
+	writeMetadataCache(ctx, d, "listRawAccountRegions", regions)
+
+	return regions, nil
+}
+
 // The "last resort" region is generally the oldest / best final failsafe region
 // to use for a given partition. For example, in AWS Commercial it's us-east-1.
 // This region is used for API calls that must go to the base endpoint. In general,
@@ -510,6 +711,44 @@ func getLastResortRegion(ctx context.Context, d *plugin.QueryData, h *plugin.Hyd
 	return "", fmt.Errorf("cannot calculate last resort region for default region %s", region)
 }
 
+// Get the region for requests to a global service (e.g. iam or cloudfront) in
+// the partition of the connection. Global services have a different endpoint
+// and signing region in each partition, e.g. iam is signed for us-east-1 in
+// aws but cn-north-1 in aws-cn, so this comes from the region catalog.
+// Services that are regional in the partition use the last resort region if
+// possible. Returns "" if the service is not available in the partition.
+func getGlobalServiceRegion(ctx context.Context, d *plugin.QueryData, serviceID string) (string, error) {
+	lastResortRegion, err := getLastResortRegion(ctx, d, nil)
+	if err != nil {
+		return "", err
+	}
+	partitionID := awsPartitionFromRegion(lastResortRegion)
+
+	catalog, err := getRegionCatalog(ctx, d)
+	if err != nil {
+		return "", err
+	}
+	partition, ok := catalog.partition(partitionID)
+	if !ok || len(partition.Services) == 0 {
+		// Nothing is known about the partition, so hope for the best
+		plugin.Logger(ctx).Debug("getGlobalServiceRegion", "connection_name", d.Connection.Name, "service_id", serviceID, "partition", partitionID, "status", "partition not in region catalog")
+		return lastResortRegion, nil
+	}
+
+	if region, ok := partition.GlobalServices[serviceID]; ok {
+		return region, nil
+	}
+	regions, ok := partition.Services[serviceID]
+	if !ok || len(regions) == 0 {
+		plugin.Logger(ctx).Debug("getGlobalServiceRegion", "connection_name", d.Connection.Name, "service_id", serviceID, "partition", partitionID, "status", "service not available in partition")
+		return "", nil
+	}
+	if helpers.StringSliceContains(regions, lastResortRegion) {
+		return lastResortRegion, nil
+	}
+	return regions[0], nil
+}
+
 // Get the default region for AWS API calls that need to go to a central /
 // non-regional endpoint (e.g. describe EC2 regions). Typically this should be
 // the region closest to the user.
@@ -569,6 +808,10 @@ func awsLastResortRegionFromRegionsConfig(ctx context.Context, d *plugin.QueryDa
 
 	if awsSpcConfig.Regions != nil {
 		for _, r := range awsSpcConfig.Regions {
+			// Exclusions say nothing about the partition to use
+			if strings.HasPrefix(r, "!") {
+				continue
+			}
 			lastResort := awsLastResortRegionFromRegionWildcard(r)
 			if lastResort != "" {
 				plugin.Logger(ctx).Debug("awsLastResortRegionFromRegionsConfig", "connection_name", d.Connection.Name, "region", lastResort)
@@ -606,12 +849,20 @@ func getDefaultRegionUncached(ctx context.Context, d *plugin.QueryData, _ *plugi
 		return region, nil
 	}
 
+	// Resolving the region with the AWS SDK loads the shared config files, so
+	// use the region saved by a previous plugin process, if any
+	if readMetadataCache(ctx, d, "getDefaultRegion", &region) {
+		plugin.Logger(ctx).Debug("getDefaultRegionUncached", "connection_name", d.Connection.Name, "region", region, "source", "metadata cache")
+		return region, nil
+	}
+
 	// Get the region from the AWS SDK. This will use the region defined in the
 	// AWS config files, the AWS_REGION environment variable, or the default
 	// region for the partition.
 	region = getAwsSdkRegion(ctx, d)
 	if region != "" {
 		plugin.Logger(ctx).Debug("getDefaultRegionUncached", "connection_name", d.Connection.Name, "region", region, "source", "AWS SDK resolution")
+		writeMetadataCache(ctx, d, "getDefaultRegion", region)
 		return region, nil
 	}
 
@@ -625,6 +876,7 @@ func getDefaultRegionUncached(ctx context.Context, d *plugin.QueryData, _ *plugi
 	region = awsLastResortRegionFromRegionsConfig(ctx, d)
 	if region != "" {
 		plugin.Logger(ctx).Debug("getDefaultRegionUncached", "connection_name", d.Connection.Name, "region", region, "source", "best guess from regions config")
+		writeMetadataCache(ctx, d, "getDefaultRegion", region)
 		return region, nil
 	}
 
@@ -632,6 +884,7 @@ func getDefaultRegionUncached(ctx context.Context, d *plugin.QueryData, _ *plugi
 	// us-east-1 (the last resort region for the most common partition).
 	region = "us-east-1"
 	plugin.Logger(ctx).Debug("getDefaultRegionUncached", "connection_name", d.Connection.Name, "region", region, "source", "last resort region in most common partition")
+	writeMetadataCache(ctx, d, "getDefaultRegion", region)
 	return region, nil
 }
 
@@ -686,20 +939,15 @@ func getDefaultRegionFromConfig(ctx context.Context, d *plugin.QueryData, _ *plu
 //
 //	us-gov-* -> us-gov-west-1
 //	cn* -> cn-northwest-1
+//	us-isof-* -> us-isof-south-1
 //	us-west-2 -> us-east-1
 //	* -> us-east-1
 //	crap -> ""
 func awsLastResortRegionFromRegionWildcard(regionWildcard string) string {
 
 	// Check prefixes for obscure partitions
-	if strings.HasPrefix(regionWildcard, "us-gov") {
-		return "us-gov-west-1"
-	} else if strings.HasPrefix(regionWildcard, "cn") {
-		return "cn-northwest-1"
-	} else if strings.HasPrefix(regionWildcard, "us-isob") {
-		return "us-isob-east-1"
-	} else if strings.HasPrefix(regionWildcard, "us-iso") {
-		return "us-iso-east-1"
+	if partitionID := awsPartitionFromRegionPrefix(regionWildcard); partitionID != "" {
+		return awsPartitionLastResortRegions[partitionID]
 	}
 
 	// Check if the prefix is for a commercial region.
@@ -707,7 +955,7 @@ func awsLastResortRegionFromRegionWildcard(regionWildcard string) string {
 	// prefixes but longer.
 	for _, prefix := range awsCommercialRegionPrefixes() {
 		if strings.HasPrefix(regionWildcard, prefix) {
-			return "us-east-1"
+			return awsPartitionLastResortRegions[awsPartitionCommercial]
 		}
 	}
 
@@ -715,83 +963,71 @@ func awsLastResortRegionFromRegionWildcard(regionWildcard string) string {
 	return ""
 }
 
+// Given a region, return the ID of the partition it belongs to. Examples:
 //
-// AWS STANDARD REGIONS
-//
-// Source: https://docs.aws.amazon.com/general/latest/gr/rande.html#regional-endpoints
+//	us-gov-west-1 -> aws-us-gov
+//	cn-north-1 -> aws-cn
+//	us-isob-east-1 -> aws-iso-b
+//	eu-isoe-west-1 -> aws-iso-e
+//	eu-west-1 -> aws
 //
-// Maintain a hard coded list of regions to use when API calls to the region
-// list endpoint are not possible. This list must be updated manually as new
-// regions are announced.
-//
-
-func awsCommercialRegionPrefixes() []string {
-	return []string{
-		"af",
-		"ap",
-		"ca",
-		"eu",
-		"me",
-		"sa",
-		"us",
+// Unknown regions are assumed to be in the commercial partition.
+func awsPartitionFromRegion(region string) string {
+	if partitionID := awsPartitionFromRegionPrefix(region); partitionID != "" {
+		return partitionID
 	}
+	return awsPartitionCommercial
 }
 
-func awsCommercialRegions() []string {
-	return []string{
-		"af-south-1",
-		"ap-east-1",
-		"ap-northeast-1",
-		"ap-northeast-2",
-		"ap-northeast-3",
-		"ap-south-1",
-		"ap-south-2",
-		"ap-southeast-1",
-		"ap-southeast-2",
-		"ap-southeast-3",
-		"ap-southeast-4",
-		"ca-central-1",
-		"eu-central-1",
-		"eu-central-2",
-		"eu-north-1",
-		"eu-south-1",
-		"eu-south-2",
-		"eu-west-1",
-		"eu-west-2",
-		"eu-west-3",
-		"me-central-1",
-		"me-south-1",
-		"sa-east-1",
-		"us-east-1",
-		"us-east-2",
-		"us-west-1",
-		"us-west-2",
-	}
-}
-
-func awsUsGovRegions() []string {
-	return []string{
-		"us-gov-east-1",
-		"us-gov-west-1",
+// Return the partition of a region (including wildcards) outside the
+// commercial partition, or "" if the prefix doesn't match one.
+func awsPartitionFromRegionPrefix(regionWildcard string) string {
+	for _, p := range awsPartitionRegionPrefixes {
+		if strings.HasPrefix(regionWildcard, p.prefix) {
+			return p.partition
+		}
 	}
+	return ""
 }
 
-func awsChinaRegions() []string {
-	return []string{
-		"cn-north-1",
-		"cn-northwest-1",
-	}
+// Region prefixes of the partitions other than the commercial partition. The
+// order matters, since us-iso is also a prefix of us-isob and us-isof.
+var awsPartitionRegionPrefixes = []struct {
+	prefix    string
+	partition string
+}{
+	{"us-gov", awsPartitionUsGov},
+	{"cn", awsPartitionChina},
+	{"us-isob", awsPartitionIsoB},
+	{"us-isof", awsPartitionIsoF},
+	{"us-iso", awsPartitionIso},
+	{"eu-isoe", awsPartitionIsoE},
 }
 
-func awsUsIsoRegions() []string {
-	return []string{
-		"us-iso-east-1",
-		"us-iso-west-1",
-	}
+// The last resort region of each partition, used for API calls that must go
+// to a central endpoint of the partition. These are the regions with the
+// partition's global endpoints (e.g. STS, IAM) where possible.
+var awsPartitionLastResortRegions = map[string]string{
+	awsPartitionCommercial: "us-east-1",
+	awsPartitionChina:      "cn-northwest-1",
+	awsPartitionUsGov:      "us-gov-west-1",
+	awsPartitionIso:        "us-iso-east-1",
+	awsPartitionIsoB:       "us-isob-east-1",
+	awsPartitionIsoE:       "eu-isoe-west-1",
+	awsPartitionIsoF:       "us-isof-south-1",
 }
 
-func awsUsIsobRegions() []string {
+// Prefixes of the regions in the commercial partition, used to guess the
+// partition from region wildcards in the config.
+func awsCommercialRegionPrefixes() []string {
 	return []string{
-		"us-isob-east-1",
+		"af",
+		"ap",
+		"ca",
+		"eu",
+		"il",
+		"me",
+		"sa",
+		"us",
 	}
 }
@@ -0,0 +1,207 @@
+package aws
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestMatchRegionPatterns(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		region   string
+		expected bool
+	}{
+		{[]string{"*"}, "ap-south-1", true},
+		{[]string{"us-*", "eu-*"}, "eu-west-1", true},
+		{[]string{"us-*", "eu-*"}, "ap-south-1", false},
+		{[]string{"*", "!ap-*"}, "ap-south-1", false},
+		{[]string{"*", "!ap-*"}, "us-east-1", true},
+		{[]string{"!ap-*", "*"}, "ap-south-1", false},
+		{[]string{"!ap-*"}, "us-east-1", true},
+		{[]string{"!ap-*"}, "ap-south-1", false},
+		{[]string{"!ap-*", "!eu-*"}, "eu-west-1", false},
+		{[]string{"us-*", "!us-west-1"}, "us-west-1", false},
+		{[]string{"us-*", "!us-west-1"}, "us-west-2", true},
+		{[]string{"us-east-1"}, "us-east-2", false},
+	}
+	for _, test := range tests {
+		if got := matchRegionPatterns(test.patterns, test.region); got != test.expected {
+			t.Errorf("matchRegionPatterns(%v, %q): expected %v, got %v", test.patterns, test.region, test.expected, got)
+		}
+	}
+}
+
+// Stand-in with four enabled regions.
+func newMultiRegionStubAPI(t *testing.T) *stubAPI {
+	api := newStubAPI(t)
+	var items string
+	for _, region := range []string{"us-east-1", "us-east-2", "us-west-2", "ap-south-1"} {
+		items += `<item><regionName>` + region + `</regionName><regionEndpoint>ec2.` + region + `.amazonaws.com</regionEndpoint><optInStatus>opt-in-not-required</optInStatus></item>`
+	}
+	api.on("ec2", "DescribeRegions", ec2Response("DescribeRegions", `<regionInfo>`+items+`</regionInfo>`))
+	api.on("sns", "ListTopics", queryResponse("ListTopics", `<Topics/>`))
+	return api
+}
+
+// Return the sorted regions that ListTopics was called in.
+func listTopicsRegions(api *stubAPI) string {
+	var regions []string
+	for _, r := range api.calls("sns", "ListTopics") {
+		regions = append(regions, r.Region)
+	}
+	sort.Strings(regions)
+	return strings.Join(regions, ",")
+}
+
+func TestRegionExclusions(t *testing.T) {
+	api := newMultiRegionStubAPI(t)
+	_, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_sns_topic",
+		Columns: []string{"topic_arn"},
+		Regions: []string{"*", "!ap-*"},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if got := listTopicsRegions(api); got != "us-east-1,us-east-2,us-west-2" {
+		t.Errorf("expected ListTopics in us-east-1,us-east-2,us-west-2, got %s", got)
+	}
+}
+
+func TestServiceRegionsOverride(t *testing.T) {
+	api := newMultiRegionStubAPI(t)
+	_, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_sns_topic",
+		Columns: []string{"topic_arn"},
+		Regions: []string{"*"},
+		Config:  `service_regions = { sns = ["us-east-2", "ap-*"] }`,
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if got := listTopicsRegions(api); got != "ap-south-1,us-east-2" {
+		t.Errorf("expected ListTopics in ap-south-1,us-east-2, got %s", got)
+	}
+}
+
+func TestAwsPartitionFromRegion(t *testing.T) {
+	tests := []struct {
+		region           string
+		partition        string
+		lastResortRegion string
+	}{
+		{"eu-west-1", awsPartitionCommercial, "us-east-1"},
+		{"cn-north-1", awsPartitionChina, "cn-northwest-1"},
+		{"us-gov-east-1", awsPartitionUsGov, "us-gov-west-1"},
+		{"us-iso-west-1", awsPartitionIso, "us-iso-east-1"},
+		{"us-isob-east-1", awsPartitionIsoB, "us-isob-east-1"},
+		{"eu-isoe-west-1", awsPartitionIsoE, "eu-isoe-west-1"},
+		{"us-isof-south-1", awsPartitionIsoF, "us-isof-south-1"},
+	}
+	for _, test := range tests {
+		if got := awsPartitionFromRegion(test.region); got != test.partition {
+			t.Errorf("awsPartitionFromRegion(%q): expected %s, got %s", test.region, test.partition, got)
+		}
+		if got := awsLastResortRegionFromRegionWildcard(test.region); got != test.lastResortRegion {
+			t.Errorf("awsLastResortRegionFromRegionWildcard(%q): expected %s, got %s", test.region, test.lastResortRegion, got)
+		}
+	}
+	if got := awsLastResortRegionFromRegionWildcard("us-isof-*"); got != "us-isof-south-1" {
+		t.Errorf("awsLastResortRegionFromRegionWildcard(\"us-isof-*\"): expected us-isof-south-1, got %s", got)
+	}
+}
+
+// Stand-in for a connection in the partition of the region.
+func newPartitionStubAPI(t *testing.T, partition string, region string) *stubAPI {
+	api := newStubAPI(t)
+	api.on("sts", "GetCallerIdentity", queryResponse("GetCallerIdentity", `<Arn>arn:`+partition+`:iam::`+stubAccountId+`:user/test</Arn><UserId>AIDATEST</UserId><Account>`+stubAccountId+`</Account>`))
+	api.on("ec2", "DescribeRegions", ec2Response("DescribeRegions", `<regionInfo><item><regionName>`+region+`</regionName><regionEndpoint>ec2.`+region+`.amazonaws.com</regionEndpoint><optInStatus>opt-in-not-required</optInStatus></item></regionInfo>`))
+	return api
+}
+
+func TestGlobalServicePartitions(t *testing.T) {
+	tests := []struct {
+		partition     string
+		defaultRegion string
+		// Signing region of the IAM endpoint of the partition
+		iamRegion string
+	}{
+		{awsPartitionCommercial, "eu-west-1", "us-east-1"},
+		{awsPartitionChina, "cn-northwest-1", "cn-north-1"},
+		{awsPartitionUsGov, "us-gov-east-1", "us-gov-west-1"},
+		{awsPartitionIso, "us-iso-west-1", "us-iso-east-1"},
+		{awsPartitionIsoB, "us-isob-east-1", "us-isob-east-1"},
+	}
+	for _, test := range tests {
+		t.Run(test.partition, func(t *testing.T) {
+			api := newPartitionStubAPI(t, test.partition, test.defaultRegion)
+			api.on("iam", "ListAccountAliases", queryResponse("ListAccountAliases", `<AccountAliases/><IsTruncated>false</IsTruncated>`))
+			rows, err := runTableQuery(t, api, tableQuery{
+				Table:   "aws_account",
+				Columns: []string{"arn", "partition", "akas"},
+				Regions: []string{test.defaultRegion},
+				Config:  `default_region = "` + test.defaultRegion + `"`,
+			})
+			if err != nil {
+				t.Fatalf("query failed: %v", err)
+			}
+			if len(rows) != 1 {
+				t.Fatalf("expected 1 row, got %d", len(rows))
+			}
+			arn := "arn:" + test.partition + ":::" + stubAccountId
+			if rows[0]["arn"] != arn || rows[0]["partition"] != test.partition {
+				t.Errorf("expected arn %s in partition %s, got %v in %v", arn, test.partition, rows[0]["arn"], rows[0]["partition"])
+			}
+			if akas, ok := rows[0]["akas"].([]interface{}); !ok || len(akas) != 1 || akas[0] != arn {
+				t.Errorf("expected akas [%s], got %v", arn, rows[0]["akas"])
+			}
+			calls := api.calls("iam", "ListAccountAliases")
+			if len(calls) != 1 || calls[0].Region != test.iamRegion {
+				t.Errorf("expected 1 ListAccountAliases call signed for %s, got %v", test.iamRegion, calls)
+			}
+		})
+	}
+}
+
+func TestGlobalServiceNotInPartition(t *testing.T) {
+	api := newPartitionStubAPI(t, awsPartitionUsGov, "us-gov-west-1")
+
+	// CloudFront is not in the GovCloud partition, and Global Accelerator is
+	// only in the commercial partition, so there is nothing to list, and no
+	// request is made.
+	for _, table := range []string{"aws_cloudfront_distribution", "aws_globalaccelerator_accelerator"} {
+		rows, err := runTableQuery(t, api, tableQuery{
+			Table:   table,
+			Columns: []string{"arn"},
+			Regions: []string{"us-gov-west-1"},
+			Config:  `default_region = "us-gov-west-1"`,
+		})
+		if err != nil {
+			t.Fatalf("%s: query failed: %v", table, err)
+		}
+		if len(rows) != 0 {
+			t.Errorf("%s: expected no rows, got %d", table, len(rows))
+		}
+	}
+}
+
+func TestServiceRegionsPartitionWithoutServices(t *testing.T) {
+	// The region catalog has no services for aws-iso-e, so regional services
+	// are assumed to be in the last resort region of the partition.
+	api := newPartitionStubAPI(t, awsPartitionIsoE, "eu-isoe-west-1")
+	api.on("sns", "ListTopics", queryResponse("ListTopics", `<Topics/>`))
+	_, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_sns_topic",
+		Columns: []string{"topic_arn"},
+		Regions: []string{"eu-isoe-west-1"},
+		Config:  `default_region = "eu-isoe-west-1"`,
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	calls := api.calls("sns", "ListTopics")
+	if len(calls) != 1 || calls[0].Region != "eu-isoe-west-1" {
+		t.Errorf("expected 1 ListTopics call in eu-isoe-west-1, got %v", calls)
+	}
+}
@@ -10,7 +10,6 @@ import (
 
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
-	"github.com/turbot/steampipe-plugin-sdk/v5/rate_limiter"
 )
 
 const pluginName = "steampipe-plugin-aws"
@@ -39,50 +38,21 @@ func Plugin(ctx context.Context) *plugin.Plugin {
 		DefaultIgnoreConfig: &plugin.IgnoreConfig{
 			ShouldIgnoreErrorFunc: shouldIgnoreErrorPluginDefault(),
 		},
-		ConnectionKeyColumns: []plugin.ConnectionKeyColumn{
-			{
-				Name:    "account_id",
-				Hydrate: accountIdForConnection,
-			},
-		},
 		ConnectionConfigSchema: &plugin.ConnectionConfigSchema{
 			NewInstance: ConfigInstance,
 		},
-		RateLimiters: []*rate_limiter.Definition{
-			{
-				Name:       "aws_servicequotas_list_aws_default_service_quotas",
-				FillRate:   5,
-				BucketSize: 5,
-				Scope:      []string{"connection", "region", "service", "action"},
-				Where:      "service = 'servicequotas' and action = 'ListAWSDefaultServiceQuotas'",
-			},
-			{
-				Name:       "aws_servicequotas_list_service_quotas",
-				FillRate:   5,
-				BucketSize: 5,
-				Scope:      []string{"connection", "region", "service", "action"},
-				Where:      "service = 'servicequotas' and action = 'ListServiceQuotas'",
-			},
-			// We still get throttled heavily by AWS when listing tags, even though
-			// we are within AWS limits per https://docs.aws.amazon.com/servicequotas/latest/userguide/reference_limits.html.
-			// But this limiter still significantly reduces the numbers we get.
-			{
-				Name:       "aws_servicequotas_list_tags_for_resource",
-				FillRate:   5,
-				BucketSize: 5,
-				Scope:      []string{"connection", "region", "service", "action"},
-				Where:      "service = 'servicequotas' and action = 'ListTagsForResource'",
-			},
-		},
+		ConnectionConfigChangedFunc: connectionConfigChanged,
 		TableMap: map[string]*plugin.Table{
 			"aws_accessanalyzer_analyzer":                                  tableAwsAccessAnalyzer(ctx),
 			"aws_accessanalyzer_finding":                                   tableAwsAccessAnalyzerFinding(ctx),
 			"aws_account":                                                  tableAwsAccount(ctx),
 			"aws_account_alternate_contact":                                tableAwsAccountAlternateContact(ctx),
 			"aws_account_contact":                                          tableAwsAccountContact(ctx),
+			"aws_account_region":                                           tableAwsAccountRegion(ctx),
 			"aws_acm_certificate":                                          tableAwsAcmCertificate(ctx),
 			"aws_acmpca_certificate_authority":                             tableAwsAcmPcaCertificateAuthority(ctx),
 			"aws_amplify_app":                                              tableAwsAmplifyApp(ctx),
+			"aws_api_call_stat":                                            tableAwsAPICallStat(ctx),
 			"aws_api_gateway_api_key":                                      tableAwsAPIGatewayAPIKey(ctx),
 			"aws_api_gateway_authorizer":                                   tableAwsAPIGatewayAuthorizer(ctx),
 			"aws_api_gateway_domain_name":                                  tableAwsAPIGatewayDomainName(ctx),
@@ -165,6 +135,7 @@ func Plugin(ctx context.Context) *plugin.Plugin {
 			"aws_config_conformance_pack":                                  tableAwsConfigConformancePack(ctx),
 			"aws_config_retention_configuration":                           tableAwsConfigRetentionConfiguration(ctx),
 			"aws_config_rule":                                              tableAwsConfigRule(ctx),
+			"aws_connection_diagnostic":                                    tableAwsConnectionDiagnostic(ctx),
 			"aws_cost_by_account_daily":                                    tableAwsCostByLinkedAccountDaily(ctx),
 			"aws_cost_by_account_monthly":                                  tableAwsCostByLinkedAccountMonthly(ctx),
 			"aws_cost_by_record_type_daily":                                tableAwsCostByRecordTypeDaily(ctx),
@@ -323,12 +294,17 @@ func Plugin(ctx context.Context) *plugin.Plugin {
 			"aws_iam_account_password_policy":                              tableAwsIamAccountPasswordPolicy(ctx),
 			"aws_iam_account_summary":                                      tableAwsIamAccountSummary(ctx),
 			"aws_iam_action":                                               tableAwsIamAction(ctx),
+			"aws_iam_condition_key":                                        tableAwsIamConditionKey(ctx),
 			"aws_iam_credential_report":                                    tableAwsIamCredentialReport(ctx),
 			"aws_iam_group":                                                tableAwsIamGroup(ctx),
 			"aws_iam_open_id_connect_provider":                             tableAwsIamOpenIdConnectProvider(ctx),
 			"aws_iam_policy":                                               tableAwsIamPolicy(ctx),
 			"aws_iam_policy_attachment":                                    tableAwsIamPolicyAttachment(ctx),
+			"aws_iam_policy_action":                                        tableAwsIamPolicyAction(ctx),
+			"aws_iam_policy_evaluation":                                    tableAwsIamPolicyEvaluation(ctx),
+			"aws_iam_policy_finding":                                       tableAwsIamPolicyFinding(ctx),
 			"aws_iam_policy_simulator":                                     tableAwsIamPolicySimulator(ctx),
+			"aws_iam_principal_effective_policy":                           tableAwsIamPrincipalEffectivePolicy(ctx),
 			"aws_iam_role":                                                 tableAwsIamRole(ctx),
 			"aws_iam_saml_provider":                                        tableAwsIamSamlProvider(ctx),
 			"aws_iam_server_certificate":                                   tableAwsIamServerCertificate(ctx),
@@ -338,6 +314,7 @@ func Plugin(ctx context.Context) *plugin.Plugin {
 			"aws_identitystore_group":                                      tableAwsIdentityStoreGroup(ctx),
 			"aws_identitystore_group_membership":                           tableAwsIdentityStoreGroupMembership(ctx),
 			"aws_identitystore_user":                                       tableAwsIdentityStoreUser(ctx),
+			"aws_ignored_error":                                            tableAwsIgnoredError(ctx),
 			"aws_inspector2_coverage":                                      tableAwsInspector2Coverage(ctx),
 			"aws_inspector2_coverage_statistics":                           tableAwsInspector2CoverageStatistics(ctx),
 			"aws_inspector2_finding":                                       tableAwsInspector2Finding(ctx),
@@ -478,6 +455,7 @@ func Plugin(ctx context.Context) *plugin.Plugin {
 			"aws_service_discovery_instance":                               tableAwsServiceDiscoveryInstance(ctx),
 			"aws_service_discovery_namespace":                              tableAwsServiceDiscoveryNamespace(ctx),
 			"aws_service_discovery_service":                                tableAwsServiceDiscoveryService(ctx),
+			"aws_service_region_availability":                              tableAwsServiceRegionAvailability(ctx),
 			"aws_servicequotas_default_service_quota":                      tableAwsServiceQuotasDefaultServiceQuota(ctx),
 			"aws_servicequotas_service":                                    tableAwsServiceQuotasService(ctx),
 			"aws_servicequotas_service_quota":                              tableAwsServiceQuotasServiceQuota(ctx),
@@ -573,12 +551,3 @@ func Plugin(ctx context.Context) *plugin.Plugin {
 
 	return p
 }
-
-func accountIdForConnection(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (any, error) {
-	commonColumnData, err := getCommonColumnsMemoized(ctx, d, h)
-	if err != nil {
-		return nil, err
-	}
-
-	return commonColumnData.(*awsCommonColumnData).AccountId, nil
-}
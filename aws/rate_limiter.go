@@ -0,0 +1,228 @@
+package aws
+
+// Rate limiters
+//
+// The plugin ships default limiters for the APIs that are most commonly
+// throttled in large accounts, and connections can add their own with
+// `rate_limiter` blocks. They are enforced by the plugin on every API call
+// made by the clients of a connection, rather than registered with the
+// Steampipe SDK, since SDK limiters are defined for the whole plugin and can't
+// be replaced per connection:
+// - A block named after a default limiter inherits its service, action, rates
+//   and scope. Only the values to change need to be set.
+// - A block replaces the default limiter of the same name, or of the same
+//   service and action, so it can raise the rate as well as lower it.
+// - Any other block adds a new limiter for the connection.
+// - The service is matched against the `service` tags of the tables (e.g.
+//   "ce", "logs", "states"), using getServiceTagForServiceID to map the AWS
+//   SDK service ID of each call to its tag.
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sync"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+	"golang.org/x/time/rate"
+)
+
+// awsRateLimiter limits the API calls of a connection to a service. Action is
+// a pattern using "*" and "?" wildcards, an empty action matches every action
+// of the service. Scope lists the values that get their own token bucket, like
+// the scope of Steampipe limiters.
+type awsRateLimiter struct {
+	Name       string
+	Service    string
+	Action     string
+	FillRate   float64
+	BucketSize int
+	Scope      []string
+}
+
+// Default limiters, based on the documented (or observed) throttling limits
+// for each API. Limits are per account, and for regional services per region.
+var defaultRateLimiters = []awsRateLimiter{
+	{
+		Name:       "aws_servicequotas_list_aws_default_service_quotas",
+		Service:    "servicequotas",
+		Action:     "ListAWSDefaultServiceQuotas",
+		FillRate:   5,
+		BucketSize: 5,
+		Scope:      []string{"connection", "region", "service", "action"},
+	},
+	{
+		Name:       "aws_servicequotas_list_service_quotas",
+		Service:    "servicequotas",
+		Action:     "ListServiceQuotas",
+		FillRate:   5,
+		BucketSize: 5,
+		Scope:      []string{"connection", "region", "service", "action"},
+	},
+	// We still get throttled heavily by AWS when listing tags, even though
+	// we are within AWS limits per https://docs.aws.amazon.com/servicequotas/latest/userguide/reference_limits.html.
+	// But this limiter still significantly reduces the numbers we get.
+	{
+		Name:       "aws_servicequotas_list_tags_for_resource",
+		Service:    "servicequotas",
+		Action:     "ListTagsForResource",
+		FillRate:   5,
+		BucketSize: 5,
+		Scope:      []string{"connection", "region", "service", "action"},
+	},
+	// IAM is a global service, and the request limit is shared by all IAM
+	// APIs in the account.
+	{
+		Name:       "aws_iam",
+		Service:    "iam",
+		FillRate:   15,
+		BucketSize: 15,
+		Scope:      []string{"connection", "service"},
+	},
+	// EC2 uses a token bucket for non-mutating (Describe*) actions, shared by
+	// all of them in the account and region.
+	// https://docs.aws.amazon.com/AWSEC2/latest/APIReference/throttling.html
+	{
+		Name:       "aws_ec2_describe",
+		Service:    "ec2",
+		Action:     "Describe*",
+		FillRate:   20,
+		BucketSize: 100,
+		Scope:      []string{"connection", "region", "service"},
+	},
+	// LookupEvents is limited to 2 requests per second per account and region.
+	{
+		Name:       "aws_cloudtrail_lookup_events",
+		Service:    "cloudtrail",
+		Action:     "LookupEvents",
+		FillRate:   2,
+		BucketSize: 2,
+		Scope:      []string{"connection", "region", "service", "action"},
+	},
+	// Organizations is a global service with low request limits shared by
+	// all of its APIs.
+	{
+		Name:       "aws_organizations",
+		Service:    "organizations",
+		FillRate:   5,
+		BucketSize: 5,
+		Scope:      []string{"connection", "service"},
+	},
+}
+
+// Scope of the limiters added by the connection config.
+var connectionRateLimiterScope = []string{"connection", "region", "service"}
+
+// Resolve the limiters of a connection: the rate_limiter blocks of the
+// connection config, filling in the values inherited from the default limiter
+// of the same name, and the default limiters they don't replace.
+func getConnectionRateLimiters(awsSpcConfig awsConfig) ([]awsRateLimiter, error) {
+	var limiters []awsRateLimiter
+	replaced := make([]bool, len(defaultRateLimiters))
+	for _, c := range awsSpcConfig.RateLimiters {
+		l := awsRateLimiter{Name: c.Name, Scope: connectionRateLimiterScope}
+		for _, d := range defaultRateLimiters {
+			if d.Name == c.Name {
+				l = d
+				break
+			}
+		}
+		if c.Service != nil {
+			l.Service = *c.Service
+		}
+		if c.Action != nil {
+			l.Action = *c.Action
+		}
+		if c.FillRate != nil {
+			l.FillRate = *c.FillRate
+		}
+		if c.BucketSize != nil {
+			l.BucketSize = *c.BucketSize
+		}
+
+		if l.Service == "" {
+			return nil, fmt.Errorf("rate_limiter %q must set service, it does not match a default limiter", c.Name)
+		}
+		if l.FillRate <= 0 {
+			return nil, fmt.Errorf("rate_limiter %q must have a fill_rate greater than 0", c.Name)
+		}
+		if l.BucketSize <= 0 {
+			return nil, fmt.Errorf("rate_limiter %q must have a bucket_size greater than 0", c.Name)
+		}
+		if _, err := path.Match(l.Action, ""); err != nil {
+			return nil, fmt.Errorf("rate_limiter %q has an invalid action pattern %q: %v", c.Name, l.Action, err)
+		}
+
+		for i, d := range defaultRateLimiters {
+			if d.Name == l.Name || (normalizeEndpointServiceKey(d.Service) == normalizeEndpointServiceKey(l.Service) && d.Action == l.Action) {
+				replaced[i] = true
+			}
+		}
+		limiters = append(limiters, l)
+	}
+	for i, d := range defaultRateLimiters {
+		if !replaced[i] {
+			limiters = append(limiters, d)
+		}
+	}
+	return limiters, nil
+}
+
+// Check whether the limiter applies to an API call, given the SDK service ID
+// and operation name.
+func (l awsRateLimiter) matches(serviceID string, action string) bool {
+	if normalizeEndpointServiceKey(l.Service) != getServiceTagForServiceID(serviceID) {
+		return false
+	}
+	if l.Action == "" {
+		return true
+	}
+	ok, _ := path.Match(l.Action, action)
+	return ok
+}
+
+// There is one limiter instance per connection limiter and scope values (e.g.
+// per region, shared by every action it matches like the AWS token buckets).
+// Instances live for the life of the plugin. The key includes the rates, so a
+// connection config change gets fresh limiters rather than the old settings.
+var rateLimiterInstances sync.Map
+
+func getRateLimiterInstance(connectionName string, l awsRateLimiter, region string, action string) *rate.Limiter {
+	key := fmt.Sprintf("%s/%s/%g/%d", connectionName, l.Name, l.FillRate, l.BucketSize)
+	for _, s := range l.Scope {
+		switch s {
+		case "region":
+			key += "/" + region
+		case "action":
+			key += "/" + action
+		}
+	}
+	if i, ok := rateLimiterInstances.Load(key); ok {
+		return i.(*rate.Limiter)
+	}
+	i, _ := rateLimiterInstances.LoadOrStore(key, rate.NewLimiter(rate.Limit(l.FillRate), l.BucketSize))
+	return i.(*rate.Limiter)
+}
+
+// Build the API option that waits for the limiters of the connection before
+// each API call. The wait happens once per operation, retries are left to the
+// retryer backoff.
+func rateLimiterAPIOption(connectionName string, limiters []awsRateLimiter) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("SteampipeConnectionRateLimiter", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			service := awsmiddleware.GetServiceID(ctx)
+			action := awsmiddleware.GetOperationName(ctx)
+			region := awsmiddleware.GetRegion(ctx)
+			for _, l := range limiters {
+				if !l.matches(service, action) {
+					continue
+				}
+				if err := getRateLimiterInstance(connectionName, l, region, action).Wait(ctx); err != nil {
+					return middleware.InitializeOutput{}, middleware.Metadata{}, err
+				}
+			}
+			return next.HandleInitialize(ctx, in)
+		}), middleware.After)
+	}
+}
@@ -0,0 +1,108 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestAwsRateLimiterMatches(t *testing.T) {
+	tests := []struct {
+		service   string
+		serviceID string
+		action    string
+		expected  bool
+	}{
+		{"iam", "IAM", "ListRoles", true},
+		{"servicequotas", "Service Quotas", "ListServiceQuotas", true},
+		{"ce", "Cost Explorer", "GetCostForecast", true},
+		{"logs", "CloudWatch Logs", "DescribeLogGroups", true},
+		{"states", "SFN", "ListExecutions", true},
+		{"elasticloadbalancing", "Elastic Load Balancing v2", "DescribeLoadBalancers", true},
+		{"elasticloadbalancing", "Elastic Load Balancing", "DescribeLoadBalancers", true},
+		{"elasticfilesystem", "EFS", "DescribeFileSystems", true},
+		{"elasticmapreduce", "EMR", "ListClusters", true},
+		{"es", "Elasticsearch Service", "DescribeElasticsearchDomains", true},
+		{"es", "OpenSearch", "DescribeDomains", true},
+		{"cognito-idp", "Cognito Identity Provider", "ListUserPools", true},
+		{"costexplorer", "Cost Explorer", "GetCostForecast", false},
+		{"ec2", "IAM", "ListRoles", false},
+	}
+	for _, test := range tests {
+		l := awsRateLimiter{Name: "test", Service: test.service}
+		if got := l.matches(test.serviceID, test.action); got != test.expected {
+			t.Errorf("service %q matches %q: expected %v, got %v", test.service, test.serviceID, test.expected, got)
+		}
+	}
+
+	l := awsRateLimiter{Name: "test", Service: "ec2", Action: "Describe*"}
+	if !l.matches("EC2", "DescribeInstances") || l.matches("EC2", "RunInstances") {
+		t.Errorf("action pattern %q does not match as expected", l.Action)
+	}
+}
+
+func TestGetConnectionRateLimiters(t *testing.T) {
+	getLimiter := func(limiters []awsRateLimiter, name string) *awsRateLimiter {
+		for _, l := range limiters {
+			if l.Name == name {
+				return &l
+			}
+		}
+		return nil
+	}
+
+	// Without rate_limiter blocks, the connection gets the default limiters
+	limiters, err := getConnectionRateLimiters(awsConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limiters) != len(defaultRateLimiters) {
+		t.Fatalf("expected %d default limiters, got %d", len(defaultRateLimiters), len(limiters))
+	}
+
+	// A block named after a default limiter replaces it, inheriting the
+	// values it doesn't set
+	limiters, err = getConnectionRateLimiters(awsConfig{RateLimiters: []awsRateLimiterConfig{
+		{Name: "aws_ec2_describe", FillRate: aws.Float64(50)},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limiters) != len(defaultRateLimiters) {
+		t.Fatalf("expected the default limiter to be replaced, got %d limiters", len(limiters))
+	}
+	l := getLimiter(limiters, "aws_ec2_describe")
+	if l == nil || l.FillRate != 50 || l.BucketSize != 100 || l.Service != "ec2" || l.Action != "Describe*" {
+		t.Errorf("expected the default limiter with fill_rate 50, got %+v", l)
+	}
+
+	// A block with the service and action of a default limiter replaces it,
+	// so the rate can be raised
+	limiters, err = getConnectionRateLimiters(awsConfig{RateLimiters: []awsRateLimiterConfig{
+		{Name: "iam", Service: aws.String("iam"), FillRate: aws.Float64(100), BucketSize: aws.Int(100)},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if getLimiter(limiters, "aws_iam") != nil {
+		t.Errorf("expected aws_iam to be replaced by the iam limiter")
+	}
+	if l := getLimiter(limiters, "iam"); l == nil || l.FillRate != 100 {
+		t.Errorf("expected the iam limiter with fill_rate 100, got %+v", l)
+	}
+
+	// Other blocks add a limiter
+	limiters, err = getConnectionRateLimiters(awsConfig{RateLimiters: []awsRateLimiterConfig{
+		{Name: "logs", Service: aws.String("logs"), FillRate: aws.Float64(5), BucketSize: aws.Int(5)},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(limiters) != len(defaultRateLimiters)+1 || getLimiter(limiters, "logs") == nil {
+		t.Errorf("expected the logs limiter to be added to the default limiters, got %+v", limiters)
+	}
+
+	if _, err := getConnectionRateLimiters(awsConfig{RateLimiters: []awsRateLimiterConfig{{Name: "missing_service", FillRate: aws.Float64(5), BucketSize: aws.Int(5)}}}); err == nil {
+		t.Errorf("expected an error for a limiter without a service")
+	}
+}
@@ -0,0 +1,302 @@
+package aws
+
+// Region catalog
+//
+// The region catalog lists the regions of each AWS partition, the regions
+// each service is available in, and the FIPS endpoints of each partition. It
+// decides which regions are queried for each service, and which regions to
+// assume when the region list of the account is not available (see
+// multi_region.go). With use_fips_endpoint, services are only queried in the
+// regions they have a FIPS endpoint in (see fips_endpoint.go).
+//
+// The catalog is generated from the AWS endpoints model by
+// scripts/generate_region_catalog and embedded in the plugin. New regions or
+// services can be added before the next plugin release with a JSON file in
+// the same format, set with region_catalog_file in the connection config.
+// Partitions, regions and services in the file are merged over the embedded
+// catalog, so the file only needs the entries that changed, e.g.
+//
+//	{
+//	  "partitions": [
+//	    {
+//	      "partition": "aws",
+//	      "regions": { "xx-east-1": "New Region" },
+//	      "services": { "ec2": ["us-east-1", "xx-east-1"] }
+//	    }
+//	  ]
+//	}
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// Partition IDs, as used in ARNs.
+const (
+	awsPartitionCommercial = "aws"
+	awsPartitionChina      = "aws-cn"
+	awsPartitionUsGov      = "aws-us-gov"
+	awsPartitionIso        = "aws-iso"
+	awsPartitionIsoB       = "aws-iso-b"
+	awsPartitionIsoE       = "aws-iso-e"
+	awsPartitionIsoF       = "aws-iso-f"
+)
+
+//go:embed region_catalog.json
+var embeddedRegionCatalogJSON []byte
+
+type regionCatalog struct {
+	Source     string                    `json:"source"`
+	Partitions []*regionCatalogPartition `json:"partitions"`
+}
+
+type regionCatalogPartition struct {
+	Partition   string `json:"partition"`
+	Name        string `json:"name"`
+	RegionRegex string `json:"region_regex"`
+	// Region descriptions, by region.
+	Regions map[string]string `json:"regions"`
+	// Signing region of services with a single global endpoint, by service.
+	GlobalServices map[string]string `json:"global_services"`
+	// Regions the service is available in, by service endpoint ID.
+	Services map[string][]string `json:"services"`
+	// Regions the service has a FIPS endpoint in, by service endpoint ID.
+	FipsServices map[string][]string `json:"fips_services"`
+	// Hostnames of the FIPS endpoints of the partition, sorted.
+	FipsHostnames []string `json:"fips_hostnames"`
+
+	// Services set by the region_catalog_file, rather than embedded.
+	overriddenServices map[string]bool
+}
+
+var (
+	embeddedRegionCatalogOnce sync.Once
+	embeddedRegionCatalog     *regionCatalog
+)
+
+// Get the catalog embedded in the plugin. It must not be modified.
+func getEmbeddedRegionCatalog() *regionCatalog {
+	embeddedRegionCatalogOnce.Do(func() {
+		catalog, err := parseRegionCatalog(embeddedRegionCatalogJSON)
+		if err != nil {
+			// The embedded catalog is generated, so this is a build problem.
+			panic(fmt.Sprintf("invalid embedded region catalog: %v", err))
+		}
+		embeddedRegionCatalog = catalog
+	})
+	return embeddedRegionCatalog
+}
+
+func parseRegionCatalog(data []byte) (*regionCatalog, error) {
+	var catalog regionCatalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, err
+	}
+	for _, p := range catalog.Partitions {
+		if p.Partition == "" {
+			return nil, fmt.Errorf("partition is required for each entry in partitions")
+		}
+	}
+	return &catalog, nil
+}
+
+// Load the region catalog for a connection: the embedded catalog, with the
+// catalog file (if any) merged over it.
+func loadRegionCatalog(path string) (*regionCatalog, error) {
+	embedded := getEmbeddedRegionCatalog()
+	if path == "" {
+		return embedded, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read region_catalog_file: %v", err)
+	}
+	override, err := parseRegionCatalog(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid region_catalog_file %s: %v", path, err)
+	}
+
+	catalog := &regionCatalog{Source: embedded.Source + ", " + path}
+	for _, p := range embedded.Partitions {
+		catalog.Partitions = append(catalog.Partitions, p.copy())
+	}
+	for _, o := range override.Partitions {
+		p, ok := catalog.partition(o.Partition)
+		if !ok {
+			p = &regionCatalogPartition{Partition: o.Partition}
+			catalog.Partitions = append(catalog.Partitions, p)
+		}
+		p.merge(o)
+	}
+	return catalog, nil
+}
+
+func (p *regionCatalogPartition) copy() *regionCatalogPartition {
+	c := *p
+	c.Regions = map[string]string{}
+	for k, v := range p.Regions {
+		c.Regions[k] = v
+	}
+	c.GlobalServices = map[string]string{}
+	for k, v := range p.GlobalServices {
+		c.GlobalServices[k] = v
+	}
+	c.Services = map[string][]string{}
+	for k, v := range p.Services {
+		c.Services[k] = v
+	}
+	c.FipsServices = map[string][]string{}
+	for k, v := range p.FipsServices {
+		c.FipsServices[k] = v
+	}
+	c.FipsHostnames = append([]string{}, p.FipsHostnames...)
+	c.overriddenServices = map[string]bool{}
+	return &c
+}
+
+// Merge the entries of o into the partition. Services in o replace the
+// region list of the service, and FIPS services the FIPS region list.
+func (p *regionCatalogPartition) merge(o *regionCatalogPartition) {
+	if o.Name != "" {
+		p.Name = o.Name
+	}
+	if o.RegionRegex != "" {
+		p.RegionRegex = o.RegionRegex
+	}
+	if p.Regions == nil {
+		p.Regions = map[string]string{}
+	}
+	for k, v := range o.Regions {
+		p.Regions[k] = v
+	}
+	if p.GlobalServices == nil {
+		p.GlobalServices = map[string]string{}
+	}
+	for k, v := range o.GlobalServices {
+		p.GlobalServices[k] = v
+	}
+	if p.Services == nil {
+		p.Services = map[string][]string{}
+	}
+	if p.overriddenServices == nil {
+		p.overriddenServices = map[string]bool{}
+	}
+	for k, v := range o.Services {
+		p.Services[k] = v
+		p.overriddenServices[k] = true
+	}
+	if p.FipsServices == nil {
+		p.FipsServices = map[string][]string{}
+	}
+	for k, v := range o.FipsServices {
+		p.FipsServices[k] = v
+	}
+	if len(o.FipsHostnames) > 0 {
+		p.FipsHostnames = append(p.FipsHostnames, o.FipsHostnames...)
+		sort.Strings(p.FipsHostnames)
+	}
+}
+
+// Get a partition of the catalog by ID, e.g. aws-cn.
+func (c *regionCatalog) partition(id string) (*regionCatalogPartition, bool) {
+	for _, p := range c.Partitions {
+		if p.Partition == id {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// List the regions of the partition, sorted.
+func (p *regionCatalogPartition) regionIds() []string {
+	regions := make([]string, 0, len(p.Regions))
+	for region := range p.Regions {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// List the services of the partition, sorted.
+func (p *regionCatalogPartition) serviceIds() []string {
+	services := make([]string, 0, len(p.Services))
+	for service := range p.Services {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	return services
+}
+
+// Check if a service has a FIPS endpoint in a region of the partition.
+func (p *regionCatalogPartition) hasFipsEndpoint(serviceID, region string) bool {
+	for _, r := range p.FipsServices[serviceID] {
+		if r == region {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if a hostname is a FIPS endpoint of any partition. Hostnames under a
+// FIPS endpoint (e.g. virtual-hosted S3 buckets) count too.
+func (c *regionCatalog) isFipsHostname(hostname string) bool {
+	for {
+		for _, p := range c.Partitions {
+			i := sort.SearchStrings(p.FipsHostnames, hostname)
+			if i < len(p.FipsHostnames) && p.FipsHostnames[i] == hostname {
+				return true
+			}
+		}
+		dot := strings.Index(hostname, ".")
+		if dot < 0 {
+			return false
+		}
+		hostname = hostname[dot+1:]
+	}
+}
+
+// List the regions of a partition from the embedded catalog. Used where the
+// connection is not available, e.g. for hard-coded region exclusions.
+func awsRegionsForPartition(partitionID string) []string {
+	p, ok := getEmbeddedRegionCatalog().partition(partitionID)
+	if !ok {
+		return []string{}
+	}
+	return p.regionIds()
+}
+
+// The region catalog is loaded once per connection.
+var getRegionCatalogCached = plugin.HydrateFunc(getRegionCatalogUncached).Memoize()
+
+// Get the region catalog for the connection, including region_catalog_file.
+func getRegionCatalog(ctx context.Context, d *plugin.QueryData) (*regionCatalog, error) {
+	i, err := getRegionCatalogCached(ctx, d, nil)
+	if err != nil {
+		return nil, err
+	}
+	return i.(*regionCatalog), nil
+}
+
+func getRegionCatalogUncached(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	awsSpcConfig := GetConfig(d.Connection)
+	path := ""
+	if awsSpcConfig.RegionCatalogFile != nil {
+		path = *awsSpcConfig.RegionCatalogFile
+	}
+	catalog, err := loadRegionCatalog(path)
+	if err != nil {
+		plugin.Logger(ctx).Error("getRegionCatalogUncached", "connection_name", d.Connection.Name, "region_catalog_file", path, "error", err)
+		return nil, err
+	}
+	plugin.Logger(ctx).Debug("getRegionCatalogUncached", "connection_name", d.Connection.Name, "source", catalog.Source)
+	return catalog, nil
+}
@@ -0,0 +1,73 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/turbot/go-kit/helpers"
+)
+
+func TestEmbeddedRegionCatalog(t *testing.T) {
+	catalog := getEmbeddedRegionCatalog()
+	for _, id := range []string{awsPartitionCommercial, awsPartitionChina, awsPartitionUsGov, awsPartitionIso, awsPartitionIsoB} {
+		if _, ok := catalog.partition(id); !ok {
+			t.Errorf("partition %s is missing", id)
+		}
+	}
+
+	aws, _ := catalog.partition(awsPartitionCommercial)
+	if !helpers.StringSliceContains(aws.Services["ec2"], "us-east-1") {
+		t.Errorf("expected ec2 to be available in us-east-1")
+	}
+	if got := aws.GlobalServices["iam"]; got != "us-east-1" {
+		t.Errorf("expected iam to be a global service signed for us-east-1, got %q", got)
+	}
+	if helpers.StringSliceContains(awsRegionsForPartition(awsPartitionChina), "us-east-1") {
+		t.Errorf("expected China regions only for %s", awsPartitionChina)
+	}
+}
+
+func TestLoadRegionCatalogFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	data := `{
+  "partitions": [
+    {
+      "partition": "aws",
+      "regions": { "xx-east-1": "New Region" },
+      "services": { "sns": ["us-east-1", "xx-east-1"] }
+    }
+  ]
+}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	catalog, err := loadRegionCatalog(path)
+	if err != nil {
+		t.Fatalf("loadRegionCatalog: %v", err)
+	}
+	aws, _ := catalog.partition(awsPartitionCommercial)
+	if got := aws.Regions["xx-east-1"]; got != "New Region" {
+		t.Errorf("expected the new region to be added, got %q", got)
+	}
+	if _, ok := aws.Regions["us-east-1"]; !ok {
+		t.Errorf("expected the embedded regions to be kept")
+	}
+	if got := aws.Services["sns"]; len(got) != 2 || !aws.overriddenServices["sns"] {
+		t.Errorf("expected the sns regions to be replaced, got %v", got)
+	}
+	if len(aws.Services["ec2"]) == 0 || aws.overriddenServices["ec2"] {
+		t.Errorf("expected the embedded ec2 regions to be kept")
+	}
+
+	// The embedded catalog is shared, so it must not change
+	embedded, _ := getEmbeddedRegionCatalog().partition(awsPartitionCommercial)
+	if _, ok := embedded.Regions["xx-east-1"]; ok {
+		t.Errorf("the embedded catalog was modified")
+	}
+
+	if _, err := loadRegionCatalog(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,351 @@
+package aws
+
+// IAM Roles Anywhere credentials
+//
+// The AWS SDK for Go does not include a credentials provider for IAM Roles
+// Anywhere. AWS publishes a signing helper to be used as a credential_process,
+// but that needs a shared AWS config file and a binary on the host. Instead,
+// this provider calls the Roles Anywhere CreateSession API directly.
+//
+// CreateSession is signed with the private key of an X.509 certificate rather
+// than AWS credentials. The signature is the same as SigV4, except:
+// - The algorithm is AWS4-X509-RSA-SHA256 or AWS4-X509-ECDSA-SHA256.
+// - The credential in the Authorization header is the certificate serial
+//   number (in decimal) instead of an access key ID.
+// - The string to sign is signed directly by the private key, rather than
+//   through a chain of HMAC derived keys.
+// - The certificate (and optional chain) are sent in the X-Amz-X509 and
+//   X-Amz-X509-Chain headers as base64 encoded DER.
+//
+// Reference: https://docs.aws.amazon.com/rolesanywhere/latest/userguide/authentication-sign-process.html
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+const (
+	rolesAnywhereSigningName      = "rolesanywhere"
+	rolesAnywhereRSAAlgorithm     = "AWS4-X509-RSA-SHA256"
+	rolesAnywhereECDSAAlgorithm   = "AWS4-X509-ECDSA-SHA256"
+	rolesAnywhereTimeFormat       = "20060102T150405Z"
+	rolesAnywhereShortTimeFormat  = "20060102"
+	rolesAnywhereDefaultDuration  = time.Hour
+	rolesAnywhereCreateSessionURI = "/sessions"
+)
+
+// RolesAnywhereCredentialsProvider retrieves credentials from the IAM Roles
+// Anywhere CreateSession API. It should be wrapped in an
+// aws.CredentialsCache, since every call to Retrieve creates a new session.
+type RolesAnywhereCredentialsProvider struct {
+	certificate      *x509.Certificate
+	certificateChain []*x509.Certificate
+	privateKey       crypto.Signer
+	trustAnchorArn   string
+	profileArn       string
+	roleArn          string
+	sessionName      string
+	duration         time.Duration
+	region           string
+	endpoint         string
+	httpClient       aws.HTTPClient
+}
+
+// NewRolesAnywhereCredentialsProvider loads the certificate and private key
+// from the roles_anywhere config and returns a provider for them. The region
+// for the CreateSession call is taken from the trust anchor ARN. endpoint may
+// be empty to use the default Roles Anywhere endpoint for that region.
+func NewRolesAnywhereCredentialsProvider(c awsRolesAnywhereConfig, sessionName string, endpoint string, httpClient aws.HTTPClient) (*RolesAnywhereCredentialsProvider, error) {
+	trustAnchor, err := arn.Parse(c.TrustAnchorArn)
+	if err != nil {
+		return nil, fmt.Errorf("roles_anywhere has invalid trust_anchor_arn %q: %v", c.TrustAnchorArn, err)
+	}
+
+	certificates, err := readPEMCertificates(c.Certificate)
+	if err != nil {
+		return nil, fmt.Errorf("roles_anywhere certificate: %v", err)
+	}
+	if len(certificates) == 0 {
+		return nil, fmt.Errorf("roles_anywhere certificate: no certificate found in %s", c.Certificate)
+	}
+
+	var chain []*x509.Certificate
+	if c.CertificateChain != nil {
+		chain, err = readPEMCertificates(*c.CertificateChain)
+		if err != nil {
+			return nil, fmt.Errorf("roles_anywhere certificate_chain: %v", err)
+		}
+	}
+
+	privateKey, err := readPEMPrivateKey(c.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("roles_anywhere private_key: %v", err)
+	}
+
+	duration := rolesAnywhereDefaultDuration
+	if c.DurationSeconds != nil {
+		duration = time.Duration(*c.DurationSeconds) * time.Second
+	}
+
+	if endpoint == "" {
+		domain := "amazonaws.com"
+		if trustAnchor.Partition == "aws-cn" {
+			domain = "amazonaws.com.cn"
+		}
+		endpoint = fmt.Sprintf("https://rolesanywhere.%s.%s", trustAnchor.Region, domain)
+	}
+
+	return &RolesAnywhereCredentialsProvider{
+		certificate:      certificates[0],
+		certificateChain: chain,
+		privateKey:       privateKey,
+		trustAnchorArn:   c.TrustAnchorArn,
+		profileArn:       c.ProfileArn,
+		roleArn:          c.RoleArn,
+		sessionName:      sessionName,
+		duration:         duration,
+		region:           trustAnchor.Region,
+		endpoint:         strings.TrimSuffix(endpoint, "/"),
+		httpClient:       httpClient,
+	}, nil
+}
+
+type rolesAnywhereCreateSessionInput struct {
+	DurationSeconds int    `json:"durationSeconds"`
+	ProfileArn      string `json:"profileArn"`
+	RoleArn         string `json:"roleArn"`
+	RoleSessionName string `json:"roleSessionName,omitempty"`
+	TrustAnchorArn  string `json:"trustAnchorArn"`
+}
+
+type rolesAnywhereCreateSessionOutput struct {
+	CredentialSet []struct {
+		Credentials struct {
+			AccessKeyId     string `json:"accessKeyId"`
+			SecretAccessKey string `json:"secretAccessKey"`
+			SessionToken    string `json:"sessionToken"`
+			Expiration      string `json:"expiration"`
+		} `json:"credentials"`
+	} `json:"credentialSet"`
+}
+
+// Retrieve creates a new Roles Anywhere session and returns its credentials.
+func (p *RolesAnywhereCredentialsProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	body, err := json.Marshal(rolesAnywhereCreateSessionInput{
+		DurationSeconds: int(p.duration.Seconds()),
+		ProfileArn:      p.profileArn,
+		RoleArn:         p.roleArn,
+		RoleSessionName: p.sessionName,
+		TrustAnchorArn:  p.trustAnchorArn,
+	})
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+rolesAnywhereCreateSessionURI, bytes.NewReader(body))
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := p.sign(req, body, time.Now().UTC()); err != nil {
+		return aws.Credentials{}, err
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return aws.Credentials{}, fmt.Errorf("operation error RolesAnywhere: CreateSession, %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return aws.Credentials{}, fmt.Errorf("operation error RolesAnywhere: CreateSession, https response error StatusCode: %d, %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var output rolesAnywhereCreateSessionOutput
+	if err := json.Unmarshal(respBody, &output); err != nil {
+		return aws.Credentials{}, fmt.Errorf("operation error RolesAnywhere: CreateSession, unable to parse response: %v", err)
+	}
+	if len(output.CredentialSet) == 0 {
+		return aws.Credentials{}, fmt.Errorf("operation error RolesAnywhere: CreateSession, no credentials returned")
+	}
+
+	creds := output.CredentialSet[0].Credentials
+	expires, err := time.Parse(time.RFC3339, creds.Expiration)
+	if err != nil {
+		return aws.Credentials{}, fmt.Errorf("operation error RolesAnywhere: CreateSession, invalid expiration %q: %v", creds.Expiration, err)
+	}
+
+	return aws.Credentials{
+		AccessKeyID:     creds.AccessKeyId,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		Source:          "RolesAnywhereCredentialsProvider",
+		CanExpire:       true,
+		Expires:         expires,
+	}, nil
+}
+
+// Sign the request with the certificate private key, adding the X-Amz-Date,
+// X-Amz-X509 and Authorization headers.
+func (p *RolesAnywhereCredentialsProvider) sign(req *http.Request, body []byte, now time.Time) error {
+	var algorithm string
+	switch p.privateKey.(type) {
+	case *rsa.PrivateKey:
+		algorithm = rolesAnywhereRSAAlgorithm
+	case *ecdsa.PrivateKey:
+		algorithm = rolesAnywhereECDSAAlgorithm
+	default:
+		return fmt.Errorf("unsupported private key type %T, must be RSA or EC", p.privateKey)
+	}
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", now.Format(rolesAnywhereTimeFormat))
+	req.Header.Set("X-Amz-X509", base64.StdEncoding.EncodeToString(p.certificate.Raw))
+	if len(p.certificateChain) > 0 {
+		var encoded []string
+		for _, c := range p.certificateChain {
+			encoded = append(encoded, base64.StdEncoding.EncodeToString(c.Raw))
+		}
+		req.Header.Set("X-Amz-X509-Chain", strings.Join(encoded, ","))
+	}
+
+	canonicalRequest, signedHeaders := rolesAnywhereCanonicalRequest(req, body)
+	credentialScope := strings.Join([]string{now.Format(rolesAnywhereShortTimeFormat), p.region, rolesAnywhereSigningName, "aws4_request"}, "/")
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{algorithm, now.Format(rolesAnywhereTimeFormat), credentialScope, hex.EncodeToString(canonicalRequestHash[:])}, "\n")
+
+	digest := sha256.Sum256([]byte(stringToSign))
+	signature, err := p.privateKey.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s", algorithm, p.certificate.SerialNumber.String(), credentialScope, signedHeaders, hex.EncodeToString(signature)))
+	return nil
+}
+
+// Build the SigV4 canonical request for the given request, signing every
+// header that is set on it. Returns the canonical request and the list of
+// signed headers.
+func rolesAnywhereCanonicalRequest(req *http.Request, body []byte) (string, string) {
+	var headerNames []string
+	headers := map[string]string{}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		headerNames = append(headerNames, lower)
+		trimmed := make([]string, len(values))
+		for i, v := range values {
+			trimmed[i] = strings.Join(strings.Fields(v), " ")
+		}
+		headers[lower] = strings.Join(trimmed, ",")
+	}
+	sort.Strings(headerNames)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range headerNames {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	uri := req.URL.EscapedPath()
+	if uri == "" {
+		uri = "/"
+	}
+
+	bodyHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uri,
+		canonicalQueryString(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+	return canonicalRequest, signedHeaders
+}
+
+func canonicalQueryString(query url.Values) string {
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+// Read all certificates from a PEM file.
+func readPEMCertificates(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var certificates []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, c)
+	}
+	return certificates, nil
+}
+
+// Read an unencrypted RSA or EC private key from a PEM file, in PKCS #8,
+// PKCS #1 or SEC 1 format.
+func readPEMPrivateKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, fmt.Errorf("no private key found in %s", path)
+		}
+		switch block.Type {
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			signer, ok := key.(crypto.Signer)
+			if !ok {
+				return nil, fmt.Errorf("unsupported private key type %T", key)
+			}
+			return signer, nil
+		case "RSA PRIVATE KEY":
+			return x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			return x509.ParseECPrivateKey(block.Bytes)
+		}
+	}
+}
@@ -2,14 +2,19 @@ package aws
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"log"
 	"math"
 	"math/rand"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -17,6 +22,7 @@ import (
 	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/accessanalyzer"
 	"github.com/aws/aws-sdk-go-v2/service/account"
 	"github.com/aws/aws-sdk-go-v2/service/acm"
@@ -140,11 +146,13 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/wellarchitected"
 	"github.com/aws/aws-sdk-go-v2/service/workspaces"
 	"github.com/aws/smithy-go/logging"
+	"github.com/aws/smithy-go/middleware"
 	"github.com/hashicorp/go-hclog"
 	"github.com/rs/dnscache"
 	"github.com/turbot/go-kit/helpers"
 	"github.com/turbot/steampipe-plugin-sdk/v5/memoize"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"golang.org/x/net/http/httpproxy"
 	"golang.org/x/sync/semaphore"
 
 	amplifyEndpoint "github.com/aws/aws-sdk-go/service/amplify"
@@ -152,6 +160,7 @@ import (
 	appsyncv2Endpoint "github.com/aws/aws-sdk-go/service/appsync"
 	auditmanagerEndpoint "github.com/aws/aws-sdk-go/service/auditmanager"
 	backupEndpoint "github.com/aws/aws-sdk-go/service/backup"
+	cloudfrontEndpoint "github.com/aws/aws-sdk-go/service/cloudfront"
 	cloudsearchEndpoint "github.com/aws/aws-sdk-go/service/cloudsearch"
 	codeartifactEndpoint "github.com/aws/aws-sdk-go/service/codeartifact"
 	codebuildEndpoint "github.com/aws/aws-sdk-go/service/codebuild"
@@ -159,6 +168,7 @@ import (
 	codepipelineEndpoint "github.com/aws/aws-sdk-go/service/codepipeline"
 	cognitoidentityEndpoint "github.com/aws/aws-sdk-go/service/cognitoidentity"
 	cognitoidentityproviderEndpoint "github.com/aws/aws-sdk-go/service/cognitoidentityprovider"
+	costexplorerEndpoint "github.com/aws/aws-sdk-go/service/costexplorer"
 	daxEndpoint "github.com/aws/aws-sdk-go/service/dax"
 	directoryserviceEndpoint "github.com/aws/aws-sdk-go/service/directoryservice"
 	dlmEndpoint "github.com/aws/aws-sdk-go/service/dlm"
@@ -170,6 +180,7 @@ import (
 	eventbridgeEndpoint "github.com/aws/aws-sdk-go/service/eventbridge"
 	fsxEndpoint "github.com/aws/aws-sdk-go/service/fsx"
 	glacierEndpoint "github.com/aws/aws-sdk-go/service/glacier"
+	iamEndpoint "github.com/aws/aws-sdk-go/service/iam"
 	inspectorEndpoint "github.com/aws/aws-sdk-go/service/inspector"
 	inspector2Endpoint "github.com/aws/aws-sdk-go/service/inspector2"
 	iotEndpoint "github.com/aws/aws-sdk-go/service/iot"
@@ -186,12 +197,15 @@ import (
 	mqEndpoint "github.com/aws/aws-sdk-go/service/mq"
 	networkfirewallEndpoint "github.com/aws/aws-sdk-go/service/networkfirewall"
 	oamEndpoint "github.com/aws/aws-sdk-go/service/oam"
+	organizationsEndpoint "github.com/aws/aws-sdk-go/service/organizations"
 	pinpointEndpoint "github.com/aws/aws-sdk-go/service/pinpoint"
 	pipesEndpoint "github.com/aws/aws-sdk-go/service/pipes"
 	pricingEndpoint "github.com/aws/aws-sdk-go/service/pricing"
 	rdsEndpoint "github.com/aws/aws-sdk-go/service/rds"
 	redshiftserverlessEndpoint "github.com/aws/aws-sdk-go/service/redshiftserverless"
 	resourceexplorer2Endpoint "github.com/aws/aws-sdk-go/service/resourceexplorer2"
+	route53Endpoint "github.com/aws/aws-sdk-go/service/route53"
+	route53domainsEndpoint "github.com/aws/aws-sdk-go/service/route53domains"
 	route53resolverEndpoint "github.com/aws/aws-sdk-go/service/route53resolver"
 	sagemakerEndpoint "github.com/aws/aws-sdk-go/service/sagemaker"
 	securityhubEndpoint "github.com/aws/aws-sdk-go/service/securityhub"
@@ -383,6 +397,7 @@ func CloudControlClient(ctx context.Context, d *plugin.QueryData) (*cloudcontrol
 	if err != nil {
 		return nil, err
 	}
+	cfg = withQueryCircuitBreaker(d, cfg)
 	return cloudcontrol.NewFromConfig(*cfg), nil
 }
 
@@ -406,15 +421,17 @@ func CloudFormationClient(ctx context.Context, d *plugin.QueryData) (*cloudforma
 }
 
 func CloudFrontClient(ctx context.Context, d *plugin.QueryData) (*cloudfront.Client, error) {
-	// CloudFront a global service with a single DNS endpoint
+	// CloudFront a global service with a single DNS endpoint per partition
 	// (cloudfront.amazonaws.com).
 	// https://docs.aws.amazon.com/general/latest/gr/cf_region.html
-	// So, while requests will go to the global endpoint, we can still prefer /
-	// reuse the client region.
-	cfg, err := getClientForDefaultRegion(ctx, d)
+	// It is not available in all partitions (e.g. aws-us-gov).
+	cfg, err := getClientForGlobalService(ctx, d, cloudfrontEndpoint.EndpointsID)
 	if err != nil {
 		return nil, err
 	}
+	if cfg == nil {
+		return nil, nil
+	}
 	return cloudfront.NewFromConfig(*cfg), nil
 }
 
@@ -545,14 +562,16 @@ func ConfigClient(ctx context.Context, d *plugin.QueryData) (*configservice.Clie
 
 func CostExplorerClient(ctx context.Context, d *plugin.QueryData) (*costexplorer.Client, error) {
 	// Cost Explorer is a global service that operates from a single
-	// region (ce.us-east-1.amazonaws.com).
+	// region per partition (ce.us-east-1.amazonaws.com, or
+	// ce.cn-northwest-1.amazonaws.com.cn in China).
 	// https://docs.aws.amazon.com/general/latest/gr/billing.html
-	// Testing shows it works with either the default or client region object,
-	// so use client region for higher reuse.
-	cfg, err := getClientForDefaultRegion(ctx, d)
+	cfg, err := getClientForGlobalService(ctx, d, costexplorerEndpoint.EndpointsID)
 	if err != nil {
 		return nil, err
 	}
+	if cfg == nil {
+		return nil, nil
+	}
 	return costexplorer.NewFromConfig(*cfg), nil
 }
 
@@ -653,6 +672,7 @@ func EC2LowRetryClientForRegion(ctx context.Context, d *plugin.QueryData, region
 	if err != nil {
 		return nil, err
 	}
+	cfg = withQueryCircuitBreaker(d, cfg)
 	return ec2.NewFromConfig(*cfg), nil
 }
 
@@ -809,6 +829,15 @@ func GlobalAcceleratorClient(ctx context.Context, d *plugin.QueryData) (*globala
 	// client region, and it's not using the default region, so we have no
 	// choice but to hard code it here.
 	// https://docs.aws.amazon.com/general/latest/gr/global_accelerator.html
+	// It is only available in the commercial partition, so return nil for
+	// other partitions.
+	region, err := getDefaultRegion(ctx, d, nil)
+	if err != nil {
+		return nil, err
+	}
+	if awsPartitionFromRegion(region) != awsPartitionCommercial {
+		return nil, nil
+	}
 	cfg, err := getClient(ctx, d, "us-west-2")
 	if err != nil {
 		return nil, err
@@ -841,14 +870,16 @@ func HealthClient(ctx context.Context, d *plugin.QueryData) (*health.Client, err
 }
 
 func IAMClient(ctx context.Context, d *plugin.QueryData) (*iam.Client, error) {
-	// IAM a global service with a single DNS endpoint (iam.amazonaws.com).
+	// IAM a global service with a single DNS endpoint per partition
+	// (iam.amazonaws.com), signed for a different region in each partition.
 	// https://docs.aws.amazon.com/general/latest/gr/iam-service.html
-	// So, while requests will go to the global endpoint, we can still prefer /
-	// reuse the client region.
-	cfg, err := getClientForDefaultRegion(ctx, d)
+	cfg, err := getClientForGlobalService(ctx, d, iamEndpoint.EndpointsID)
 	if err != nil {
 		return nil, err
 	}
+	if cfg == nil {
+		return nil, nil
+	}
 	return iam.NewFromConfig(*cfg), nil
 }
 
@@ -1070,15 +1101,15 @@ func OpenSearchClient(ctx context.Context, d *plugin.QueryData) (*opensearch.Cli
 
 func OrganizationClient(ctx context.Context, d *plugin.QueryData) (*organizations.Client, error) {
 	// Organizations is a global service that operates from a single
-	// region (organizations.us-east-1.amazonaws.com).
+	// region per partition (organizations.us-east-1.amazonaws.com).
 	// https://docs.aws.amazon.com/general/latest/gr/ao.html
-	// So, we must specify the default region rather than the client region.
-	// Testing shows it works with either the default or client region object,
-	// so use client region for higher reuse.
-	cfg, err := getClientForDefaultRegion(ctx, d)
+	cfg, err := getClientForGlobalService(ctx, d, organizationsEndpoint.EndpointsID)
 	if err != nil {
 		return nil, err
 	}
+	if cfg == nil {
+		return nil, nil
+	}
 	return organizations.NewFromConfig(*cfg), nil
 }
 
@@ -1183,8 +1214,8 @@ func RDSDBRecommendationClient(ctx context.Context, d *plugin.QueryData) (*rds.C
 		"cn-north-1",     // China (Beijing)
 		"cn-northwest-1", // China (Ningxia)
 	}
-	excludeRegions = append(excludeRegions, awsChinaRegions()...)
-	excludeRegions = append(excludeRegions, awsUsGovRegions()...)
+	excludeRegions = append(excludeRegions, awsRegionsForPartition(awsPartitionChina)...)
+	excludeRegions = append(excludeRegions, awsRegionsForPartition(awsPartitionUsGov)...)
 	cfg, err := getClientForQuerySupportedRegionWithExclusions(ctx, d, rdsEndpoint.EndpointsID, excludeRegions)
 	if err != nil {
 		return nil, err
@@ -1208,8 +1239,8 @@ func RDSDBProxyClient(ctx context.Context, d *plugin.QueryData) (*rds.Client, er
 		"eu-south-2",     // Spain
 		"me-central-1",   // UAE
 	}
-	excludeRegions = append(excludeRegions, awsChinaRegions()...)
-	excludeRegions = append(excludeRegions, awsUsGovRegions()...)
+	excludeRegions = append(excludeRegions, awsRegionsForPartition(awsPartitionChina)...)
+	excludeRegions = append(excludeRegions, awsRegionsForPartition(awsPartitionUsGov)...)
 	cfg, err := getClientForQuerySupportedRegionWithExclusions(ctx, d, rdsEndpoint.EndpointsID, excludeRegions)
 	if err != nil {
 		return nil, err
@@ -1279,15 +1310,16 @@ func ResourceGroupsTaggingClient(ctx context.Context, d *plugin.QueryData) (*res
 }
 
 func Route53Client(ctx context.Context, d *plugin.QueryData) (*route53.Client, error) {
-	// Route53 is a global service with a single DNS endpoint
+	// Route53 is a global service with a single DNS endpoint per partition
 	// (route53.amazonaws.com).
 	// https://docs.aws.amazon.com/general/latest/gr/r53.html
-	// So, while requests will go to the global endpoint, but we can still
-	// prefer / reuse the client region.
-	cfg, err := getClientForDefaultRegion(ctx, d)
+	cfg, err := getClientForGlobalService(ctx, d, route53Endpoint.EndpointsID)
 	if err != nil {
 		return nil, err
 	}
+	if cfg == nil {
+		return nil, nil
+	}
 	return route53.NewFromConfig(*cfg), nil
 }
 
@@ -1295,11 +1327,14 @@ func Route53DomainsClient(ctx context.Context, d *plugin.QueryData) (*route53dom
 	// Route53 Domains is a global service that operates from a single
 	// region (route53domains.us-east-1.amazonaws.com).
 	// https://docs.aws.amazon.com/general/latest/gr/r53.html
-	// So, we must specify the default region rather than the client region.
-	cfg, err := getClientForLastResortRegion(ctx, d)
+	// It is only available in the commercial partition.
+	cfg, err := getClientForGlobalService(ctx, d, route53domainsEndpoint.EndpointsID)
 	if err != nil {
 		return nil, err
 	}
+	if cfg == nil {
+		return nil, nil
+	}
 	return route53domains.NewFromConfig(*cfg), nil
 }
 
@@ -1347,8 +1382,16 @@ func S3ControlClient(ctx context.Context, d *plugin.QueryData, region string) (*
 // All requests to create or maintain Multi-Region Access Points are routed to the US West (Oregon) Region. so we have no choice but to hard code it here.
 // This is true regardless of which Region you are in when making the request, or what Regions the Multi-Region Access Point supports.
 // https://docs.aws.amazon.com/AmazonS3/latest/userguide/ManagingMultiRegionAccessPoints.html
-// S3 multi-region access point supports in China but not in US Gov or US ISO
+// US West (Oregon) is only in the commercial partition, so return nil for other
+// partitions rather than sending requests across partitions.
 func S3ControlMultiRegionAccessClient(ctx context.Context, d *plugin.QueryData) (*s3control.Client, error) {
+	region, err := getDefaultRegion(ctx, d, nil)
+	if err != nil {
+		return nil, err
+	}
+	if awsPartitionFromRegion(region) != awsPartitionCommercial {
+		return nil, nil
+	}
 	cfg, err := getClient(ctx, d, "us-west-2")
 	if err != nil {
 		return nil, err
@@ -1605,10 +1648,15 @@ func WAFV2Client(ctx context.Context, d *plugin.QueryData, region string) (*wafv
 	var cfg *aws.Config
 	var err error
 	// For WAFv2 resources of type CloudFront, we are building the region metrix including a region value 'global'.
-	// We need to pass the the region value 'us-east-1' to get the cloudfront resource types.
+	// We need to pass the region of the CloudFront endpoint for the partition (e.g. 'us-east-1') to get the cloudfront resource types.
 	// getClientForQuerySupportedRegion function removes the invalid region(global) while building the client for which we need the below check
 	if region == "global" {
-		cfg, err = getClient(ctx, d, "us-east-1")
+		var cloudfrontRegion string
+		cloudfrontRegion, err = getGlobalServiceRegion(ctx, d, cloudfrontEndpoint.EndpointsID)
+		// CloudFront is not available in all partitions (e.g. aws-us-gov)
+		if err == nil && cloudfrontRegion != "" {
+			cfg, err = getClient(ctx, d, cloudfrontRegion)
+		}
 	} else {
 		cfg, err = getClientForQuerySupportedRegion(ctx, d, wafv2Endpoint.EndpointsID)
 	}
@@ -1691,12 +1739,34 @@ func getClientForDefaultRegion(ctx context.Context, d *plugin.QueryData) (*aws.C
 	return getClient(ctx, d, r)
 }
 
-// Helper function to get the session for the last resort region in this partition
-func getClientForLastResortRegion(ctx context.Context, d *plugin.QueryData) (*aws.Config, error) {
-	r, err := getLastResortRegion(ctx, d, nil)
+// Helper function to get the session for a global service (e.g. iam), using
+// the region of the service's global endpoint in the partition of the
+// connection. Returns nil if the service is not available in the partition,
+// rather than calling an endpoint that doesn't exist, so tables return no
+// data like they do for unsupported regions.
+func getClientForGlobalService(ctx context.Context, d *plugin.QueryData, serviceID string) (*aws.Config, error) {
+	r, err := getGlobalServiceRegion(ctx, d, serviceID)
 	if err != nil {
 		return nil, err
 	}
+	if r == "" {
+		defaultRegion, err := getDefaultRegion(ctx, d, nil)
+		if err != nil {
+			return nil, err
+		}
+		plugin.Logger(ctx).Debug("getClientForGlobalService", "connection_name", d.Connection.Name, "service", serviceID, "partition", awsPartitionFromRegion(defaultRegion), "status", "not_available")
+		return nil, nil
+	}
+
+	// With use_fips_endpoint, global services without a FIPS endpoint fail
+	// rather than silently returning no data
+	catalog, err := getRegionCatalog(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+	if !hasFipsEndpointForServiceRegion(catalog, GetConfig(d.Connection), serviceID, r) {
+		return nil, newFipsEndpointNotAvailableError(serviceID, r)
+	}
 	return getClient(ctx, d, r)
 }
 
@@ -1728,7 +1798,7 @@ func getClient(ctx context.Context, d *plugin.QueryData, region string) (*aws.Co
 	if err != nil {
 		return nil, err
 	}
-	return i.(*aws.Config), nil
+	return withQueryCircuitBreaker(d, i.(*aws.Config)), nil
 }
 
 // Cached form of getClient, using the per-connection and parallel safe
@@ -1742,6 +1812,10 @@ func getClientCacheKey(ctx context.Context, d *plugin.QueryData, h *plugin.Hydra
 	// but a clever pass through of context for our case.
 	region := h.Item.(string)
 	key := fmt.Sprintf("getClient-%s", region)
+	// Organization connections need a client per member account too
+	if accountId := getMatrixItemAccountId(ctx, d); accountId != "" {
+		key = fmt.Sprintf("getClient-%s-%s", accountId, region)
+	}
 	return key, nil
 }
 
@@ -1825,52 +1899,155 @@ func getClientWithMaxRetries(ctx context.Context, d *plugin.QueryData, region st
 	plugin.Logger(ctx).Debug("getClientWithMaxRetries", "connection_name", d.Connection.Name, "config_region", cfg.Region, "status", "set_client_region")
 
 	// Add the retryer definition
-	retryer := retry.NewStandard(func(o *retry.StandardOptions) {
+	standardOptions := func(o *retry.StandardOptions) {
 		// reseting state of rand to generate different random values
 		rand.New(rand.NewSource(time.Now().UnixNano()))
 		o.MaxAttempts = maxRetries
 		o.MaxBackoff = 5 * time.Minute
 		o.RateLimiter = NoOpRateLimit{} // With no rate limiter
 		o.Backoff = NewExponentialJitterBackoff(minRetryDelay, maxRetries)
-	})
+	}
+
+	// Set the retry mode from config file or env variable (config file has precedence)
+	retryMode := aws.RetryModeStandard
+	if awsSpcConfig := GetConfig(d.Connection); awsSpcConfig.RetryMode != nil {
+		retryMode = aws.RetryMode(*awsSpcConfig.RetryMode)
+	} else if os.Getenv("AWS_RETRY_MODE") != "" {
+		retryMode = aws.RetryMode(os.Getenv("AWS_RETRY_MODE"))
+	}
+
+	var retryer aws.Retryer
+	switch retryMode {
+	case aws.RetryModeStandard:
+		retryer = retry.NewStandard(standardOptions)
+	case aws.RetryModeAdaptive:
+		// Adaptive mode adds a client-side rate limiter on top of the standard
+		// retryer, which slows down attempts once requests are throttled.
+		retryer = retry.NewAdaptiveMode(func(o *retry.AdaptiveModeOptions) {
+			o.StandardOptions = append(o.StandardOptions, standardOptions)
+		})
+	default:
+		return nil, fmt.Errorf("connection config has invalid value for \"retry_mode\", it must be \"standard\" or \"adaptive\"")
+	}
 	cfg.Retryer = func() aws.Retryer {
 		// UnknownError is the code returned for a 408 from the aws go sdk, these can be frequent on large accounts especially around SNS Topics, etc.
 		additionalErrors := []string{"UnknownError"}
 		return retry.AddWithErrorCodes(retryer, additionalErrors...)
 	}
 
-	// Plugin level config
+	// Add the rate limiters of the connection (the defaults and the rate_limiter
+	// blocks of its config). The API options are copied, since the copied
+	// config shares them with the base config.
 	awsSpcConfig := GetConfig(d.Connection)
+	apiOptions := append([]func(*middleware.Stack) error{}, cfg.APIOptions...)
+	rateLimiters, err := getConnectionRateLimiters(awsSpcConfig)
+	if err != nil {
+		return nil, err
+	}
+	if len(rateLimiters) > 0 {
+		apiOptions = append(apiOptions, rateLimiterAPIOption(d.Connection.Name, rateLimiters))
+	}
 
-	// If there is a custom endpoint, use it
-	var awsEndpointUrl string
-	awsEndpointUrl = os.Getenv("AWS_ENDPOINT_URL")
-	if awsSpcConfig.EndpointUrl != nil {
-		awsEndpointUrl = *awsSpcConfig.EndpointUrl
-		if awsEndpointUrl != "" {
-			customResolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
-				return aws.Endpoint{
-					PartitionID:   "aws",
-					URL:           awsEndpointUrl,
-					SigningRegion: region,
-				}, nil
-			})
-			newCfg, err := config.LoadDefaultConfig(ctx, config.WithEndpointResolverWithOptions(customResolver))
-			if err != nil {
-				plugin.Logger(ctx).Error("service.getClientWithMaxRetries", "connection_error", err)
-				return nil, err
-			}
-			newCfg.Retryer = cfg.Retryer
-			newCfg.Region = cfg.Region
-			cfg = newCfg
+	// Count the API calls of the connection, after the rate limiters so the
+	// latency doesn't include the time spent waiting for them.
+	apiOptions = append(apiOptions, apiCallStatsAPIOption(d.Connection.Name))
+
+	// FIPS endpoints are set on the base config (see
+	// getBaseClientForAccountUncached). Services without a FIPS endpoint are
+	// skipped in the region matrix, so this only fails calls outside of it,
+	// rather than sending them to a hostname that doesn't exist. All calls go
+	// to endpoint_url if it is set, so there is nothing to check.
+	if useFIPSEndpoint(awsSpcConfig) && awsSpcConfig.EndpointUrl == nil {
+		catalog, err := getRegionCatalog(ctx, d)
+		if err != nil {
+			return nil, err
 		}
+		apiOptions = append(apiOptions, fipsEndpointAPIOption(catalog, awsSpcConfig))
 	}
+	cfg.APIOptions = apiOptions
 
 	plugin.Logger(ctx).Debug("getClientWithMaxRetries", "connection_name", d.Connection.Name, "region", region, "status", "done")
 
 	return &cfg, err
 }
 
+// Build an endpoint resolver from the endpoint_url and endpoints config.
+// Services in the endpoints map use their own endpoint, all other services use
+// endpoint_url if it is set. Services without either return an
+// EndpointNotFoundError, which makes the AWS SDK fall back to the default
+// endpoint for the service. Returns nil if no custom endpoints are configured.
+func getEndpointResolver(awsSpcConfig awsConfig) aws.EndpointResolverWithOptions {
+	endpointUrl := aws.ToString(awsSpcConfig.EndpointUrl)
+	if endpointUrl == "" && len(awsSpcConfig.Endpoints) == 0 {
+		return nil
+	}
+
+	serviceEndpoints := map[string]string{}
+	for service, url := range awsSpcConfig.Endpoints {
+		serviceEndpoints[normalizeEndpointServiceKey(service)] = url
+	}
+
+	return aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+		url, ok := serviceEndpoints[normalizeEndpointServiceKey(service)]
+		if !ok {
+			url = endpointUrl
+		}
+		if url == "" {
+			return aws.Endpoint{}, &aws.EndpointNotFoundError{}
+		}
+		return aws.Endpoint{
+			PartitionID:   awsPartitionFromRegion(region),
+			URL:           url,
+			SigningRegion: region,
+		}, nil
+	})
+}
+
+// The endpoint resolver is called with the SDK service ID (e.g. "S3",
+// "DynamoDB", "Resource Groups Tagging API"). Keys in the endpoints config
+// are matched case insensitively, ignoring spaces, dashes and underscores, so
+// "s3", "dynamodb" and "resource_groups_tagging_api" all work.
+func normalizeEndpointServiceKey(service string) string {
+	return strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(service))
+}
+
+// The `service` tags of the tables are named after the IAM service prefix
+// (e.g. "ce", "logs"), which is not always the SDK service ID. Services whose
+// tag differs from their normalized SDK service ID are listed here, keyed by
+// the normalized service ID.
+var serviceTagsByServiceID = map[string]string{
+	"apigatewayv2":                    "apigateway",
+	"cloudwatchlogs":                  "logs",
+	"cognitoidentityprovider":         "cognitoidp",
+	"configservice":                   "config",
+	"costexplorer":                    "ce",
+	"databasemigrationservice":        "dms",
+	"directoryservice":                "ds",
+	"efs":                             "elasticfilesystem",
+	"elasticloadbalancingv2":          "elasticloadbalancing",
+	"elasticsearchservice":            "es",
+	"emr":                             "elasticmapreduce",
+	"eventbridge":                     "events",
+	"kinesisanalyticsv2":              "kinesisanalytics",
+	"opensearch":                      "es",
+	"pinpoint":                        "mobiletargeting",
+	"resourcegroupstaggingapi":        "tag",
+	"serverlessapplicationrepository": "serverlessrepo",
+	"sfn":                             "states",
+	"ssoadmin":                        "sso",
+}
+
+// Get the `service` tag of the tables for an SDK service ID (e.g. "Cost
+// Explorer" is "ce"), normalized like the endpoint keys so it can be compared
+// with normalizeEndpointServiceKey of a tag.
+func getServiceTagForServiceID(serviceID string) string {
+	key := normalizeEndpointServiceKey(serviceID)
+	if tag, ok := serviceTagsByServiceID[key]; ok {
+		return tag
+	}
+	return key
+}
+
 // Helper function to get an AWS config object for each connection. This object
 // is then copied and shared across regions. This approach avoids unnecssary
 // creation work for sessions, particularly when using a shared service like IDMS.
@@ -1884,7 +2061,12 @@ func getClientWithMaxRetries(ctx context.Context, d *plugin.QueryData, region st
 // even worse when the cache was not safe against parallel runs, causing many to
 // be recreated. Using a base client creation and combining with the safety of
 // Memoize() is a much better approach.
+// For organization connections, the base client of the member account in the
+// current matrix item is returned instead.
 func getBaseClientForAccount(ctx context.Context, d *plugin.QueryData) (*aws.Config, error) {
+	if accountId := getMatrixItemAccountId(ctx, d); accountId != "" {
+		return getBaseClientForMemberAccount(ctx, d, accountId)
+	}
 	tmp, err := getBaseClientForAccountCached(ctx, d, nil)
 	if err != nil {
 		return nil, err
@@ -1892,10 +2074,12 @@ func getBaseClientForAccount(ctx context.Context, d *plugin.QueryData) (*aws.Con
 	return tmp.(*aws.Config), nil
 }
 
-// Initialize a single HTTP client that is optimized for Steampipe and shared
-// across all AWS SDK clients. We have hundreds of AWS SDK clients (one per
-// account region) that are all sharing this same HTTP client - creating shared
-// caching and controls over parallelism.
+// Initialize an HTTP client that is optimized for Steampipe. By default, a
+// single client is shared across all AWS SDK clients. We have hundreds of AWS
+// SDK clients (one per account region) that are all sharing this same HTTP
+// client - creating shared caching and controls over parallelism. Connections
+// with custom transport settings (e.g. a proxy) get their own client, but
+// still share the DNS cache and DNS lookup controls.
 //
 // The AWS SDK defaults are good, but not great for our highly parallel use in
 // Steampipe. Specific problems this client aims to solve:
@@ -1908,32 +2092,7 @@ func getBaseClientForAccount(ctx context.Context, d *plugin.QueryData) (*aws.Con
 // 3. DNS caching - Golang does not cache DNS lookups by default. We end up
 // looking up the same host thousands of times both within a query and across
 // queries.
-func initializeHTTPClient() aws.HTTPClient {
-
-	// DNS lookup floods are a real problem with highly parallel AWS SDK calls. Every
-	// API request leads to a DNS lookup by default (since Go doesn't cache them). We
-	// employ a DNS lookup cache, but we also need to limit the number of parallel DNS
-	// requests to avoid overwhelming the underlying DNS server. For example, listing
-	// S3 buckets will create 2 DNS lookup requests per bucket which is a lot of
-	// pressure on the DNS layer of your network.
-	// This setting will limit the number of parallel DNS lookups. An appropriate setting
-	// depends on the capabilities of your DNS server. The default is 25, which is low
-	// enough for a Macbook M1 to work without "no such host" errors when using the cgo
-	// network stack. It's high enough to work great in most cases, except maybe massive
-	// S3 bucket listing (which is rare). Notably on the same Macbook M1, when the plugin
-	// is compiled using netgo (our default on Mac) DNS lookups will succeed with virtually
-	// no upper limit on this setting. So, bottom line, 25 is a guess to try and ensure
-	// it works reliably and optimally enough.
-	dnsLookupMaxParallel := readEnvVarToInt("STEAMPIPE_AWS_DNS_LOOKUP_MAX_PARALLEL", 25)
-
-	// The DNS cache will be refreshed at this interval. A refresh means that
-	// any unused entries are removed and any entries that were used since the
-	// last refresh will be re-looked up to ensure they are current.
-	// This setting should be large enough to get the benefit of caching and short
-	// enough to prevent stale entries from being used for too long.
-	// Set to 0 to disable the refresh completely (not a good idea).
-	// Set to -1 to disable the DNS cache completely (the AWS default).
-	dnsCacheRefreshIntervalSecs := readEnvVarToInt("STEAMPIPE_AWS_DNS_CACHE_REFRESH_INTERVAL_SECS", 300)
+func initializeHTTPClient(settings httpClientSettings) (aws.HTTPClient, error) {
 
 	// This is the maximum number of HTTPS API connections used for each host
 	// (e.g.  iam.amazonaws.com). We want a number that is high enough to do a
@@ -1946,18 +2105,6 @@ func initializeHTTPClient() aws.HTTPClient {
 	// Set to 0 to remove the limit (which is the AWS SDK default).
 	httpTransportMaxConnsPerHost := readEnvVarToInt("STEAMPIPE_AWS_HTTP_TRANSPORT_MAX_CONNS_PER_HOST", 5000)
 
-	// Our DNS resolver should automatically refresh itself on this schedule.
-	var resolver = &dnscache.Resolver{}
-	if dnsCacheRefreshIntervalSecs > 0 {
-		go func() {
-			t := time.NewTicker(time.Duration(dnsCacheRefreshIntervalSecs) * time.Second)
-			defer t.Stop()
-			for range t.C {
-				resolver.Refresh(true)
-			}
-		}()
-	}
-
 	// The AWS SDK has a special "buildable" HTTP client so it can be combined
 	// with specific options such as custom certificate bundles. It matches the
 	// interface of a HTTPClient, but has specific approaches for setting
@@ -1976,10 +2123,7 @@ func initializeHTTPClient() aws.HTTPClient {
 
 	// Use a DNS cache if it's set, otherwise we just avoid changing the dialer behavior
 	// of the AWS HTTP client.
-	if dnsCacheRefreshIntervalSecs >= 0 {
-
-		// A semaphore is used to control the number of parallel DNS lookups.
-		sem := semaphore.NewWeighted(int64(dnsLookupMaxParallel))
+	if sharedDNSResolver != nil {
 
 		// A dialer for testing connections
 		dialer := client.GetDialer()
@@ -1993,16 +2137,16 @@ func initializeHTTPClient() aws.HTTPClient {
 				}
 
 				// Acquire a semaphore slot, blocking until one is available.
-				if err := sem.Acquire(ctx, 1); err != nil {
+				if err := sharedDNSLookupSemaphore.Acquire(ctx, 1); err != nil {
 					return nil, err
 				}
 
 				// Actually resolve the host, using a cached result if possible.
 				// Returns an array of IPs for the host.
-				ips, err := resolver.LookupHost(ctx, host)
+				ips, err := sharedDNSResolver.LookupHost(ctx, host)
 
 				// Release the semaphore, even if there was an error.
-				sem.Release(1)
+				sharedDNSLookupSemaphore.Release(1)
 
 				// If there was an error during lookup, we give up immediately.
 				if err != nil {
@@ -2024,10 +2168,164 @@ func initializeHTTPClient() aws.HTTPClient {
 		})
 	}
 
-	return client
+	// Route requests through a proxy if set in the connection config. Proxy
+	// settings from the environment (HTTPS_PROXY, NO_PROXY, etc) are used for
+	// anything not set in the config, matching the AWS SDK default behavior.
+	if settings.HTTPProxy != "" || settings.NoProxy != "" {
+		proxyConfig := httpproxy.FromEnvironment()
+		if settings.HTTPProxy != "" {
+			proxyConfig.HTTPProxy = settings.HTTPProxy
+			proxyConfig.HTTPSProxy = settings.HTTPProxy
+		}
+		if settings.NoProxy != "" {
+			proxyConfig.NoProxy = settings.NoProxy
+		}
+		proxyFunc := proxyConfig.ProxyFunc()
+		client = client.WithTransportOptions(func(tr *http.Transport) {
+			tr.Proxy = func(r *http.Request) (*url.URL, error) {
+				return proxyFunc(r.URL)
+			}
+		})
+	}
+
+	// Trust the certificates in the CA bundle in addition to the system
+	// certificates, e.g. for a TLS inspecting proxy.
+	if settings.CABundle != "" {
+		pem, err := os.ReadFile(settings.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read ca_bundle: %v", err)
+		}
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil || rootCAs == nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in ca_bundle %s", settings.CABundle)
+		}
+		client = client.WithTransportOptions(func(tr *http.Transport) {
+			if tr.TLSClientConfig == nil {
+				tr.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+			}
+			tr.TLSClientConfig.RootCAs = rootCAs
+		})
+	}
+
+	// Only intended for local stand-ins of AWS services using self-signed
+	// certificates. Never use this against real AWS endpoints.
+	if settings.InsecureSkipVerify {
+		client = client.WithTransportOptions(func(tr *http.Transport) {
+			if tr.TLSClientConfig == nil {
+				tr.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+			}
+			tr.TLSClientConfig.InsecureSkipVerify = true
+		})
+	}
+
+	return client, nil
 }
 
-var sharedHTTPClient = initializeHTTPClient()
+// DNS lookup floods are a real problem with highly parallel AWS SDK calls. Every
+// API request leads to a DNS lookup by default (since Go doesn't cache them). We
+// employ a DNS lookup cache, but we also need to limit the number of parallel DNS
+// requests to avoid overwhelming the underlying DNS server. For example, listing
+// S3 buckets will create 2 DNS lookup requests per bucket which is a lot of
+// pressure on the DNS layer of your network.
+// This setting will limit the number of parallel DNS lookups. An appropriate setting
+// depends on the capabilities of your DNS server. The default is 25, which is low
+// enough for a Macbook M1 to work without "no such host" errors when using the cgo
+// network stack. It's high enough to work great in most cases, except maybe massive
+// S3 bucket listing (which is rare). Notably on the same Macbook M1, when the plugin
+// is compiled using netgo (our default on Mac) DNS lookups will succeed with virtually
+// no upper limit on this setting. So, bottom line, 25 is a guess to try and ensure
+// it works reliably and optimally enough.
+// The semaphore is shared by every HTTP client, so the limit applies to the
+// plugin process as a whole.
+var sharedDNSLookupSemaphore = semaphore.NewWeighted(int64(readEnvVarToInt("STEAMPIPE_AWS_DNS_LOOKUP_MAX_PARALLEL", 25)))
+
+// The DNS cache shared by every HTTP client, or nil if DNS caching is
+// disabled.
+var sharedDNSResolver = initializeDNSResolver()
+
+func initializeDNSResolver() *dnscache.Resolver {
+
+	// The DNS cache will be refreshed at this interval. A refresh means that
+	// any unused entries are removed and any entries that were used since the
+	// last refresh will be re-looked up to ensure they are current.
+	// This setting should be large enough to get the benefit of caching and short
+	// enough to prevent stale entries from being used for too long.
+	// Set to 0 to disable the refresh completely (not a good idea).
+	// Set to -1 to disable the DNS cache completely (the AWS default).
+	dnsCacheRefreshIntervalSecs := readEnvVarToInt("STEAMPIPE_AWS_DNS_CACHE_REFRESH_INTERVAL_SECS", 300)
+	if dnsCacheRefreshIntervalSecs < 0 {
+		return nil
+	}
+
+	// Our DNS resolver should automatically refresh itself on this schedule.
+	var resolver = &dnscache.Resolver{}
+	if dnsCacheRefreshIntervalSecs > 0 {
+		go func() {
+			t := time.NewTicker(time.Duration(dnsCacheRefreshIntervalSecs) * time.Second)
+			defer t.Stop()
+			for range t.C {
+				resolver.Refresh(true)
+			}
+		}()
+	}
+	return resolver
+}
+
+// Transport settings from the connection config that need a dedicated HTTP
+// client. It is used as the key to share clients between connections with
+// the same settings.
+type httpClientSettings struct {
+	HTTPProxy          string
+	NoProxy            string
+	CABundle           string
+	InsecureSkipVerify bool
+}
+
+func getHTTPClientSettings(awsSpcConfig awsConfig) httpClientSettings {
+	return httpClientSettings{
+		HTTPProxy:          aws.ToString(awsSpcConfig.HTTPProxy),
+		NoProxy:            strings.Join(awsSpcConfig.NoProxy, ","),
+		CABundle:           aws.ToString(awsSpcConfig.CABundle),
+		InsecureSkipVerify: aws.ToBool(awsSpcConfig.InsecureSkipVerify),
+	}
+}
+
+// The default HTTP client, shared by all connections without custom transport
+// settings.
+var sharedHTTPClient, _ = initializeHTTPClient(httpClientSettings{})
+
+// HTTP clients for custom transport settings, built on first use.
+var httpClients = map[httpClientSettings]aws.HTTPClient{}
+var httpClientsMutex sync.Mutex
+
+// Get the HTTP client for the transport settings in the connection config.
+// Connections with the same settings share a client (and so its connection
+// pool), and connections without custom settings use sharedHTTPClient.
+func getHTTPClient(awsSpcConfig awsConfig) (aws.HTTPClient, error) {
+	settings := getHTTPClientSettings(awsSpcConfig)
+	if settings == (httpClientSettings{}) {
+		return sharedHTTPClient, nil
+	}
+
+	httpClientsMutex.Lock()
+	defer httpClientsMutex.Unlock()
+
+	if client, ok := httpClients[settings]; ok {
+		return client, nil
+	}
+	client, err := initializeHTTPClient(settings)
+	if err != nil {
+		return nil, err
+	}
+	if settings.InsecureSkipVerify {
+		log.Printf("[WARN] initializeHTTPClient: TLS certificate verification is disabled by insecure_skip_verify")
+	}
+	httpClients[settings] = client
+	return client, nil
+}
 
 // Cached form of the base client.
 // This cache HAS A 30 DAY EXPIRATION! This is because the AWS SDK will
@@ -2035,7 +2333,9 @@ var sharedHTTPClient = initializeHTTPClient()
 // If we expire the cache regularly we are causing SSO sessions to end
 // prematurely, and causing the AWS SDK to refresh credentials more often
 // using the IDMS service etc.
-var getBaseClientForAccountCached = plugin.HydrateFunc(getBaseClientForAccountUncached).Memoize(memoize.WithTtl(time.Hour * 24 * 30))
+var getBaseClientForAccountCached = plugin.HydrateFunc(getBaseClientForAccountUncached).Memoize(memoize.WithTtl(baseClientCacheTtl))
+
+const baseClientCacheTtl = time.Hour * 24 * 30
 
 // Do the actual work of creating an AWS config object for reuse across many
 // regions. This client has the minimal reusable configuration on it, so it
@@ -2044,6 +2344,12 @@ func getBaseClientForAccountUncached(ctx context.Context, d *plugin.QueryData, h
 
 	plugin.Logger(ctx).Debug("getBaseClientForAccountUncached", "connection_name", d.Connection.Name, "status", "starting")
 
+	// Check the config first, so mistakes are reported as such rather than as
+	// credential errors from the SDK
+	if err := validateConnectionConfig(ctx, d); err != nil {
+		return nil, err
+	}
+
 	awsSpcConfig := GetConfig(d.Connection)
 
 	var configOptions []func(*config.LoadOptions) error
@@ -2054,17 +2360,22 @@ func getBaseClientForAccountUncached(ctx context.Context, d *plugin.QueryData, h
 	// important, as this base client is even used when trying to guess the
 	// default region for the user based on these settings.
 
-	if awsSpcConfig.Profile != nil {
+	// In replay mode every response comes from the cassette, so credentials
+	// from the connection config (which may not even exist on this machine)
+	// are not used. Requests are still signed, with placeholder credentials.
+	replay := aws.ToString(awsSpcConfig.CassetteMode) == cassetteModeReplay
+	if replay {
+		plugin.Logger(ctx).Debug("getBaseClientForAccountUncached", "connection_name", d.Connection.Name, "status", "cassette_replay")
+		configOptions = append(configOptions, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("ASIAREPLAY", "replay", "")))
+	}
+
+	if awsSpcConfig.Profile != nil && !replay {
 		profile := aws.ToString(awsSpcConfig.Profile)
 		plugin.Logger(ctx).Debug("getBaseClientForAccountUncached", "connection_name", d.Connection.Name, "status", "profile_found", "profile", profile)
 		configOptions = append(configOptions, config.WithSharedConfigProfile(profile))
 	}
 
-	if awsSpcConfig.AccessKey != nil && awsSpcConfig.SecretKey == nil {
-		return nil, fmt.Errorf("partial credentials found in connection config, missing: secret_key")
-	} else if awsSpcConfig.SecretKey != nil && awsSpcConfig.AccessKey == nil {
-		return nil, fmt.Errorf("partial credentials found in connection config, missing: access_key")
-	} else if awsSpcConfig.AccessKey != nil && awsSpcConfig.SecretKey != nil {
+	if awsSpcConfig.AccessKey != nil && awsSpcConfig.SecretKey != nil && !replay {
 		plugin.Logger(ctx).Debug("getBaseClientForAccountUncached", "connection_name", d.Connection.Name, "status", "key_pair_found")
 		sessionToken := ""
 		if awsSpcConfig.SessionToken != nil {
@@ -2111,7 +2422,38 @@ func getBaseClientForAccountUncached(ctx context.Context, d *plugin.QueryData, h
 	//   opts.Client = imds.New(imds.Options{Retryer: retryer, ClientLogMode: aws.LogRetries | aws.LogRequest}, withDebugHTTPClient())
 	// }))
 
-	configOptions = append(configOptions, config.WithHTTPClient(sharedHTTPClient))
+	httpClient, err := getHTTPClient(awsSpcConfig)
+	if err != nil {
+		plugin.Logger(ctx).Error("getBaseClientForAccountUncached", "connection_name", d.Connection.Name, "http_client_error", err)
+		return nil, err
+	}
+	configOptions = append(configOptions, config.WithHTTPClient(httpClient))
+
+	// FIPS and dual-stack endpoints are set on the base config, so they are
+	// used by every regional client and by background calls like
+	// sts:AssumeRole. Setting them to false overrides AWS_USE_FIPS_ENDPOINT
+	// and AWS_USE_DUALSTACK_ENDPOINT.
+	if awsSpcConfig.UseFIPSEndpoint != nil {
+		state := aws.FIPSEndpointStateDisabled
+		if *awsSpcConfig.UseFIPSEndpoint {
+			state = aws.FIPSEndpointStateEnabled
+		}
+		configOptions = append(configOptions, config.WithUseFIPSEndpoint(state))
+	}
+	if awsSpcConfig.UseDualStackEndpoint != nil {
+		state := aws.DualStackEndpointStateDisabled
+		if *awsSpcConfig.UseDualStackEndpoint {
+			state = aws.DualStackEndpointStateEnabled
+		}
+		configOptions = append(configOptions, config.WithUseDualStackEndpoint(state))
+	}
+
+	// Custom endpoints are set on the base config, so they are used by every
+	// regional client and by background calls like sts:AssumeRole.
+	if resolver := getEndpointResolver(awsSpcConfig); resolver != nil {
+		plugin.Logger(ctx).Debug("getBaseClientForAccountUncached", "connection_name", d.Connection.Name, "status", "custom_endpoints_found")
+		configOptions = append(configOptions, config.WithEndpointResolverWithOptions(resolver))
+	}
 
 	cfg, err := config.LoadDefaultConfig(ctx, configOptions...)
 	if err != nil {
@@ -2142,12 +2484,157 @@ func getBaseClientForAccountUncached(ctx context.Context, d *plugin.QueryData, h
 		}
 	}
 
+	// Record or replay the requests of the connection. This wraps the HTTP
+	// client after the config is loaded, since the AWS SDK needs the
+	// buildable client itself while loading (e.g. for AWS_CA_BUNDLE).
+	if awsSpcConfig.CassetteMode != nil {
+		cassetteClient, err := newCassetteHTTPClient(cfg.HTTPClient, *awsSpcConfig.CassetteMode, aws.ToString(awsSpcConfig.CassetteDir), aws.ToBool(awsSpcConfig.RedactAccountIds))
+		if err != nil {
+			plugin.Logger(ctx).Error("getBaseClientForAccountUncached", "connection_name", d.Connection.Name, "cassette_error", err)
+			return nil, err
+		}
+		cfg.HTTPClient = cassetteClient
+	}
+
+	if replay {
+		plugin.Logger(ctx).Debug("getBaseClientForAccountUncached", "connection_name", d.Connection.Name, "status", "done")
+		return &cfg, nil
+	}
+
+	// Credential sources that need the region and HTTP client of the loaded
+	// config replace the credentials resolved by the AWS SDK.
+	if awsSpcConfig.WebIdentityTokenFile != nil {
+		plugin.Logger(ctx).Debug("getBaseClientForAccountUncached", "connection_name", d.Connection.Name, "status", "web_identity_token_file_found")
+		cfg.Credentials = getWebIdentityCredentialsProvider(ctx, d, cfg, awsSpcConfig)
+		plugin.Logger(ctx).Debug("getBaseClientForAccountUncached", "connection_name", d.Connection.Name, "status", "done")
+		return &cfg, nil
+	}
+
+	if awsSpcConfig.RolesAnywhere != nil {
+		plugin.Logger(ctx).Debug("getBaseClientForAccountUncached", "connection_name", d.Connection.Name, "status", "roles_anywhere_found")
+		endpoint := ""
+		if resolver := getEndpointResolver(awsSpcConfig); resolver != nil {
+			if e, err := resolver.ResolveEndpoint(rolesAnywhereSigningName, cfg.Region); err == nil {
+				endpoint = e.URL
+			}
+		}
+		provider, err := NewRolesAnywhereCredentialsProvider(*awsSpcConfig.RolesAnywhere, defaultRoleSessionName(d.Connection.Name), endpoint, cfg.HTTPClient)
+		if err != nil {
+			plugin.Logger(ctx).Error("getBaseClientForAccountUncached", "connection_name", d.Connection.Name, "roles_anywhere_error", err)
+			return nil, err
+		}
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+
+	// Assume the role (or chain of roles) from the connection config on top of
+	// the credentials resolved above. This is done after the region is settled,
+	// since the background sts:AssumeRole calls need a region to sign requests.
+	if awsSpcConfig.RoleArn != nil || len(awsSpcConfig.AssumeRoleChain) > 0 {
+		plugin.Logger(ctx).Debug("getBaseClientForAccountUncached", "connection_name", d.Connection.Name, "status", "assume_role_found")
+		cfg.Credentials = getAssumeRoleChainCredentialsProvider(ctx, d, cfg, awsSpcConfig)
+	} else if awsSpcConfig.MFASerial != nil {
+		// Without a role to assume, MFA is added with an MFA session of the
+		// credentials resolved above
+		plugin.Logger(ctx).Debug("getBaseClientForAccountUncached", "connection_name", d.Connection.Name, "status", "mfa_serial_found")
+		cfg.Credentials = getMFASessionCredentialsProvider(ctx, d, cfg, awsSpcConfig)
+	}
+
 	plugin.Logger(ctx).Debug("getBaseClientForAccountUncached", "connection_name", d.Connection.Name, "status", "done")
 
 	return &cfg, err
 
 }
 
+// Build a credentials provider that assumes each role in the assume_role_chain
+// config in order, followed by the role_arn of the connection (if set). Each
+// step uses the credentials of the previous step, starting from the
+// credentials already resolved in cfg.
+// With mfa_serial, the first role is assumed with the MFA device, since it's
+// the only step using the credentials of the user.
+// Every provider in the chain is wrapped in a credentials cache, so the
+// assumed role sessions are only refreshed when they expire. Combined with
+// the memoized base client, this means each connection assumes its roles once
+// per session lifetime rather than once per region or query.
+func getAssumeRoleChainCredentialsProvider(ctx context.Context, d *plugin.QueryData, cfg aws.Config, awsSpcConfig awsConfig) aws.CredentialsProvider {
+
+	roles := append([]awsAssumeRoleChainConfig{}, awsSpcConfig.AssumeRoleChain...)
+	if awsSpcConfig.RoleArn != nil {
+		roles = append(roles, awsAssumeRoleChainConfig{
+			RoleArn:         *awsSpcConfig.RoleArn,
+			ExternalId:      awsSpcConfig.ExternalId,
+			RoleSessionName: awsSpcConfig.RoleSessionName,
+			DurationSeconds: awsSpcConfig.DurationSeconds,
+		})
+	}
+
+	provider := cfg.Credentials
+	for i, role := range roles {
+		plugin.Logger(ctx).Debug("getAssumeRoleChainCredentialsProvider", "connection_name", d.Connection.Name, "role_arn", role.RoleArn)
+
+		// Each role is assumed by an STS client using the credentials of the
+		// previous step in the chain.
+		stsCfg := cfg.Copy()
+		stsCfg.Credentials = provider
+
+		role := role
+		assumeRoleProvider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(stsCfg), role.RoleArn, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = defaultRoleSessionName(d.Connection.Name)
+			if role.RoleSessionName != nil {
+				o.RoleSessionName = *role.RoleSessionName
+			}
+			if role.ExternalId != nil {
+				o.ExternalID = role.ExternalId
+			}
+			if role.DurationSeconds != nil {
+				o.Duration = time.Duration(*role.DurationSeconds) * time.Second
+			}
+			if i == 0 && awsSpcConfig.MFASerial != nil {
+				o.SerialNumber = awsSpcConfig.MFASerial
+				o.TokenProvider = getMFATokenProvider(awsSpcConfig)
+			}
+		})
+		provider = aws.NewCredentialsCache(assumeRoleProvider)
+	}
+
+	return provider
+}
+
+// Build a credentials provider that exchanges the OIDC token in
+// web_identity_token_file for credentials of role_arn using
+// sts:AssumeRoleWithWebIdentity. The token file is read on every refresh, so
+// rotated tokens (e.g. Kubernetes projected service account tokens) are
+// picked up automatically.
+func getWebIdentityCredentialsProvider(ctx context.Context, d *plugin.QueryData, cfg aws.Config, awsSpcConfig awsConfig) aws.CredentialsProvider {
+	plugin.Logger(ctx).Debug("getWebIdentityCredentialsProvider", "connection_name", d.Connection.Name, "role_arn", *awsSpcConfig.RoleArn)
+
+	// AssumeRoleWithWebIdentity is an unsigned call, so the STS client does
+	// not need (and must not depend on) any other credentials.
+	stsCfg := cfg.Copy()
+	stsCfg.Credentials = aws.AnonymousCredentials{}
+
+	tokenRetriever := stscreds.IdentityTokenFile(*awsSpcConfig.WebIdentityTokenFile)
+	provider := stscreds.NewWebIdentityRoleProvider(sts.NewFromConfig(stsCfg), *awsSpcConfig.RoleArn, tokenRetriever, func(o *stscreds.WebIdentityRoleOptions) {
+		o.RoleSessionName = defaultRoleSessionName(d.Connection.Name)
+		if awsSpcConfig.RoleSessionName != nil {
+			o.RoleSessionName = *awsSpcConfig.RoleSessionName
+		}
+		if awsSpcConfig.DurationSeconds != nil {
+			o.Duration = time.Duration(*awsSpcConfig.DurationSeconds) * time.Second
+		}
+	})
+	return aws.NewCredentialsCache(provider)
+}
+
+// Role session names are limited to 64 characters from the set [\w+=,.@-].
+// Connection names are a subset of that, so only the length needs care.
+func defaultRoleSessionName(connectionName string) string {
+	name := fmt.Sprintf("steampipe-%s", connectionName)
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
 // HCLoggerToSmithyLoggerWrapper wraps an hclog Logger in order to pass it as an AWS SDK smithy Logger
 type HCLoggerToSmithyLoggerWrapper struct {
 	hclogger *hclog.Logger
@@ -29,6 +29,7 @@ func tableAwsAccount(ctx context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "organizations", "action": "DescribeOrganization"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "account_aliases",
@@ -122,6 +123,10 @@ func listAccountAlias(ctx context.Context, d *plugin.QueryData, h *plugin.Hydrat
 		plugin.Logger(ctx).Error("aws_account.listAccountAlias", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	commonData, err := getCommonColumns(ctx, d, h)
 	if err != nil {
@@ -162,6 +167,10 @@ func getOrganizationDetails(ctx context.Context, d *plugin.QueryData, _ *plugin.
 		plugin.Logger(ctx).Error("aws_account.getOrganizationDetails", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	op, err := svc.DescribeOrganization(ctx, &organizations.DescribeOrganizationInput{})
 	if err != nil {
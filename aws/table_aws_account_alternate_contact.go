@@ -36,6 +36,7 @@ func tableAwsAccountAlternateContact(_ context.Context) *plugin.Table {
 				},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "name",
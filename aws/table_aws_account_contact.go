@@ -29,6 +29,7 @@ func tableAwsAccountContact(_ context.Context) *plugin.Table {
 				},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "full_name",
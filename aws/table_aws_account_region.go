@@ -0,0 +1,217 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/account"
+	"github.com/aws/aws-sdk-go-v2/service/account/types"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+//// TABLE DEFINITION
+
+func tableAwsAccountRegion(_ context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "aws_account_region",
+		Description: "AWS Account Region",
+		List: &plugin.ListConfig{
+			Hydrate: listAwsAccountRegions,
+			Tags:    map[string]string{"service": "account", "action": "ListRegions"},
+			KeyColumns: []*plugin.KeyColumn{
+				{
+					Name:       "linked_account_id",
+					Require:    plugin.Optional,
+					CacheMatch: "exact",
+				},
+				{
+					Name:    "opt_status",
+					Require: plugin.Optional,
+				},
+			},
+		},
+		Get: &plugin.GetConfig{
+			KeyColumns: []*plugin.KeyColumn{
+				{Name: "region_name", Require: plugin.Required},
+				{Name: "linked_account_id", Require: plugin.Optional},
+			},
+			Hydrate: getAwsAccountRegion,
+			Tags:    map[string]string{"service": "account", "action": "GetRegionOptStatus"},
+		},
+		GetMatrixItemFunc: accountRegionMatrix,
+		Columns: awsGlobalRegionColumns([]*plugin.Column{
+			{
+				Name:        "region_name",
+				Description: "The region code, e.g. us-east-1.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "opt_status",
+				Description: "The opt-in status of the region: ENABLED, ENABLING, DISABLING, DISABLED or ENABLED_BY_DEFAULT.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("RegionOptStatus"),
+			},
+			{
+				Name:        "linked_account_id",
+				Description: "The account ID of the region status. Defaults to the account of the connection, set it to get the regions of a member account from the management or a delegated administrator account.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("LinkedAccountID"),
+			},
+			// Steampipe standard columns
+			{
+				Name:        "title",
+				Description: resourceInterfaceDescription("title"),
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("RegionName"),
+			},
+		}),
+	}
+}
+
+type accountRegionInfo struct {
+	RegionName      *string
+	RegionOptStatus types.RegionOptStatus
+	LinkedAccountID string
+}
+
+// With a linked_account_id qual, the regions of the linked account are listed
+// by the management account, so organization connections don't query every
+// member account (which would be denied, or return the same rows for each).
+func accountRegionMatrix(ctx context.Context, d *plugin.QueryData) []map[string]interface{} {
+	if d.EqualsQualString("linked_account_id") != "" {
+		return nil
+	}
+	return OrganizationAccountMatrix(ctx, d)
+}
+
+//// LIST FUNCTION
+
+func listAwsAccountRegions(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	logger := plugin.Logger(ctx)
+
+	commonData, err := getCommonColumns(ctx, d, h)
+	if err != nil {
+		return nil, err
+	}
+	commonColumnData := commonData.(*awsCommonColumnData)
+
+	// Account management APIs are not supported in GovCloud as of 2022-09-23
+	if commonColumnData.Partition == "aws-us-gov" {
+		return nil, nil
+	}
+
+	// Create service
+	svc, err := AccountClient(ctx, d)
+	if err != nil {
+		logger.Error("aws_account_region.listAwsAccountRegions", "service_creation_error", err)
+		return nil, err
+	}
+
+	if svc == nil {
+		// Unsupported region, return no data
+		return nil, nil
+	}
+
+	linkedAccountID := commonColumnData.AccountId
+	input := &account.ListRegionsInput{
+		MaxResults: aws.Int32(50),
+	}
+	// The account of the connection must be called without an account ID,
+	// even by the management account
+	if d.EqualsQualString("linked_account_id") != "" && d.EqualsQualString("linked_account_id") != linkedAccountID {
+		linkedAccountID = d.EqualsQualString("linked_account_id")
+		input.AccountId = aws.String(linkedAccountID)
+	}
+	if d.EqualsQualString("opt_status") != "" {
+		input.RegionOptStatusContains = []types.RegionOptStatus{types.RegionOptStatus(d.EqualsQualString("opt_status"))}
+	}
+
+	paginator := account.NewListRegionsPaginator(svc, input, func(o *account.ListRegionsPaginatorOptions) {
+		o.Limit = *input.MaxResults
+		o.StopOnDuplicateToken = true
+	})
+
+	for paginator.HasMorePages() {
+		// apply rate limiting
+		d.WaitForListRateLimit(ctx)
+
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			logger.Error("aws_account_region.listAwsAccountRegions", "api_error", err)
+			return nil, err
+		}
+
+		for _, region := range output.Regions {
+			d.StreamListItem(ctx, &accountRegionInfo{
+				RegionName:      region.RegionName,
+				RegionOptStatus: region.RegionOptStatus,
+				LinkedAccountID: linkedAccountID,
+			})
+
+			// Context can be cancelled due to manual cancellation or the limit has been hit
+			if d.RowsRemaining(ctx) == 0 {
+				return nil, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+//// HYDRATE FUNCTIONS
+
+func getAwsAccountRegion(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	logger := plugin.Logger(ctx)
+
+	regionName := d.EqualsQualString("region_name")
+	if regionName == "" {
+		return nil, nil
+	}
+
+	commonData, err := getCommonColumns(ctx, d, h)
+	if err != nil {
+		return nil, err
+	}
+	commonColumnData := commonData.(*awsCommonColumnData)
+
+	// Account management APIs are not supported in GovCloud as of 2022-09-23
+	if commonColumnData.Partition == "aws-us-gov" {
+		return nil, nil
+	}
+
+	// Create service
+	svc, err := AccountClient(ctx, d)
+	if err != nil {
+		logger.Error("aws_account_region.getAwsAccountRegion", "service_creation_error", err)
+		return nil, err
+	}
+
+	if svc == nil {
+		// Unsupported region, return no data
+		return nil, nil
+	}
+
+	linkedAccountID := commonColumnData.AccountId
+	input := &account.GetRegionOptStatusInput{
+		RegionName: aws.String(regionName),
+	}
+	if d.EqualsQualString("linked_account_id") != "" && d.EqualsQualString("linked_account_id") != linkedAccountID {
+		linkedAccountID = d.EqualsQualString("linked_account_id")
+		input.AccountId = aws.String(linkedAccountID)
+	}
+
+	op, err := svc.GetRegionOptStatus(ctx, input)
+	if err != nil {
+		logger.Error("aws_account_region.getAwsAccountRegion", "api_error", err)
+		return nil, err
+	}
+
+	return &accountRegionInfo{
+		RegionName:      op.RegionName,
+		RegionOptStatus: op.RegionOptStatus,
+		LinkedAccountID: linkedAccountID,
+	}, nil
+}
@@ -0,0 +1,111 @@
+package aws
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"testing"
+)
+
+// Account ListRegions response for the regions of newMultiRegionStubAPI
+var accountListRegionsResponse = jsonResponse(map[string]interface{}{
+	"Regions": []map[string]string{
+		{"RegionName": "us-east-1", "RegionOptStatus": "ENABLED_BY_DEFAULT"},
+		{"RegionName": "us-east-2", "RegionOptStatus": "ENABLING"},
+		{"RegionName": "us-west-2", "RegionOptStatus": "ENABLED"},
+		{"RegionName": "ap-south-1", "RegionOptStatus": "DISABLING"},
+	},
+})
+
+func TestAwsAccountRegion(t *testing.T) {
+	api := newStubAPI(t)
+	api.on("account", "POST /listRegions", accountListRegionsResponse)
+
+	rows, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_account_region",
+		Columns: []string{"region_name", "opt_status", "linked_account_id"},
+		Quals:   map[string]string{"linked_account_id": "111122223333"},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 rows, got %d: %v", len(rows), rows)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i]["region_name"].(string) < rows[j]["region_name"].(string) })
+	if rows[2]["region_name"] != "us-east-2" || rows[2]["opt_status"] != "ENABLING" || rows[2]["linked_account_id"] != "111122223333" {
+		t.Errorf("expected us-east-2 ENABLING in 111122223333, got %v", rows[2])
+	}
+
+	calls := api.calls("account", "POST /listRegions")
+	if len(calls) != 1 {
+		t.Fatalf("expected 1 ListRegions call, got %d", len(calls))
+	}
+	var input map[string]interface{}
+	if err := json.Unmarshal(calls[0].Body, &input); err != nil {
+		t.Fatalf("invalid ListRegions request: %v", err)
+	}
+	if input["AccountId"] != "111122223333" {
+		t.Errorf("expected AccountId 111122223333, got %v", input["AccountId"])
+	}
+}
+
+func TestAccountRegionSource(t *testing.T) {
+	api := newMultiRegionStubAPI(t)
+	api.on("account", "POST /listRegions", accountListRegionsResponse)
+	_, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_sns_topic",
+		Columns: []string{"topic_arn"},
+		Regions: []string{"*"},
+		Config:  `region_source = "account"`,
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if got := listTopicsRegions(api); got != "us-east-1,us-west-2" {
+		t.Errorf("expected ListTopics in us-east-1,us-west-2, got %s", got)
+	}
+
+	// Without access to the account service, fall back to the EC2 regions
+	api = newMultiRegionStubAPI(t)
+	api.on("account", "POST /listRegions", jsonErrorResponse(http.StatusForbidden, "AccessDeniedException", "not authorized"))
+	_, err = runTableQuery(t, api, tableQuery{
+		Table:   "aws_sns_topic",
+		Columns: []string{"topic_arn"},
+		Regions: []string{"*"},
+		Config:  `region_source = "account"`,
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if got := listTopicsRegions(api); got != "ap-south-1,us-east-1,us-east-2,us-west-2" {
+		t.Errorf("expected ListTopics in ap-south-1,us-east-1,us-east-2,us-west-2, got %s", got)
+	}
+}
+
+func TestAwsAccountRegionOrganizationConnection(t *testing.T) {
+	api := newStubAPI(t)
+	stubOrganization(api)
+	api.on("account", "POST /listRegions", accountListRegionsResponse)
+
+	// The linked account is only queried by the management account
+	rows, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_account_region",
+		Columns: []string{"region_name", "linked_account_id"},
+		Quals:   map[string]string{"linked_account_id": testMemberAccountId},
+		Config:  testOrganizationConfig,
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 rows, got %d: %v", len(rows), rows)
+	}
+	calls := api.calls("account", "POST /listRegions")
+	if len(calls) != 1 {
+		t.Fatalf("expected 1 ListRegions call, got %d", len(calls))
+	}
+	if accountId := stubRequestAccountId(calls[0]); accountId != stubAccountId {
+		t.Errorf("expected ListRegions to be called by the management account, got %s", accountId)
+	}
+}
@@ -0,0 +1,213 @@
+package aws
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/turbot/go-kit/helpers"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+	"github.com/turbot/steampipe-plugin-sdk/v5/rate_limiter"
+)
+
+type apiCallStatItem struct {
+	apiCallStatRow
+	AverageLatencyMs float64
+	HydrateFunctions []string
+}
+
+//// TABLE DEFINITION
+
+func tableAwsAPICallStat(_ context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "aws_api_call_stat",
+		Description: "AWS API Call Statistics",
+		List: &plugin.ListConfig{
+			Hydrate: listAPICallStats,
+			KeyColumns: []*plugin.KeyColumn{
+				{Name: "region", Require: plugin.Optional},
+				{Name: "service", Require: plugin.Optional},
+				{Name: "operation", Require: plugin.Optional},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "region",
+				Description: "The region of the client that made the calls.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "service",
+				Description: "The AWS SDK service ID, e.g. EC2 or Cost Explorer.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "operation",
+				Description: "The API operation, e.g. DescribeInstances.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "calls",
+				Description: "The number of calls made, not counting retries.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "retries",
+				Description: "The number of retried attempts.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "throttles",
+				Description: "The number of attempts that were throttled, including those that succeeded when retried.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "errors",
+				Description: "The number of calls that failed after all retries.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "total_latency_ms",
+				Description: "The total time spent in calls, in milliseconds, including retries.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("TotalLatency").Transform(durationToMilliseconds),
+			},
+			{
+				Name:        "average_latency_ms",
+				Description: "The average time of a call, in milliseconds, including retries.",
+				Type:        proto.ColumnType_DOUBLE,
+			},
+			{
+				Name:        "max_latency_ms",
+				Description: "The longest time of a call, in milliseconds, including retries.",
+				Type:        proto.ColumnType_INT,
+				Transform:   transform.FromField("MaxLatency").Transform(durationToMilliseconds),
+			},
+			{
+				Name:        "last_error_code",
+				Description: "The error code of the last failed call.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("LastErrorCode").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "first_call_time",
+				Description: "The time of the first call.",
+				Type:        proto.ColumnType_TIMESTAMP,
+			},
+			{
+				Name:        "last_call_time",
+				Description: "The time of the last call.",
+				Type:        proto.ColumnType_TIMESTAMP,
+			},
+			{
+				Name:        "hydrate_functions",
+				Description: "The hydrate functions of the plugin tables with service and action tags matching the call, as table.function.",
+				Type:        proto.ColumnType_JSON,
+			},
+		},
+	}
+}
+
+//// LIST FUNCTION
+
+func listAPICallStats(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	regionQual := d.EqualsQualString("region")
+	serviceQual := d.EqualsQualString("service")
+	operationQual := d.EqualsQualString("operation")
+
+	hydrateFunctions := getHydrateFunctionsByTags(d.Table.Plugin)
+
+	for _, row := range getAPICallStatRows(d.Connection.Name) {
+		if (regionQual != "" && regionQual != row.Region) || (serviceQual != "" && serviceQual != row.Service) || (operationQual != "" && operationQual != row.Operation) {
+			continue
+		}
+
+		item := &apiCallStatItem{
+			apiCallStatRow:   row,
+			HydrateFunctions: hydrateFunctions[hydrateTagsKey(getServiceTagForServiceID(row.Service), row.Operation)],
+		}
+		if row.Calls > 0 {
+			item.AverageLatencyMs = float64(row.TotalLatency.Microseconds()) / 1000 / float64(row.Calls)
+		}
+		d.StreamListItem(ctx, item)
+
+		// Context can be cancelled due to manual cancellation or the limit has been hit
+		if d.RowsRemaining(ctx) == 0 {
+			return nil, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Key the hydrate functions by their service and action tags. The SDK service
+// ID of an API call must be mapped to its tag with getServiceTagForServiceID
+// first, e.g. "Cost Explorer" is tagged "ce".
+func hydrateTagsKey(service string, action string) string {
+	return normalizeEndpointServiceKey(service) + ":" + action
+}
+
+// List the hydrate functions of the plugin tables by their service and
+// action tags, as table.function.
+func getHydrateFunctionsByTags(p *plugin.Plugin) map[string][]string {
+	functions := map[string][]string{}
+	if p == nil {
+		return functions
+	}
+	for tableName, table := range p.TableMap {
+		for key, names := range getTableHydrateFunctionsByTags(table) {
+			for _, name := range names {
+				functions[key] = append(functions[key], tableName+"."+name)
+			}
+		}
+	}
+	for _, names := range functions {
+		sort.Strings(names)
+	}
+	return functions
+}
+
+// List the hydrate functions of a table by their service and action tags.
+func getTableHydrateFunctionsByTags(table *plugin.Table) map[string][]string {
+	functions := map[string][]string{}
+	add := func(tags map[string]string, f plugin.HydrateFunc) {
+		if f == nil || tags["service"] == "" || tags["action"] == "" {
+			return
+		}
+		name := tags[rate_limiter.RateLimiterScopeFunction]
+		if name == "" {
+			name = helpers.GetFunctionName(f)
+		}
+		key := hydrateTagsKey(tags["service"], tags["action"])
+		if !helpers.StringSliceContains(functions[key], name) {
+			functions[key] = append(functions[key], name)
+		}
+	}
+	if table.List != nil {
+		add(table.List.Tags, table.List.Hydrate)
+		add(table.List.ParentTags, table.List.ParentHydrate)
+	}
+	if table.Get != nil {
+		add(table.Get.Tags, table.Get.Hydrate)
+	}
+	for _, h := range table.HydrateConfig {
+		add(h.Tags, h.Func)
+	}
+	for _, names := range functions {
+		sort.Strings(names)
+	}
+	return functions
+}
+
+//// TRANSFORM FUNCTIONS
+
+func durationToMilliseconds(_ context.Context, d *transform.TransformData) (interface{}, error) {
+	v, ok := d.Value.(time.Duration)
+	if !ok {
+		return nil, nil
+	}
+	return v.Milliseconds(), nil
+}
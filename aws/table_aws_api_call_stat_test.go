@@ -0,0 +1,86 @@
+package aws
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAwsAPICallStat(t *testing.T) {
+	// Stats are kept for the life of the process, so start from scratch
+	apiCallStats.Range(func(k, _ interface{}) bool {
+		apiCallStats.Delete(k)
+		return true
+	})
+
+	api := newStubAPI(t)
+	api.on("sns", "ListTopics", queryErrorResponse(http.StatusBadRequest, "Throttling", "Rate exceeded"))
+	_, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_sns_topic",
+		Columns: []string{"topic_arn"},
+		Config:  `ignore_error_codes = ["Throttling"]`,
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	rows, err := runTableQuery(t, api, tableQuery{
+		Table: "aws_api_call_stat",
+		Quals: map[string]string{"service": "SNS"},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d: %v", len(rows), rows)
+	}
+	row := rows[0]
+	if row["region"] != "us-east-1" || row["operation"] != "ListTopics" {
+		t.Errorf("expected ListTopics in us-east-1, got %v in %v", row["operation"], row["region"])
+	}
+	for column, expected := range map[string]int64{"calls": 1, "retries": 0, "throttles": 1, "errors": 1} {
+		if row[column] != expected {
+			t.Errorf("expected %s %d, got %v", column, expected, row[column])
+		}
+	}
+	if row["last_error_code"] != "Throttling" {
+		t.Errorf("expected last_error_code Throttling, got %v", row["last_error_code"])
+	}
+	functions, _ := row["hydrate_functions"].([]interface{})
+	if len(functions) != 1 || functions[0] != "aws_sns_topic.listAwsSnsTopics" {
+		t.Errorf("expected hydrate_functions [aws_sns_topic.listAwsSnsTopics], got %v", row["hydrate_functions"])
+	}
+}
+
+func TestAwsAPICallStatServiceTag(t *testing.T) {
+	apiCallStats.Range(func(k, _ interface{}) bool {
+		apiCallStats.Delete(k)
+		return true
+	})
+
+	// The Cost Explorer service ID doesn't match the "ce" service tag
+	api := newStubAPI(t)
+	api.on("ce", "GetCostForecast", jsonResponse(map[string]interface{}{"ForecastResultsByTime": []interface{}{}}))
+	_, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_cost_forecast_monthly",
+		Columns: []string{"period_start"},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	rows, err := runTableQuery(t, api, tableQuery{
+		Table: "aws_api_call_stat",
+		Quals: map[string]string{"service": "Cost Explorer"},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d: %v", len(rows), rows)
+	}
+	functions, _ := rows[0]["hydrate_functions"].([]interface{})
+	expected := []interface{}{"aws_cost_forecast_daily.listCostForecastDaily", "aws_cost_forecast_monthly.listCostForecastMonthly"}
+	if len(functions) != len(expected) || functions[0] != expected[0] || functions[1] != expected[1] {
+		t.Errorf("expected hydrate_functions %v, got %v", expected, rows[0]["hydrate_functions"])
+	}
+}
@@ -36,6 +36,7 @@ func tableAwsCloudFrontCachePolicy(_ context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "cloudfront", "action": "GetCachePolicy"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "name",
@@ -120,6 +121,10 @@ func listCloudFrontCachePolicies(ctx context.Context, d *plugin.QueryData, _ *pl
 		plugin.Logger(ctx).Error("aws_cloudfront_cache_policy.listCloudFrontCachePolicies", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	// The maximum number for MaxItems parameter is not defined by the API
 	// We have set the MaxItems to 1000 based on our test
@@ -179,6 +184,10 @@ func getCloudFrontCachePolicy(ctx context.Context, d *plugin.QueryData, h *plugi
 		plugin.Logger(ctx).Error("aws_cloudfront_cache_policy.getCloudFrontCachePolicy", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	var id string
 	if h.Item != nil {
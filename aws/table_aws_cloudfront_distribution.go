@@ -39,6 +39,7 @@ func tableAwsCloudFrontDistribution(_ context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "cloudfront", "action": "ListTagsForResource"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsRegionalColumns([]*plugin.Column{
 			{
 				Name:        "id",
@@ -249,6 +250,10 @@ func listAwsCloudFrontDistributions(ctx context.Context, d *plugin.QueryData, _
 		plugin.Logger(ctx).Error("aws_cloudfront_distribution.listAwsCloudFrontDistributions", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	maxItems := int32(1000)
 
@@ -306,6 +311,10 @@ func getCloudFrontDistribution(ctx context.Context, d *plugin.QueryData, h *plug
 		plugin.Logger(ctx).Error("aws_cloudfront_distribution.getCloudFrontDistribution", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	var distributionID string
 	if h.Item != nil {
@@ -338,6 +347,10 @@ func getCloudFrontDistributionTags(ctx context.Context, d *plugin.QueryData, h *
 		plugin.Logger(ctx).Error("aws_cloudfront_distribution.getCloudFrontDistributionTags", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	distributionAka := cloudFrontDistributionAka(h.Item)
 
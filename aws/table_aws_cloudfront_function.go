@@ -36,6 +36,7 @@ func tableAwsCloudFrontFunction(_ context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "cloudfront", "action": "GetFunction"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsRegionalColumns([]*plugin.Column{
 			{
 				Name:        "name",
@@ -102,6 +103,10 @@ func listCloudWatchFunctions(ctx context.Context, d *plugin.QueryData, h *plugin
 		plugin.Logger(ctx).Error("aws_cloudfront_function.listCloudWatchFunctions", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	maxItems := int32(100)
 
@@ -172,6 +177,10 @@ func getCloudFrontFunction(ctx context.Context, d *plugin.QueryData, h *plugin.H
 		plugin.Logger(ctx).Error("aws_cloudfront_function.getCloudFrontFunction", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	// Build the params
 	params := &cloudfront.DescribeFunctionInput{
@@ -35,6 +35,7 @@ func tableAwsCloudFrontOriginAccessIdentity(_ context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "cloudfront", "action": "GetCloudFrontOriginAccessIdentity"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "id",
@@ -103,6 +104,10 @@ func listCloudFrontOriginAccessIdentities(ctx context.Context, d *plugin.QueryDa
 		plugin.Logger(ctx).Error("aws_cloudfront_origin_access_identity.listCloudFrontOriginAccessIdentities", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	// The maximum number for MaxItems parameter is not defined by the API
 	// We have set the MaxItems to 1000 based on our test
@@ -161,6 +166,10 @@ func getCloudFrontOriginAccessIdentity(ctx context.Context, d *plugin.QueryData,
 		plugin.Logger(ctx).Error("aws_cloudfront_origin_access_identity.getCloudFrontOriginAccessIdentity", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	var identityID string
 	if h.Item != nil {
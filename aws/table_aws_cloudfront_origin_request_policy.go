@@ -36,6 +36,7 @@ func tableAwsCloudFrontOriginRequestPolicy(_ context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "cloudfront", "action": "GetOriginRequestPolicy"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "name",
@@ -114,6 +115,10 @@ func listCloudFrontOriginRequestPolicies(ctx context.Context, d *plugin.QueryDat
 		plugin.Logger(ctx).Error("aws_cloudfront_origin_request_policy.listCloudFrontOriginRequestPolicies", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	// The maximum number for MaxItems parameter is not defined by the API
 	// We have set the MaxItems to 1000 based on our test
@@ -174,6 +179,10 @@ func getCloudFrontOriginRequestPolicy(ctx context.Context, d *plugin.QueryData,
 		plugin.Logger(ctx).Error("aws_cloudfront_origin_request_policy.getCloudFrontOriginRequestPolicy", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	var policyID string
 	if h.Item != nil {
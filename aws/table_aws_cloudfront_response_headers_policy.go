@@ -33,6 +33,7 @@ func tableAwsCloudFrontResponseHeadersPolicy(_ context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "cloudfront", "action": "GetResponseHeadersPolicy"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsRegionalColumns([]*plugin.Column{
 			{
 				Name:        "name",
@@ -104,6 +105,10 @@ func listCloudFrontResponseHeadersPolicies(ctx context.Context, d *plugin.QueryD
 		plugin.Logger(ctx).Error("aws_cloudfront_response_headers_policy.listCloudFrontResponseHeadersPolicies", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	// The maximum number for MaxItems parameter is not defined by the API
 	// We have set the MaxItems to 1000 based on our test
@@ -170,6 +175,10 @@ func getETagValue(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateDat
 		plugin.Logger(ctx).Error("aws_cloudfront_response_headers_policy.getETagValue", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	// Build the params
 	params := &cloudfront.GetResponseHeadersPolicyInput{Id: &id}
@@ -0,0 +1,643 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudfront"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/configservice"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+
+	autoscalingv1 "github.com/aws/aws-sdk-go/service/autoscaling"
+	cloudformationv1 "github.com/aws/aws-sdk-go/service/cloudformation"
+	cloudtrailv1 "github.com/aws/aws-sdk-go/service/cloudtrail"
+	cloudwatchv1 "github.com/aws/aws-sdk-go/service/cloudwatch"
+	cloudwatchlogsv1 "github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	configservicev1 "github.com/aws/aws-sdk-go/service/configservice"
+	dynamodbv1 "github.com/aws/aws-sdk-go/service/dynamodb"
+	ec2v1 "github.com/aws/aws-sdk-go/service/ec2"
+	ecrv1 "github.com/aws/aws-sdk-go/service/ecr"
+	ecsv1 "github.com/aws/aws-sdk-go/service/ecs"
+	elbv2v1 "github.com/aws/aws-sdk-go/service/elbv2"
+	kmsv1 "github.com/aws/aws-sdk-go/service/kms"
+	lambdav1 "github.com/aws/aws-sdk-go/service/lambda"
+	rdsv1 "github.com/aws/aws-sdk-go/service/rds"
+	secretsmanagerv1 "github.com/aws/aws-sdk-go/service/secretsmanager"
+	snsv1 "github.com/aws/aws-sdk-go/service/sns"
+	sqsv1 "github.com/aws/aws-sdk-go/service/sqs"
+	ssmv1 "github.com/aws/aws-sdk-go/service/ssm"
+	stsv1 "github.com/aws/aws-sdk-go/service/sts"
+
+	"github.com/turbot/go-kit/helpers"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// Connection diagnostics
+//
+// Each row is the result of one cheap, read-only probe call for a service in
+// a region, using the credentials and settings of the connection. Global
+// services are probed once, with the region "global". Probes are not run for
+// regions where the service is not available, or that are not opted in.
+
+const (
+	connectionDiagnosticStatusOk           = "ok"
+	connectionDiagnosticStatusError        = "error"
+	connectionDiagnosticStatusNotSupported = "not_supported"
+	connectionDiagnosticStatusNotOptedIn   = "not_opted_in"
+)
+
+// Maximum number of probe calls running at the same time.
+const connectionDiagnosticConcurrency = 10
+
+type connectionDiagnosticProbe struct {
+	// Service name, as used in the service tag of rate limiters.
+	Service string
+	// Endpoints ID used to check if the service is available in a region.
+	// Empty for global services.
+	ServiceID string
+	Action    string
+	Call      func(ctx context.Context, cfg aws.Config, awsSpcConfig awsConfig) error
+}
+
+// Probes cover the most commonly queried services only, not every client in
+// service.go: each probe needs a read-only action that is cheap and needs no
+// resource ID, which has to be picked by hand for each service. They are
+// ordered by service name, with global services first. The list of probed
+// services is in the table docs, update it with the probes.
+var connectionDiagnosticProbes = []connectionDiagnosticProbe{
+	{
+		Service: "cloudfront",
+		Action:  "ListDistributions",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			_, err := cloudfront.NewFromConfig(cfg).ListDistributions(ctx, &cloudfront.ListDistributionsInput{MaxItems: aws.Int32(1)})
+			return err
+		},
+	},
+	{
+		Service: "iam",
+		Action:  "GetAccountSummary",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			_, err := iam.NewFromConfig(cfg).GetAccountSummary(ctx, &iam.GetAccountSummaryInput{})
+			return err
+		},
+	},
+	{
+		Service: "organizations",
+		Action:  "DescribeOrganization",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			_, err := organizations.NewFromConfig(cfg).DescribeOrganization(ctx, &organizations.DescribeOrganizationInput{})
+			return err
+		},
+	},
+	{
+		Service: "route53",
+		Action:  "ListHostedZones",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			_, err := route53.NewFromConfig(cfg).ListHostedZones(ctx, &route53.ListHostedZonesInput{MaxItems: aws.Int32(1)})
+			return err
+		},
+	},
+	{
+		Service: "s3",
+		Action:  "ListBuckets",
+		Call: func(ctx context.Context, cfg aws.Config, awsSpcConfig awsConfig) error {
+			svc := s3.NewFromConfig(cfg, func(o *s3.Options) {
+				if awsSpcConfig.S3ForcePathStyle != nil {
+					o.UsePathStyle = *awsSpcConfig.S3ForcePathStyle
+				}
+			})
+			_, err := svc.ListBuckets(ctx, &s3.ListBucketsInput{})
+			return err
+		},
+	},
+	{
+		Service:   "autoscaling",
+		ServiceID: autoscalingv1.EndpointsID,
+		Action:    "DescribeAutoScalingGroups",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			_, err := autoscaling.NewFromConfig(cfg).DescribeAutoScalingGroups(ctx, &autoscaling.DescribeAutoScalingGroupsInput{MaxRecords: aws.Int32(1)})
+			return err
+		},
+	},
+	{
+		Service:   "cloudformation",
+		ServiceID: cloudformationv1.EndpointsID,
+		Action:    "ListStacks",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			_, err := cloudformation.NewFromConfig(cfg).ListStacks(ctx, &cloudformation.ListStacksInput{})
+			return err
+		},
+	},
+	{
+		Service:   "cloudtrail",
+		ServiceID: cloudtrailv1.EndpointsID,
+		Action:    "DescribeTrails",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			_, err := cloudtrail.NewFromConfig(cfg).DescribeTrails(ctx, &cloudtrail.DescribeTrailsInput{})
+			return err
+		},
+	},
+	{
+		Service:   "cloudwatch",
+		ServiceID: cloudwatchv1.EndpointsID,
+		Action:    "DescribeAlarms",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			_, err := cloudwatch.NewFromConfig(cfg).DescribeAlarms(ctx, &cloudwatch.DescribeAlarmsInput{MaxRecords: aws.Int32(1)})
+			return err
+		},
+	},
+	{
+		Service:   "config",
+		ServiceID: configservicev1.EndpointsID,
+		Action:    "DescribeConfigurationRecorders",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			_, err := configservice.NewFromConfig(cfg).DescribeConfigurationRecorders(ctx, &configservice.DescribeConfigurationRecordersInput{})
+			return err
+		},
+	},
+	{
+		Service:   "dynamodb",
+		ServiceID: dynamodbv1.EndpointsID,
+		Action:    "ListTables",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			_, err := dynamodb.NewFromConfig(cfg).ListTables(ctx, &dynamodb.ListTablesInput{Limit: aws.Int32(1)})
+			return err
+		},
+	},
+	{
+		Service:   "ec2",
+		ServiceID: ec2v1.EndpointsID,
+		Action:    "DescribeAvailabilityZones",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			_, err := ec2.NewFromConfig(cfg).DescribeAvailabilityZones(ctx, &ec2.DescribeAvailabilityZonesInput{})
+			return err
+		},
+	},
+	{
+		Service:   "ecr",
+		ServiceID: ecrv1.EndpointsID,
+		Action:    "DescribeRepositories",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			_, err := ecr.NewFromConfig(cfg).DescribeRepositories(ctx, &ecr.DescribeRepositoriesInput{MaxResults: aws.Int32(1)})
+			return err
+		},
+	},
+	{
+		Service:   "ecs",
+		ServiceID: ecsv1.EndpointsID,
+		Action:    "ListClusters",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			_, err := ecs.NewFromConfig(cfg).ListClusters(ctx, &ecs.ListClustersInput{MaxResults: aws.Int32(1)})
+			return err
+		},
+	},
+	{
+		Service:   "elasticloadbalancing",
+		ServiceID: elbv2v1.EndpointsID,
+		Action:    "DescribeLoadBalancers",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			_, err := elasticloadbalancingv2.NewFromConfig(cfg).DescribeLoadBalancers(ctx, &elasticloadbalancingv2.DescribeLoadBalancersInput{PageSize: aws.Int32(1)})
+			return err
+		},
+	},
+	{
+		Service:   "kms",
+		ServiceID: kmsv1.EndpointsID,
+		Action:    "ListKeys",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			_, err := kms.NewFromConfig(cfg).ListKeys(ctx, &kms.ListKeysInput{Limit: aws.Int32(1)})
+			return err
+		},
+	},
+	{
+		Service:   "lambda",
+		ServiceID: lambdav1.EndpointsID,
+		Action:    "ListFunctions",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			_, err := lambda.NewFromConfig(cfg).ListFunctions(ctx, &lambda.ListFunctionsInput{MaxItems: aws.Int32(1)})
+			return err
+		},
+	},
+	{
+		Service:   "logs",
+		ServiceID: cloudwatchlogsv1.EndpointsID,
+		Action:    "DescribeLogGroups",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			_, err := cloudwatchlogs.NewFromConfig(cfg).DescribeLogGroups(ctx, &cloudwatchlogs.DescribeLogGroupsInput{Limit: aws.Int32(1)})
+			return err
+		},
+	},
+	{
+		Service:   "rds",
+		ServiceID: rdsv1.EndpointsID,
+		Action:    "DescribeDBInstances",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			// 20 is the minimum page size for RDS
+			_, err := rds.NewFromConfig(cfg).DescribeDBInstances(ctx, &rds.DescribeDBInstancesInput{MaxRecords: aws.Int32(20)})
+			return err
+		},
+	},
+	{
+		Service:   "secretsmanager",
+		ServiceID: secretsmanagerv1.EndpointsID,
+		Action:    "ListSecrets",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			_, err := secretsmanager.NewFromConfig(cfg).ListSecrets(ctx, &secretsmanager.ListSecretsInput{MaxResults: aws.Int32(1)})
+			return err
+		},
+	},
+	{
+		Service:   "sns",
+		ServiceID: snsv1.EndpointsID,
+		Action:    "ListTopics",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			_, err := sns.NewFromConfig(cfg).ListTopics(ctx, &sns.ListTopicsInput{})
+			return err
+		},
+	},
+	{
+		Service:   "sqs",
+		ServiceID: sqsv1.EndpointsID,
+		Action:    "ListQueues",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			_, err := sqs.NewFromConfig(cfg).ListQueues(ctx, &sqs.ListQueuesInput{MaxResults: aws.Int32(1)})
+			return err
+		},
+	},
+	{
+		Service:   "ssm",
+		ServiceID: ssmv1.EndpointsID,
+		Action:    "DescribeParameters",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			_, err := ssm.NewFromConfig(cfg).DescribeParameters(ctx, &ssm.DescribeParametersInput{MaxResults: aws.Int32(1)})
+			return err
+		},
+	},
+	{
+		Service:   "sts",
+		ServiceID: stsv1.EndpointsID,
+		Action:    "GetCallerIdentity",
+		Call: func(ctx context.Context, cfg aws.Config, _ awsConfig) error {
+			_, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+			return err
+		},
+	},
+}
+
+type connectionDiagnostic struct {
+	Region               string
+	Service              string
+	Action               string
+	Status               string
+	ErrorCode            string
+	ErrorMessage         string
+	Ignored              bool
+	LatencyMs            int64
+	RegionOptInStatus    string
+	CredentialSource     string
+	CredentialExpiration *time.Time
+	CredentialError      string
+	DefaultRegion        string
+	Partition            string
+	AccountId            string
+	CallerArn            string
+}
+
+//// TABLE DEFINITION
+
+func tableAwsConnectionDiagnostic(_ context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "aws_connection_diagnostic",
+		Description: "AWS Connection Diagnostic",
+		List: &plugin.ListConfig{
+			Hydrate: listConnectionDiagnostics,
+			KeyColumns: []*plugin.KeyColumn{
+				{Name: "region", Require: plugin.Optional},
+				{Name: "service", Require: plugin.Optional},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "region",
+				Description: "The region probed, or global for global services.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "service",
+				Description: "The service probed, e.g. ec2.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "action",
+				Description: "The API action called to probe the service, e.g. DescribeAvailabilityZones.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "status",
+				Description: "The result of the probe: ok, error, not_supported (the service is not available in the region, or the region is excluded by service_regions) or not_opted_in (the region is not enabled for the account).",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "error_code",
+				Description: "The error code returned by the probe call, e.g. AccessDeniedException.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("ErrorCode").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "error_message",
+				Description: "The error message returned by the probe call.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("ErrorMessage").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "ignored",
+				Description: "True if the error code matches ignore_error_codes in the connection config, so tables silently return no rows for this service and region.",
+				Type:        proto.ColumnType_BOOL,
+			},
+			{
+				Name:        "latency_ms",
+				Description: "The time taken by the probe call (including retries) in milliseconds.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "region_opt_in_status",
+				Description: "The opt-in status of the region for the account: opt-in-not-required, opted-in or not-opted-in. Null if the region list could not be retrieved.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("RegionOptInStatus").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "credential_source",
+				Description: "The source of the credentials used by the connection, e.g. SharedConfigCredentials or AssumeRoleProvider.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("CredentialSource").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "credential_expiration",
+				Description: "The time the credentials used by the connection expire, if they can expire.",
+				Type:        proto.ColumnType_TIMESTAMP,
+			},
+			{
+				Name:        "credential_error",
+				Description: "The error retrieving the credentials of the connection, e.g. an expired SSO token or a role that can't be assumed. Null if the credentials were retrieved.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("CredentialError").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "default_region",
+				Description: "The default region of the connection, used for connection-wide calls. Its partition decides the endpoints of global services.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "partition",
+				Description: "The AWS partition of the connection, e.g. aws, aws-cn or aws-us-gov.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "account_id",
+				Description: "The AWS account ID of the connection credentials. Null if the caller identity could not be retrieved.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("AccountId").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "caller_arn",
+				Description: "The ARN of the IAM user or role used by the connection. Null if the caller identity could not be retrieved.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("CallerArn").Transform(transform.NullIfZeroValue),
+			},
+		},
+	}
+}
+
+//// LIST FUNCTION
+
+func listConnectionDiagnostics(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	awsSpcConfig := GetConfig(d.Connection)
+
+	defaultRegion, err := getDefaultRegion(ctx, d, h)
+	if err != nil {
+		plugin.Logger(ctx).Error("aws_connection_diagnostic.listConnectionDiagnostics", "default_region_error", err)
+		return nil, err
+	}
+
+	// Connection-wide details, shared by every row. Failures here are part
+	// of the diagnosis, so they are reported in the rows rather than returned.
+	base := connectionDiagnostic{
+		DefaultRegion: defaultRegion,
+		Partition:     awsPartitionFromRegion(defaultRegion),
+	}
+	if cfg, err := getBaseClientForAccount(ctx, d); err != nil {
+		plugin.Logger(ctx).Warn("aws_connection_diagnostic.listConnectionDiagnostics", "base_client_error", err)
+		base.CredentialError = err.Error()
+	} else if creds, err := cfg.Credentials.Retrieve(ctx); err != nil {
+		plugin.Logger(ctx).Warn("aws_connection_diagnostic.listConnectionDiagnostics", "credentials_error", err)
+		base.CredentialError = err.Error()
+	} else {
+		base.CredentialSource = creds.Source
+		if creds.CanExpire {
+			base.CredentialExpiration = aws.Time(creds.Expires)
+		}
+	}
+	if i, err := getCallerIdentity(ctx, d, h); err != nil {
+		plugin.Logger(ctx).Warn("aws_connection_diagnostic.listConnectionDiagnostics", "caller_identity_error", err)
+	} else {
+		identity := i.(*sts.GetCallerIdentityOutput)
+		base.AccountId = aws.ToString(identity.Account)
+		base.CallerArn = aws.ToString(identity.Arn)
+		if parts := strings.Split(base.CallerArn, ":"); len(parts) > 1 {
+			base.Partition = parts[1]
+		}
+	}
+
+	regions, err := listConnectionDiagnosticRegions(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("aws_connection_diagnostic.listConnectionDiagnostics", "regions_error", err)
+		return nil, err
+	}
+
+	// Opt-in status per region, when the region list is available
+	optInStatus := map[string]string{}
+	if i, err := listRawAwsRegions(ctx, d, h); err == nil {
+		for _, r := range i.([]types.Region) {
+			optInStatus[aws.ToString(r.RegionName)] = aws.ToString(r.OptInStatus)
+		}
+	}
+
+	regionQual := d.EqualsQualString("region")
+	serviceQual := d.EqualsQualString("service")
+
+	var rows []*connectionDiagnostic
+	var probes []func()
+	addRow := func(region string, probe connectionDiagnosticProbe, status string) *connectionDiagnostic {
+		row := base
+		row.Region = region
+		row.Service = probe.Service
+		row.Action = probe.Action
+		row.Status = status
+		row.RegionOptInStatus = optInStatus[region]
+		rows = append(rows, &row)
+		return &row
+	}
+
+	for _, probe := range connectionDiagnosticProbes {
+		probe := probe
+		if serviceQual != "" && serviceQual != probe.Service {
+			continue
+		}
+
+		if probe.ServiceID == "" {
+			if regionQual != "" && regionQual != "global" {
+				continue
+			}
+			// Global services are called in the region of their endpoint in
+			// the partition, and are not available in every partition.
+			globalRegion, err := getGlobalServiceRegion(ctx, d, probe.Service)
+			if err != nil {
+				plugin.Logger(ctx).Error("aws_connection_diagnostic.listConnectionDiagnostics", "service", probe.Service, "global_region_error", err)
+				return nil, err
+			}
+			if globalRegion == "" {
+				addRow("global", probe, connectionDiagnosticStatusNotSupported)
+				continue
+			}
+			row := addRow("global", probe, "")
+			probes = append(probes, func() { runConnectionDiagnosticProbe(ctx, d, globalRegion, probe, awsSpcConfig, row) })
+			continue
+		}
+
+		// The regions of the service depend on the partition of the caller
+		// identity, so this fails if the credentials do. Report the error in
+		// each region instead of failing the query.
+		supportedRegions, regionsErr := listRegionsForService(ctx, d, probe.ServiceID)
+		if regionsErr != nil {
+			plugin.Logger(ctx).Warn("aws_connection_diagnostic.listConnectionDiagnostics", "service", probe.Service, "regions_error", regionsErr)
+		}
+
+		for _, region := range regions {
+			region := region
+			if regionQual != "" && regionQual != region {
+				continue
+			}
+			switch {
+			case regionsErr != nil:
+				setConnectionDiagnosticError(addRow(region, probe, ""), regionsErr, awsSpcConfig)
+			case optInStatus[region] == "not-opted-in":
+				addRow(region, probe, connectionDiagnosticStatusNotOptedIn)
+			case !helpers.StringSliceContains(supportedRegions, region):
+				addRow(region, probe, connectionDiagnosticStatusNotSupported)
+			default:
+				row := addRow(region, probe, "")
+				probes = append(probes, func() { runConnectionDiagnosticProbe(ctx, d, region, probe, awsSpcConfig, row) })
+			}
+		}
+	}
+
+	// Run the probes in parallel, but stream the rows in a stable order
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, connectionDiagnosticConcurrency)
+	for _, run := range probes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(run func()) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			run()
+		}(run)
+	}
+	wg.Wait()
+
+	for _, row := range rows {
+		d.StreamListItem(ctx, row)
+
+		// Context can be cancelled due to manual cancellation or the limit has been hit
+		if d.RowsRemaining(ctx) == 0 {
+			return nil, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// List the regions to probe: the query regions of the connection, plus any
+// regions that match the regions config but are not opted in, so it is clear
+// why they return no results.
+func listConnectionDiagnosticRegions(ctx context.Context, d *plugin.QueryData) ([]string, error) {
+	regions, err := listQueryRegionsForConnection(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	awsSpcConfig := GetConfig(d.Connection)
+	if awsSpcConfig.Regions == nil {
+		return regions, nil
+	}
+
+	iRegionData, err := listRegionsCached(ctx, d, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, region := range iRegionData.(RegionsData).NotOptedRegions {
+		if matchRegionPatterns(awsSpcConfig.Regions, region) {
+			regions = append(regions, region)
+		}
+	}
+	return regions, nil
+}
+
+// Run a single probe and record the result in the row.
+func runConnectionDiagnosticProbe(ctx context.Context, d *plugin.QueryData, region string, probe connectionDiagnosticProbe, awsSpcConfig awsConfig, row *connectionDiagnostic) {
+	start := time.Now()
+	cfg, err := getClientForRegion(ctx, d, region)
+	if err == nil {
+		err = probe.Call(ctx, *cfg, awsSpcConfig)
+	}
+	row.LatencyMs = time.Since(start).Milliseconds()
+
+	if err == nil {
+		row.Status = connectionDiagnosticStatusOk
+		return
+	}
+
+	plugin.Logger(ctx).Debug("aws_connection_diagnostic.runConnectionDiagnosticProbe", "service", probe.Service, "region", region, "error", err)
+	setConnectionDiagnosticError(row, err, awsSpcConfig)
+}
+
+// Record an error in the row, with the error code if it is an API error.
+func setConnectionDiagnosticError(row *connectionDiagnostic, err error, awsSpcConfig awsConfig) {
+	row.Status = connectionDiagnosticStatusError
+	row.ErrorMessage = err.Error()
+	var ae smithy.APIError
+	if errors.As(err, &ae) {
+		row.ErrorCode = ae.ErrorCode()
+		row.ErrorMessage = ae.ErrorMessage()
+		row.Ignored = matchesErrorCode(awsSpcConfig.IgnoreErrorCodes, row.ErrorCode)
+	}
+}
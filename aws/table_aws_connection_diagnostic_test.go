@@ -0,0 +1,96 @@
+package aws
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAwsConnectionDiagnosticOk(t *testing.T) {
+	api := newStubAPI(t)
+	api.on("sns", "ListTopics", queryResponse("ListTopics", `<Topics/>`))
+
+	rows, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_connection_diagnostic",
+		Columns: []string{"region", "service", "action", "status", "error_code", "region_opt_in_status", "credential_source", "default_region", "partition", "account_id"},
+		Quals:   map[string]string{"service": "sns"},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d: %v", len(rows), rows)
+	}
+
+	expected := map[string]interface{}{
+		"region":               "us-east-1",
+		"service":              "sns",
+		"action":               "ListTopics",
+		"status":               "ok",
+		"error_code":           nil,
+		"region_opt_in_status": "opt-in-not-required",
+		"credential_source":    "StaticCredentials",
+		"default_region":       "us-east-1",
+		"partition":            "aws",
+		"account_id":           stubAccountId,
+	}
+	for k, v := range expected {
+		if got := rows[0][k]; got != v {
+			t.Errorf("%s: expected %v, got %v", k, v, got)
+		}
+	}
+}
+
+func TestAwsConnectionDiagnosticError(t *testing.T) {
+	api := newStubAPI(t)
+	api.on("iam", "GetAccountSummary", queryErrorResponse(http.StatusForbidden, "AccessDenied", "not authorized to perform: iam:GetAccountSummary"))
+
+	rows, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_connection_diagnostic",
+		Columns: []string{"region", "service", "status", "error_code", "error_message", "ignored"},
+		Quals:   map[string]string{"service": "iam"},
+		Config:  `ignore_error_codes = ["AccessDenied*"]`,
+	})
+	if err != nil {
+		t.Fatalf("expected the probe error to be reported as a row, got: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d: %v", len(rows), rows)
+	}
+
+	expected := map[string]interface{}{
+		"region":        "global",
+		"service":       "iam",
+		"status":        "error",
+		"error_code":    "AccessDenied",
+		"error_message": "not authorized to perform: iam:GetAccountSummary",
+		"ignored":       true,
+	}
+	for k, v := range expected {
+		if got := rows[0][k]; got != v {
+			t.Errorf("%s: expected %v, got %v", k, v, got)
+		}
+	}
+}
+
+func TestAwsConnectionDiagnosticCredentialError(t *testing.T) {
+	api := newStubAPI(t)
+	api.on("sts", "AssumeRole", queryErrorResponse(http.StatusForbidden, "AccessDenied", "not authorized to perform: sts:AssumeRole"))
+
+	rows, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_connection_diagnostic",
+		Columns: []string{"service", "status", "credential_source", "credential_error"},
+		Quals:   map[string]string{"service": "sns"},
+		Config:  `role_arn = "arn:aws:iam::123456789012:role/missing"`,
+	})
+	if err != nil {
+		t.Fatalf("expected the credential error to be reported as a row, got: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d: %v", len(rows), rows)
+	}
+	credentialError, _ := rows[0]["credential_error"].(string)
+	if !strings.Contains(credentialError, "AccessDenied") || rows[0]["credential_source"] != nil || rows[0]["status"] != "error" {
+		t.Errorf("expected the AssumeRole error in credential_error, got %v", rows[0])
+	}
+}
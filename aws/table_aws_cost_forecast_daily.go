@@ -54,6 +54,10 @@ func listCostForecastDaily(ctx context.Context, d *plugin.QueryData, _ *plugin.H
 		plugin.Logger(ctx).Error("aws_cost_forecast_daily.listCostForecastDaily", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := buildCostForecastInput(d.EqualsQuals, "DAILY")
 
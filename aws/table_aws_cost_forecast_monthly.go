@@ -49,6 +49,10 @@ func listCostForecastMonthly(ctx context.Context, d *plugin.QueryData, _ *plugin
 		plugin.Logger(ctx).Error("aws_cost_forecast_monthly.listCostForecastMonthly", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := buildCostForecastInput(d.EqualsQuals, "MONTHLY")
 
@@ -39,6 +39,7 @@ func tableAwsGlobalAcceleratorAccelerator(_ context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "globalaccelerator", "action": "DescribeAcceleratorAttributes"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "name",
@@ -146,6 +147,11 @@ func listGlobalAcceleratorAccelerators(ctx context.Context, d *plugin.QueryData,
 		return nil, err
 	}
 
+	// Unsupported partition, return no data
+	if svc == nil {
+		return nil, nil
+	}
+
 	maxItems := int32(100)
 
 	// Reduce the basic request limit down if the user has only requested a small number of rows
@@ -208,6 +214,11 @@ func getGlobalAcceleratorAccelerator(ctx context.Context, d *plugin.QueryData, _
 		return nil, err
 	}
 
+	// Unsupported partition, return no data
+	if svc == nil {
+		return nil, nil
+	}
+
 	// Build the params
 	params := &globalaccelerator.DescribeAcceleratorInput{
 		AcceleratorArn: aws.String(arn),
@@ -231,6 +242,11 @@ func getGlobalAcceleratorAcceleratorTags(ctx context.Context, d *plugin.QueryDat
 		return nil, err
 	}
 
+	// Unsupported partition, return no data
+	if svc == nil {
+		return nil, nil
+	}
+
 	// Build the params
 	params := &globalaccelerator.ListTagsForResourceInput{
 		ResourceArn: accelerator.AcceleratorArn,
@@ -254,6 +270,11 @@ func getGlobalAcceleratorAcceleratorAttributes(ctx context.Context, d *plugin.Qu
 		return nil, err
 	}
 
+	// Unsupported partition, return no data
+	if svc == nil {
+		return nil, nil
+	}
+
 	// Build the params
 	params := &globalaccelerator.DescribeAcceleratorAttributesInput{
 		AcceleratorArn: accelerator.AcceleratorArn,
@@ -36,6 +36,7 @@ func tableAwsGlobalAcceleratorEndpointGroup(_ context.Context) *plugin.Table {
 			Hydrate:       listGlobalAcceleratorEndpointGroups,
 			Tags:          map[string]string{"service": "globalaccelerator", "action": "ListListeners"},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "arn",
@@ -140,6 +141,11 @@ func listGlobalAcceleratorEndpointGroups(ctx context.Context, d *plugin.QueryDat
 		return nil, err
 	}
 
+	// Unsupported partition, return no data
+	if svc == nil {
+		return nil, nil
+	}
+
 	// First get accelerator listener ARNs
 	listenerArns := []*string{}
 
@@ -234,6 +240,11 @@ func getGlobalAcceleratorEndpointGroup(ctx context.Context, d *plugin.QueryData,
 		return nil, err
 	}
 
+	// Unsupported partition, return no data
+	if svc == nil {
+		return nil, nil
+	}
+
 	// Build the params
 	params := &globalaccelerator.DescribeEndpointGroupInput{
 		EndpointGroupArn: aws.String(arn),
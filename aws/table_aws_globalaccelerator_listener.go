@@ -33,6 +33,7 @@ func tableAwsGlobalAcceleratorListener(_ context.Context) *plugin.Table {
 			Hydrate:       listGlobalAcceleratorListeners,
 			Tags:          map[string]string{"service": "globalaccelerator", "action": "ListListeners"},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "arn",
@@ -100,6 +101,11 @@ func listGlobalAcceleratorListeners(ctx context.Context, d *plugin.QueryData, h
 		return nil, err
 	}
 
+	// Unsupported partition, return no data
+	if svc == nil {
+		return nil, nil
+	}
+
 	maxItems := int32(100)
 
 	// Reduce the basic request limit down if the user has only requested a small number of rows
@@ -164,6 +170,11 @@ func getGlobalAcceleratorListener(ctx context.Context, d *plugin.QueryData, _ *p
 		return nil, err
 	}
 
+	// Unsupported partition, return no data
+	if svc == nil {
+		return nil, nil
+	}
+
 	// Build the params
 	params := &globalaccelerator.DescribeListenerInput{
 		ListenerArn: aws.String(arn),
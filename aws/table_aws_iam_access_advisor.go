@@ -115,6 +115,10 @@ func listAccessAdvisor(ctx context.Context, d *plugin.QueryData, h *plugin.Hydra
 		plugin.Logger(ctx).Error("aws_iam_access_advisor.listAccessAdvisor", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	// Generate the details.  We'll need the job id of this to get the details...
 	generateResp, err := svc.GenerateServiceLastAccessedDetails(
@@ -31,6 +31,7 @@ func tableAwsIamAccessKey(_ context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "iam", "action": "GetAccessKeyLastUsed"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "access_key_id",
@@ -115,6 +116,10 @@ func listUserAccessKeys(ctx context.Context, d *plugin.QueryData, h *plugin.Hydr
 		plugin.Logger(ctx).Error("aws_iam_access_key.listUserAccessKeys", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &iam.ListAccessKeysInput{UserName: user.UserName}
 
@@ -168,6 +173,10 @@ func getIamAccessKeyLastUsed(ctx context.Context, d *plugin.QueryData, h *plugin
 		plugin.Logger(ctx).Error("aws_iam_access_key.getIamAccessKeyLastUsed", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	accessKey := h.Item.(types.AccessKeyMetadata)
 
@@ -20,6 +20,7 @@ func tableAwsIamAccountPasswordPolicy(_ context.Context) *plugin.Table {
 			Hydrate: listAccountPasswordPolicies,
 			Tags:    map[string]string{"service": "iam", "action": "GetAccountPasswordPolicy"},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "allow_users_to_change_password",
@@ -94,6 +95,10 @@ func listAccountPasswordPolicies(ctx context.Context, d *plugin.QueryData, _ *pl
 		plugin.Logger(ctx).Error("aws_iam_account_password_policy.listAccountPasswordPolicies", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	resp, err := svc.GetAccountPasswordPolicy(ctx, &iam.GetAccountPasswordPolicyInput{})
 	if err != nil {
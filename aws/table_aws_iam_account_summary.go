@@ -55,6 +55,7 @@ func tableAwsIamAccountSummary(_ context.Context) *plugin.Table {
 			Hydrate: listAccountSummary,
 			Tags:    map[string]string{"service": "iam", "action": "GetAccountSummary"},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "access_keys_per_user_quota",
@@ -233,6 +234,10 @@ func listAccountSummary(ctx context.Context, d *plugin.QueryData, _ *plugin.Hydr
 		plugin.Logger(ctx).Error("aws_iam_account_summary.listAccountSummary", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	resp, err := svc.GetAccountSummary(ctx, &iam.GetAccountSummaryInput{})
 	if err != nil {
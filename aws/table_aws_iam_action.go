@@ -58,16 +58,59 @@ func tableAwsIamAction(_ context.Context) *plugin.Table {
 				Description: "The description for this action.",
 				Transform:   transform.FromGo(),
 			},
+			{
+				Name:        "service_name",
+				Type:        proto.ColumnType_STRING,
+				Description: "The name of the service of this action.",
+				Transform:   transform.FromGo(),
+			},
+			{
+				Name:        "supports_resource_level_permissions",
+				Type:        proto.ColumnType_BOOL,
+				Description: "True if the Resource element of a policy can restrict this action to specific resources, false if it requires *.",
+				Transform:   transform.FromGo(),
+			},
+			{
+				Name:        "resource_types",
+				Type:        proto.ColumnType_JSON,
+				Description: "The resource types supported by this action, with their ARN patterns, condition keys and dependent actions.",
+				Transform:   transform.FromGo().Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "condition_keys",
+				Type:        proto.ColumnType_JSON,
+				Description: "The condition keys supported by this action, for any resource type.",
+				Transform:   transform.FromGo().Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "dependent_actions",
+				Type:        proto.ColumnType_JSON,
+				Description: "The actions that must also be allowed for this action to succeed, for any resource type.",
+				Transform:   transform.FromGo().Transform(transform.NullIfZeroValue),
+			},
 		},
 	}
 }
 
 type awsIamPermissionData struct {
-	Action      string
-	Prefix      string
-	Privilege   string
-	AccessLevel string
-	Description string
+	Action                           string
+	Prefix                           string
+	Privilege                        string
+	AccessLevel                      string
+	Description                      string
+	ServiceName                      string
+	SupportsResourceLevelPermissions bool
+	ResourceTypes                    []awsIamActionResourceType
+	ConditionKeys                    []string
+	DependentActions                 []string
+}
+
+type awsIamActionResourceType struct {
+	ResourceType     string
+	Arn              string
+	Required         bool
+	ConditionKeys    []string
+	DependentActions []string
 }
 
 //// LIST FUNCTION
@@ -75,13 +118,7 @@ type awsIamPermissionData struct {
 func listIamActions(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
 	for _, service := range permissionsData {
 		for _, privilege := range service.Privileges {
-			d.StreamListItem(ctx, awsIamPermissionData{
-				AccessLevel: privilege.AccessLevel,
-				Action:      strings.ToLower(service.Prefix + ":" + privilege.Privilege),
-				Description: privilege.Description,
-				Prefix:      service.Prefix,
-				Privilege:   privilege.Privilege,
-			})
+			d.StreamListItem(ctx, getIamPermissionData(service, privilege))
 
 			// Context can be cancelled due to manual cancellation or the limit has been hit
 			if d.RowsRemaining(ctx) == 0 {
@@ -101,15 +138,57 @@ func getIamAction(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateDat
 		for _, privilege := range service.Privileges {
 			a := strings.ToLower(service.Prefix + ":" + privilege.Privilege)
 			if a == strings.ToLower(action) {
-				return awsIamPermissionData{
-					AccessLevel: privilege.AccessLevel,
-					Action:      a,
-					Description: privilege.Description,
-					Prefix:      service.Prefix,
-					Privilege:   privilege.Privilege,
-				}, nil
+				return getIamPermissionData(service, privilege), nil
 			}
 		}
 	}
 	return nil, nil
 }
+
+//// UTILITY FUNCTIONS
+
+// Resource types of a privilege are listed by name, with the ARN pattern in
+// the resources of the service. The generated data keeps the * suffix of
+// required resource types from the service authorization reference, e.g.
+// object*, so it is stripped from the name. Condition keys that don't depend on the
+// resource are listed with an empty resource type.
+func getIamPermissionData(service ParliamentService, privilege ParliamentPrivilege) awsIamPermissionData {
+	data := awsIamPermissionData{
+		AccessLevel: privilege.AccessLevel,
+		Action:      strings.ToLower(service.Prefix + ":" + privilege.Privilege),
+		Description: privilege.Description,
+		Prefix:      service.Prefix,
+		Privilege:   privilege.Privilege,
+		ServiceName: service.ServiceName,
+	}
+
+	arns := map[string]string{}
+	for _, resource := range service.Resources {
+		arns[resource.Resource] = resource.Arn
+	}
+
+	for _, resourceType := range privilege.ResourceTypes {
+		data.ConditionKeys = append(data.ConditionKeys, resourceType.ConditionKeys...)
+		data.DependentActions = append(data.DependentActions, resourceType.DependentActions...)
+		if resourceType.ResourceType == "" {
+			continue
+		}
+		name := strings.TrimSuffix(resourceType.ResourceType, "*")
+		data.SupportsResourceLevelPermissions = true
+		data.ResourceTypes = append(data.ResourceTypes, awsIamActionResourceType{
+			ResourceType:     name,
+			Arn:              arns[name],
+			Required:         name != resourceType.ResourceType,
+			ConditionKeys:    resourceType.ConditionKeys,
+			DependentActions: resourceType.DependentActions,
+		})
+	}
+	if len(data.ConditionKeys) > 0 {
+		data.ConditionKeys = uniqueStrings(data.ConditionKeys)
+	}
+	if len(data.DependentActions) > 0 {
+		data.DependentActions = uniqueStrings(data.DependentActions)
+	}
+
+	return data
+}
@@ -0,0 +1,57 @@
+package aws
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetIamPermissionData(t *testing.T) {
+	service := ParliamentService{
+		Prefix:      "s3",
+		ServiceName: "Amazon S3",
+		Resources: []ParliamentResource{
+			{Resource: "bucket", Arn: "arn:${Partition}:s3:::${BucketName}"},
+			{Resource: "object", Arn: "arn:${Partition}:s3:::${BucketName}/${ObjectName}"},
+		},
+	}
+	tests := []struct {
+		privilege ParliamentPrivilege
+		expected  awsIamPermissionData
+	}{
+		{
+			ParliamentPrivilege{Privilege: "PutObject", AccessLevel: "Write", ResourceTypes: []ParliamentResourceType{
+				{ResourceType: "object*"},
+				{ResourceType: "bucket"},
+				{ConditionKeys: []string{"s3:x-amz-acl", "aws:RequestTag/${TagKey}"}},
+				{ConditionKeys: []string{"s3:x-amz-acl"}, DependentActions: []string{"s3:PutObjectAcl"}},
+			}},
+			awsIamPermissionData{
+				Action:                           "s3:putobject",
+				Prefix:                           "s3",
+				Privilege:                        "PutObject",
+				AccessLevel:                      "Write",
+				ServiceName:                      "Amazon S3",
+				SupportsResourceLevelPermissions: true,
+				ResourceTypes:                    []awsIamActionResourceType{{ResourceType: "object", Arn: "arn:${Partition}:s3:::${BucketName}/${ObjectName}", Required: true}, {ResourceType: "bucket", Arn: "arn:${Partition}:s3:::${BucketName}"}},
+				ConditionKeys:                    []string{"s3:x-amz-acl", "aws:RequestTag/${TagKey}"},
+				DependentActions:                 []string{"s3:PutObjectAcl"},
+			},
+		},
+		{
+			ParliamentPrivilege{Privilege: "ListAllMyBuckets", AccessLevel: "List", ResourceTypes: []ParliamentResourceType{{}}},
+			awsIamPermissionData{
+				Action:      "s3:listallmybuckets",
+				Prefix:      "s3",
+				Privilege:   "ListAllMyBuckets",
+				AccessLevel: "List",
+				ServiceName: "Amazon S3",
+			},
+		},
+	}
+	for _, test := range tests {
+		actual := getIamPermissionData(service, test.privilege)
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.privilege.Privilege, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+//// TABLE DEFINITION
+
+func tableAwsIamConditionKey(_ context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "aws_iam_condition_key",
+		Description: "AWS IAM Condition Key",
+		List: &plugin.ListConfig{
+			Hydrate: listIamConditionKeys,
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "condition_key",
+				Type:        proto.ColumnType_STRING,
+				Description: "The condition key, e.g. s3:prefix.",
+				Transform:   transform.FromField("Condition"),
+			},
+			{
+				Name:        "prefix",
+				Type:        proto.ColumnType_STRING,
+				Description: "The prefix of the service that supports this condition key.",
+				Transform:   transform.FromGo(),
+			},
+			{
+				Name:        "service_name",
+				Type:        proto.ColumnType_STRING,
+				Description: "The name of the service that supports this condition key.",
+				Transform:   transform.FromGo(),
+			},
+			{
+				Name:        "type",
+				Type:        proto.ColumnType_STRING,
+				Description: "The type of the value of this condition key, e.g. String, ArrayOfString, Bool, Date or Numeric.",
+				Transform:   transform.FromGo(),
+			},
+			{
+				Name:        "description",
+				Type:        proto.ColumnType_STRING,
+				Description: "The description of this condition key.",
+				Transform:   transform.FromGo(),
+			},
+		},
+	}
+}
+
+type awsIamConditionKeyData struct {
+	Condition   string
+	Prefix      string
+	ServiceName string
+	Type        string
+	Description string
+}
+
+//// LIST FUNCTION
+
+func listIamConditionKeys(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	for _, service := range permissionsData {
+		for _, condition := range service.Conditions {
+			d.StreamListItem(ctx, awsIamConditionKeyData{
+				Condition:   condition.Condition,
+				Prefix:      service.Prefix,
+				ServiceName: service.ServiceName,
+				Type:        condition.Type,
+				Description: condition.Description,
+			})
+
+			// Context can be cancelled due to manual cancellation or the limit has been hit
+			if d.RowsRemaining(ctx) == 0 {
+				return nil, nil
+			}
+		}
+	}
+	return nil, nil
+}
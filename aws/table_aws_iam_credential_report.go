@@ -48,6 +48,7 @@ func tableAwsIamCredentialReport(_ context.Context) *plugin.Table {
 			Hydrate: listCredentialReports,
 			Tags:    map[string]string{"service": "iam", "action": "GetCredentialReport"},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "user_name",
@@ -206,6 +207,10 @@ func listCredentialReports(ctx context.Context, d *plugin.QueryData, _ *plugin.H
 		plugin.Logger(ctx).Error("aws_iam_credential_report.listCredentialReports", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	resp, err := svc.GetCredentialReport(ctx, &iam.GetCredentialReportInput{})
 	if err != nil {
@@ -51,6 +51,7 @@ func tableAwsIamGroup(_ context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "iam", "action": "ListGroupPolicies"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "name",
@@ -131,6 +132,10 @@ func listIamGroups(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateDa
 		plugin.Logger(ctx).Error("aws_iam_group.listIamGroups", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	input := &iam.ListGroupsInput{}
 
@@ -196,6 +201,10 @@ func getIamGroup(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData
 		plugin.Logger(ctx).Error("aws_iam_group.getIamGroup", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &iam.GetGroupInput{
 		GroupName: aws.String(groupName),
@@ -219,6 +228,10 @@ func getAwsIamGroupAttachedPolicies(ctx context.Context, d *plugin.QueryData, h
 		plugin.Logger(ctx).Error("aws_iam_group.listIamGroups", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &iam.ListAttachedGroupPoliciesInput{
 		GroupName: group.GroupName,
@@ -250,6 +263,10 @@ func getAwsIamGroupUsers(ctx context.Context, d *plugin.QueryData, h *plugin.Hyd
 		plugin.Logger(ctx).Error("aws_iam_group.getAwsIamGroupUsers", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &iam.GetGroupInput{GroupName: group.GroupName}
 
@@ -274,6 +291,10 @@ func listAwsIamGroupInlinePolicies(ctx context.Context, d *plugin.QueryData, h *
 		plugin.Logger(ctx).Error("aws_iam_group.listAwsIamGroupInlinePolicies", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &iam.ListGroupPoliciesInput{GroupName: group.GroupName}
 	groupData, err := svc.ListGroupPolicies(ctx, params)
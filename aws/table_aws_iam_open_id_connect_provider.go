@@ -36,6 +36,7 @@ func tableAwsIamOpenIdConnectProvider(ctx context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "iam", "action": "ListOpenIDConnectProviders"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "arn",
@@ -107,6 +108,10 @@ func listIamOpenIdConnectProviders(ctx context.Context, d *plugin.QueryData, _ *
 		plugin.Logger(ctx).Error("aws_iam_open_id_connect_provider.listIamOpenIdConnectProviders", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	// SDK doesn't have new paginator for ListOpenIDConnectProviders action
 	output, err := svc.ListOpenIDConnectProviders(ctx, &iam.ListOpenIDConnectProvidersInput{}, func(o *iam.Options) {
@@ -148,6 +153,10 @@ func getIamOpenIdConnectProvider(ctx context.Context, d *plugin.QueryData, h *pl
 		plugin.Logger(ctx).Error("aws_iam_open_id_connect_provider.getIamOpenIdConnectProvider", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &iam.GetOpenIDConnectProviderInput{
 		OpenIDConnectProviderArn: aws.String(arn),
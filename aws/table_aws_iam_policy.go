@@ -43,6 +43,7 @@ func tableAwsIamPolicy(_ context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "iam", "action": "GetPolicy"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "name",
@@ -168,6 +169,10 @@ func listIamPolicies(ctx context.Context, d *plugin.QueryData, _ *plugin.Hydrate
 		plugin.Logger(ctx).Error("aws_iam_policy.listIamPolicies", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := buildIamPolicyFilter(d.EqualsQuals, d.Quals)
 	maxItems := int32(1000)
@@ -227,6 +232,10 @@ func getIamPolicy(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateDat
 		plugin.Logger(ctx).Error("aws_iam_policy.getIamPolicy", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &iam.GetPolicyInput{
 		PolicyArn: &arn,
@@ -250,6 +259,10 @@ func getPolicyVersion(ctx context.Context, d *plugin.QueryData, h *plugin.Hydrat
 		plugin.Logger(ctx).Error("aws_iam_policy.getPolicyVersion", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &iam.GetPolicyVersionInput{
 		PolicyArn: policy.Arn,
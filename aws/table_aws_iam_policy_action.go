@@ -0,0 +1,240 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+//// TABLE DEFINITION
+
+func tableAwsIamPolicyAction(_ context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "aws_iam_policy_action",
+		Description: "AWS IAM Policy Action",
+		List: &plugin.ListConfig{
+			Hydrate: listIamPolicyActions,
+			KeyColumns: []*plugin.KeyColumn{
+				{Name: "policy", Require: plugin.Required},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "action",
+				Description: "The action granted or denied by the statement, e.g. s3:getobject.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "prefix",
+				Description: "The service prefix of the action.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "privilege",
+				Description: "The privilege of the action.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "access_level",
+				Description: "The access level of the action: List, Read, Write, Permissions management or Tagging. Null if the action is not in the IAM action catalog.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("AccessLevel").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "effect",
+				Description: "The effect of the statement: Allow or Deny.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "sid",
+				Description: "The Sid of the statement.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("Sid").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "statement_index",
+				Description: "The index of the statement in the policy, starting at 0.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "action_pattern",
+				Description: "The pattern of the Action element that matches the action, e.g. s3:get*. Null if the statement uses NotAction.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("ActionPattern").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "is_not_action",
+				Description: "True if the statement uses NotAction, i.e. it applies to every action not in the NotAction element.",
+				Type:        proto.ColumnType_BOOL,
+			},
+			{
+				Name:        "resources",
+				Description: "The Resource element of the statement.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Resources").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "not_resources",
+				Description: "The NotResource element of the statement.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("NotResources").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "condition",
+				Description: "The Condition element of the statement, in canonical form.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Condition").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "policy",
+				Description: "The policy document, as an object or a (URL encoded) string.",
+				Type:        proto.ColumnType_JSON,
+			},
+		},
+	}
+}
+
+type iamPolicyActionRow struct {
+	Action         string
+	Prefix         string
+	Privilege      string
+	AccessLevel    string
+	Effect         string
+	Sid            string
+	StatementIndex int
+	ActionPattern  string
+	IsNotAction    bool
+	Resources      []string
+	NotResources   []string
+	Condition      map[string]interface{}
+	Policy         interface{}
+}
+
+//// LIST FUNCTION
+
+func listIamPolicyActions(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	policy, raw, err := getIamPolicyDocumentQual(d, "policy")
+	if err != nil {
+		plugin.Logger(ctx).Error("aws_iam_policy_action.listIamPolicyActions", "policy_error", err)
+		return nil, err
+	}
+	if policy == nil {
+		return nil, nil
+	}
+
+	for _, row := range expandIamPolicyActions(*policy, permissionsData) {
+		row.Policy = raw
+		d.StreamListItem(ctx, row)
+
+		// Context can be cancelled due to manual cancellation or the limit has been hit
+		if d.RowsRemaining(ctx) == 0 {
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
+
+//// UTILITY FUNCTIONS
+
+// Get a policy document qual, in canonical form. The policy column of
+// aws_iam_policy is an object, while policy documents returned by other APIs
+// are often (URL encoded) strings.
+func getIamPolicyDocumentQual(d *plugin.QueryData, column string) (*Policy, interface{}, error) {
+	value, raw, err := getIamPolicyEvaluationJsonQual(d, column)
+	if err != nil || raw == nil {
+		return nil, raw, err
+	}
+
+	var policy *Policy
+	if document, ok := raw.(string); ok {
+		policy, err = decodeIamPolicyDocument(document)
+	} else {
+		var p interface{}
+		p, err = canonicalPolicy(value)
+		if err == nil {
+			canonical := p.(Policy)
+			policy = &canonical
+		}
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %v", column, err)
+	}
+	return policy, raw, nil
+}
+
+// Expand the Action and NotAction elements of each statement of a policy to
+// the actions of the catalog they apply to, one row per statement and action.
+// Actions without wildcards that are not in the catalog, e.g. new actions,
+// are kept with no access level.
+func expandIamPolicyActions(policy Policy, catalog ParliamentPermissions) []iamPolicyActionRow {
+	var rows []iamPolicyActionRow
+	for i, s := range policy.Statements {
+		statementRow := iamPolicyActionRow{
+			Effect:         s.Effect,
+			Sid:            s.Sid,
+			StatementIndex: i,
+			IsNotAction:    len(s.Action) == 0 && len(s.NotAction) > 0,
+			Resources:      s.Resource,
+			NotResources:   s.NotResource,
+		}
+		if len(s.Condition) > 0 {
+			statementRow.Condition = s.Condition
+		}
+
+		found := map[string]bool{}
+		for _, service := range catalog {
+			for _, privilege := range service.Privileges {
+				action := strings.ToLower(service.Prefix + ":" + privilege.Privilege)
+				pattern, ok := getIamStatementActionPattern(s, action)
+				if !ok || found[action] {
+					continue
+				}
+				found[action] = true
+
+				row := statementRow
+				row.Action = action
+				row.Prefix = service.Prefix
+				row.Privilege = privilege.Privilege
+				row.AccessLevel = privilege.AccessLevel
+				row.ActionPattern = pattern
+				rows = append(rows, row)
+			}
+		}
+
+		for _, action := range s.Action {
+			if found[action] || strings.ContainsAny(action, "*?") {
+				continue
+			}
+			found[action] = true
+
+			row := statementRow
+			row.Action = action
+			row.Prefix, row.Privilege, _ = strings.Cut(action, ":")
+			row.ActionPattern = action
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// Get the pattern of the Action element of a statement that matches an
+// action. NotAction statements match any action not in the element, with no
+// pattern.
+func getIamStatementActionPattern(s Statement, action string) (string, bool) {
+	if len(s.Action) > 0 {
+		for _, pattern := range s.Action {
+			if matchIamWildcard(pattern, action) {
+				return pattern, true
+			}
+		}
+		return "", false
+	}
+	if len(s.NotAction) > 0 {
+		return "", !matchIamActions(s.NotAction, action)
+	}
+	return "", false
+}
@@ -0,0 +1,88 @@
+package aws
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+var testIamActionCatalog = ParliamentPermissions{
+	{
+		Prefix: "s3",
+		Privileges: []ParliamentPrivilege{
+			{Privilege: "GetObject", AccessLevel: "Read"},
+			{Privilege: "PutObject", AccessLevel: "Write"},
+			{Privilege: "ListBucket", AccessLevel: "List"},
+		},
+	},
+	{
+		Prefix: "ec2",
+		Privileges: []ParliamentPrivilege{
+			{Privilege: "DescribeInstances", AccessLevel: "List"},
+		},
+	},
+}
+
+func TestExpandIamPolicyActions(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		expected []string
+	}{
+		{"wildcard", `{"Statement": {"Effect": "Allow", "Action": "s3:Get*", "Resource": "*"}}`, []string{"Allow 0 s3:getobject Read s3:get*"}},
+		{"all actions", `{"Statement": {"Effect": "Deny", "Action": "*", "Resource": "*"}}`, []string{"Deny 0 s3:getobject Read *", "Deny 0 s3:putobject Write *", "Deny 0 s3:listbucket List *", "Deny 0 ec2:describeinstances List *"}},
+		{"not action", `{"Statement": {"Effect": "Allow", "NotAction": ["s3:*Object"], "Resource": "*"}}`, []string{"Allow 0 s3:listbucket List ", "Allow 0 ec2:describeinstances List "}},
+		{"overlapping patterns", `{"Statement": {"Effect": "Allow", "Action": ["s3:GetObject", "s3:*"], "Resource": "*"}}`, []string{"Allow 0 s3:getobject Read s3:*", "Allow 0 s3:putobject Write s3:*", "Allow 0 s3:listbucket List s3:*"}},
+		{"unknown action", `{"Statement": {"Effect": "Allow", "Action": ["s3:NewAction", "sqs:*"], "Resource": "*"}}`, []string{"Allow 0 s3:newaction  s3:newaction"}},
+		{"statements", `{"Statement": [{"Effect": "Allow", "Action": "ec2:Describe*"}, {"Effect": "Deny", "Action": "s3:PutObject"}]}`, []string{"Allow 0 ec2:describeinstances List ec2:describe*", "Deny 1 s3:putobject Write s3:putobject"}},
+	}
+	for _, test := range tests {
+		policy, err := canonicalPolicy(test.policy)
+		if err != nil {
+			t.Fatalf("%s: invalid policy: %v", test.name, err)
+		}
+		var actual []string
+		for _, row := range expandIamPolicyActions(policy.(Policy), testIamActionCatalog) {
+			actual = append(actual, fmt.Sprintf("%s %d %s %s %s", row.Effect, row.StatementIndex, row.Action, row.AccessLevel, row.ActionPattern))
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, actual)
+		}
+	}
+}
+
+func TestAwsIamPolicyAction(t *testing.T) {
+	api := newStubAPI(t)
+
+	// Policy documents returned by the IAM API are URL encoded strings
+	for _, policy := range []string{
+		`{"Version": "2012-10-17", "Statement": [{"Sid": "Read", "Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::data/*"}, {"Effect": "Deny", "Action": "s3:DeleteBucket", "Resource": "*", "Condition": {"Bool": {"aws:MultiFactorAuthPresent": "false"}}}]}`,
+		`"%7B%22Version%22%3A%222012-10-17%22%2C%22Statement%22%3A%5B%7B%22Sid%22%3A%22Read%22%2C%22Effect%22%3A%22Allow%22%2C%22Action%22%3A%22s3%3AGetObject%22%2C%22Resource%22%3A%22arn%3Aaws%3As3%3A%3A%3Adata%2F%2A%22%7D%2C%7B%22Effect%22%3A%22Deny%22%2C%22Action%22%3A%22s3%3ADeleteBucket%22%2C%22Resource%22%3A%22%2A%22%2C%22Condition%22%3A%7B%22Bool%22%3A%7B%22aws%3AMultiFactorAuthPresent%22%3A%22false%22%7D%7D%7D%5D%7D"`,
+	} {
+		rows, err := runTableQuery(t, api, tableQuery{
+			Table:   "aws_iam_policy_action",
+			Columns: []string{"action", "prefix", "effect", "sid", "statement_index", "resources", "condition", "is_not_action"},
+			Quals:   map[string]string{"policy": policy},
+		})
+		if err != nil {
+			t.Fatalf("query failed: %v", err)
+		}
+		if len(rows) != 2 {
+			t.Fatalf("expected 2 rows, got %d: %v", len(rows), rows)
+		}
+		expected := map[string]map[string]interface{}{
+			"s3:getobject":    {"action": "s3:getobject", "prefix": "s3", "effect": "Allow", "sid": "Read", "statement_index": int64(0), "resources": []interface{}{"arn:aws:s3:::data/*"}, "condition": nil, "is_not_action": false},
+			"s3:deletebucket": {"action": "s3:deletebucket", "prefix": "s3", "effect": "Deny", "sid": nil, "statement_index": int64(1), "resources": []interface{}{"*"}, "condition": map[string]interface{}{"Bool": map[string]interface{}{"aws:multifactorauthpresent": []interface{}{"false"}}}, "is_not_action": false},
+		}
+		for _, row := range rows {
+			for column, value := range expected[row["action"].(string)] {
+				if !reflect.DeepEqual(row[column], value) {
+					t.Errorf("%s: expected %s %v, got %v", row["action"], column, value, row[column])
+				}
+			}
+		}
+	}
+	if len(api.requests) != 0 {
+		t.Errorf("expected no API calls, got %d", len(api.requests))
+	}
+}
@@ -26,6 +26,7 @@ func tableAwsIamPolicyAttachment(_ context.Context) *plugin.Table {
 				{Name: "is_attached", Require: plugin.Optional, Operators: []string{"<>", "="}},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "policy_arn",
@@ -74,6 +75,10 @@ func listIamPolicyAttachments(ctx context.Context, d *plugin.QueryData, h *plugi
 		plugin.Logger(ctx).Error("aws_iam_policy_attachment.listIamPolicyAttachments", "api_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 	policy := h.Item.(types.Policy)
 	maxItems := int32(1000)
 
@@ -0,0 +1,344 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+//// TABLE DEFINITION
+
+func tableAwsIamPolicyEvaluation(_ context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "aws_iam_policy_evaluation",
+		Description: "AWS IAM Policy Evaluation",
+		List: &plugin.ListConfig{
+			Hydrate: listIamPolicyEvaluations,
+			Tags:    map[string]string{"service": "iam"},
+			KeyColumns: []*plugin.KeyColumn{
+				{Name: "principal_arn", Require: plugin.Required},
+				{Name: "action", Require: plugin.Required},
+				{Name: "resource_arn", Require: plugin.Optional},
+				{Name: "context", Require: plugin.Optional},
+				{Name: "resource_policy", Require: plugin.Optional},
+				{Name: "session_policy", Require: plugin.Optional},
+				{Name: "service_control_policies", Require: plugin.Optional},
+			},
+		},
+		Columns: awsGlobalRegionColumns([]*plugin.Column{
+			{
+				Name:        "principal_arn",
+				Description: "The ARN of the IAM user, IAM role or role session making the request.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "action",
+				Description: "The action of the request, e.g. s3:GetObject.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "resource_arn",
+				Description: "The ARN of the resource of the request. Defaults to *.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "decision",
+				Description: "The decision for the request: allowed, explicitDeny or implicitDeny.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "decision_details",
+				Description: "The decision of each type of policy included in the evaluation.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "matched_statements",
+				Description: "The policy statements that apply to the request.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("MatchedStatements").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "missing_context_values",
+				Description: "The condition keys used by the policies that are not in the request context.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("MissingContextValues").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "unevaluated_policy_types",
+				Description: "The types of policy that could not be read with the credentials of the connection, e.g. service_control.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("UnevaluatedPolicyTypes").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "principal_type",
+				Description: "The type of the principal: user, role or assumed-role.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "context",
+				Description: "The request context, as an object of condition keys and values, e.g. {\"aws:SourceIp\": \"203.0.113.10\"}. The principal and current time keys are added unless set.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "resource_policy",
+				Description: "The resource-based policy of the resource, to include in the evaluation.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "session_policy",
+				Description: "The session policy of the request, to include in the evaluation.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "service_control_policies",
+				Description: "The SCPs of each level of the organization, from the root to the account, as an array of arrays of policies. Set to [] if SCPs don't apply. Defaults to the SCPs from AWS Organizations, when the connection can read them.",
+				Type:        proto.ColumnType_JSON,
+			},
+		}),
+	}
+}
+
+type iamPolicyEvaluationRow struct {
+	PrincipalArn           string
+	PrincipalType          string
+	Action                 string
+	ResourceArn            string
+	Decision               string
+	DecisionDetails        map[string]string
+	MatchedStatements      []iamEvaluationStatement
+	MissingContextValues   []string
+	UnevaluatedPolicyTypes []string
+	Context                interface{}
+	ResourcePolicy         interface{}
+	SessionPolicy          interface{}
+	ServiceControlPolicies interface{}
+}
+
+//// LIST FUNCTION
+
+func listIamPolicyEvaluations(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	logger := plugin.Logger(ctx)
+
+	principalArn := d.EqualsQualString("principal_arn")
+	action := d.EqualsQualString("action")
+	if !strings.Contains(action, ":") {
+		return nil, fmt.Errorf("invalid action %q, it must be in service:Action format, e.g. s3:GetObject", action)
+	}
+	resourceArn := d.EqualsQualString("resource_arn")
+	if resourceArn == "" {
+		resourceArn = "*"
+	}
+
+	commonData, err := getCommonColumns(ctx, d, h)
+	if err != nil {
+		return nil, err
+	}
+	commonColumnData := commonData.(*awsCommonColumnData)
+
+	a, err := arn.Parse(principalArn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid principal_arn %q: %v", principalArn, err)
+	}
+	if a.AccountID != commonColumnData.AccountId {
+		return nil, fmt.Errorf("principal_arn %s is not in the account of the connection (%s)", principalArn, commonColumnData.AccountId)
+	}
+
+	row := &iamPolicyEvaluationRow{
+		PrincipalArn: principalArn,
+		Action:       action,
+		ResourceArn:  resourceArn,
+	}
+
+	// Policies and request context from the quals
+	requestContext, raw, err := getIamPolicyEvaluationContextQual(d)
+	if err != nil {
+		return nil, err
+	}
+	row.Context = raw
+	resourcePolicies, raw, err := getIamPolicyEvaluationPolicyQual(d, "resource_policy", iamPolicyTypeResource)
+	if err != nil {
+		return nil, err
+	}
+	row.ResourcePolicy = raw
+	sessionPolicies, raw, err := getIamPolicyEvaluationPolicyQual(d, "session_policy", iamPolicyTypeSession)
+	if err != nil {
+		return nil, err
+	}
+	row.SessionPolicy = raw
+
+	// Policies of the principal
+	loader, err := newIamPolicyLoader(ctx, d)
+	if err != nil {
+		logger.Error("aws_iam_policy_evaluation.listIamPolicyEvaluations", "client_error", err)
+		return nil, err
+	}
+	if loader == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
+	principal, err := loader.getPrincipalPolicies(ctx, principalArn)
+	if err != nil {
+		logger.Error("aws_iam_policy_evaluation.listIamPolicyEvaluations", "api_error", err)
+		return nil, err
+	}
+	row.PrincipalType = principal.PrincipalType
+
+	serviceControl, raw, err := getIamPolicyEvaluationServiceControlQual(d)
+	if err != nil {
+		return nil, err
+	}
+	row.ServiceControlPolicies = raw
+	if raw == nil {
+		var evaluated bool
+		serviceControl, evaluated, err = getIamServiceControlPolicies(ctx, d, principal.AccountId)
+		if err != nil {
+			return nil, err
+		}
+		if !evaluated {
+			row.UnevaluatedPolicyTypes = append(row.UnevaluatedPolicyTypes, iamPolicyTypeServiceControl)
+		}
+	}
+
+	addIamPrincipalContextKeys(requestContext, principalArn, principal)
+	resourceAccount := ""
+	if values := requestContext["aws:resourceaccount"]; len(values) > 0 {
+		resourceAccount = values[0]
+	} else if r, err := arn.Parse(resourceArn); err == nil {
+		resourceAccount = r.AccountID
+	}
+
+	result := evaluateIamPolicies(iamEvaluationInput{
+		PrincipalArns:       principal.PrincipalArns,
+		PrincipalAccount:    principal.AccountId,
+		Action:              action,
+		Resource:            resourceArn,
+		ResourceAccount:     resourceAccount,
+		Context:             requestContext,
+		Identity:            principal.Identity,
+		PermissionsBoundary: principal.PermissionsBoundary,
+		Session:             sessionPolicies,
+		ResourcePolicies:    resourcePolicies,
+		ServiceControl:      serviceControl,
+	})
+	row.Decision = result.Decision
+	row.DecisionDetails = result.DecisionDetails
+	row.MatchedStatements = result.MatchedStatements
+	row.MissingContextValues = result.MissingContextValues
+
+	d.StreamListItem(ctx, row)
+
+	return nil, nil
+}
+
+//// UTILITY FUNCTIONS
+
+// Get the value of a JSON qual, decoded.
+func getIamPolicyEvaluationJsonQual(d *plugin.QueryData, column string) (string, interface{}, error) {
+	q := d.EqualsQuals[column]
+	if q == nil {
+		return "", nil, nil
+	}
+	value := q.GetJsonbValue()
+	var raw interface{}
+	if err := json.Unmarshal([]byte(value), &raw); err != nil {
+		return "", nil, fmt.Errorf("invalid %s: %v", column, err)
+	}
+	return value, raw, nil
+}
+
+// The request context is an object of condition keys, with a string, number,
+// boolean or array value each.
+func getIamPolicyEvaluationContextQual(d *plugin.QueryData) (map[string][]string, interface{}, error) {
+	requestContext := map[string][]string{}
+	_, raw, err := getIamPolicyEvaluationJsonQual(d, "context")
+	if err != nil || raw == nil {
+		return requestContext, raw, err
+	}
+	values, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid context, it must be an object of condition keys and values")
+	}
+	for key, value := range values {
+		if value == nil {
+			continue
+		}
+		s, err := toSliceOfStrings(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid value of %s in context: %v", key, err)
+		}
+		requestContext[strings.ToLower(key)] = s
+	}
+	return requestContext, raw, nil
+}
+
+func getIamPolicyEvaluationPolicyQual(d *plugin.QueryData, column string, policyType string) ([]iamEvaluationPolicy, interface{}, error) {
+	value, raw, err := getIamPolicyEvaluationJsonQual(d, column)
+	if err != nil || raw == nil {
+		return nil, raw, err
+	}
+	policy, err := canonicalPolicy(value)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s: %v", column, err)
+	}
+	return []iamEvaluationPolicy{{Type: policyType, Id: column, Policy: policy.(Policy)}}, raw, nil
+}
+
+func getIamPolicyEvaluationServiceControlQual(d *plugin.QueryData) ([][]iamEvaluationPolicy, interface{}, error) {
+	value, raw, err := getIamPolicyEvaluationJsonQual(d, "service_control_policies")
+	if err != nil || raw == nil {
+		return nil, raw, err
+	}
+	var levels []json.RawMessage
+	if err := json.Unmarshal([]byte(value), &levels); err != nil {
+		return nil, nil, fmt.Errorf("invalid service_control_policies, it must be an array of arrays of policies: %v", err)
+	}
+	var serviceControl [][]iamEvaluationPolicy
+	for i, level := range levels {
+		var documents []json.RawMessage
+		if err := json.Unmarshal(level, &documents); err != nil {
+			return nil, nil, fmt.Errorf("invalid service_control_policies, it must be an array of arrays of policies: %v", err)
+		}
+		var policies []iamEvaluationPolicy
+		for j, document := range documents {
+			policy, err := canonicalPolicy(string(document))
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid service_control_policies[%d][%d]: %v", i, j, err)
+			}
+			policies = append(policies, iamEvaluationPolicy{Type: iamPolicyTypeServiceControl, Id: fmt.Sprintf("service_control_policies[%d][%d]", i, j), Policy: policy.(Policy)})
+		}
+		serviceControl = append(serviceControl, policies)
+	}
+	return serviceControl, raw, nil
+}
+
+// Add the global condition keys of the principal and the request time,
+// unless set in the request context.
+func addIamPrincipalContextKeys(requestContext map[string][]string, principalArn string, principal *iamPrincipalPolicies) {
+	now := time.Now().UTC()
+	keys := map[string]string{
+		"aws:principalarn":     principalArn,
+		"aws:principalaccount": principal.AccountId,
+		"aws:currenttime":      now.Format(time.RFC3339),
+		"aws:epochtime":        fmt.Sprintf("%d", now.Unix()),
+	}
+	switch principal.PrincipalType {
+	case "user":
+		keys["aws:principaltype"] = "User"
+		keys["aws:username"] = principal.Name
+	case "role", "assumed-role":
+		keys["aws:principaltype"] = "AssumedRole"
+	}
+	for key, value := range keys {
+		if _, ok := requestContext[key]; !ok {
+			requestContext[key] = []string{value}
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package aws
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Stand-in for a role with an inline policy, a managed policy and a
+// permissions boundary.
+func newIamPolicyEvaluationStubAPI(t *testing.T) *stubAPI {
+	api := newStubAPI(t)
+	roleArn := "arn:aws:iam::" + stubAccountId + ":role/app"
+	managedArn := "arn:aws:iam::" + stubAccountId + ":policy/managed"
+	boundaryArn := "arn:aws:iam::" + stubAccountId + ":policy/boundary"
+	documents := map[string]string{
+		managedArn:  `{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": "ec2:Describe*", "Resource": "*"}, {"Effect": "Deny", "Action": "s3:DeleteObject", "Resource": "*"}]}`,
+		boundaryArn: `{"Version": "2012-10-17", "Statement": {"Effect": "Allow", "Action": ["s3:*", "ec2:DescribeInstances"], "Resource": "*"}}`,
+	}
+	inline := `{"Version": "2012-10-17", "Statement": {"Sid": "Data", "Effect": "Allow", "Action": "s3:*", "Resource": "arn:aws:s3:::data/*", "Condition": {"IpAddress": {"aws:SourceIp": "10.0.0.0/8"}}}}`
+
+	api.on("iam", "GetRole", queryResponse("GetRole", `<Role><Path>/</Path><RoleName>app</RoleName><RoleId>AROASTUB</RoleId><Arn>`+roleArn+`</Arn><CreateDate>2024-01-01T00:00:00Z</CreateDate><PermissionsBoundary><PermissionsBoundaryType>Policy</PermissionsBoundaryType><PermissionsBoundaryArn>`+boundaryArn+`</PermissionsBoundaryArn></PermissionsBoundary></Role>`))
+	api.on("iam", "ListRolePolicies", queryResponse("ListRolePolicies", `<PolicyNames><member>data</member></PolicyNames><IsTruncated>false</IsTruncated>`))
+	api.on("iam", "GetRolePolicy", queryResponse("GetRolePolicy", `<RoleName>app</RoleName><PolicyName>data</PolicyName><PolicyDocument>`+url.QueryEscape(inline)+`</PolicyDocument>`))
+	api.on("iam", "ListAttachedRolePolicies", queryResponse("ListAttachedRolePolicies", `<AttachedPolicies><member><PolicyName>managed</PolicyName><PolicyArn>`+managedArn+`</PolicyArn></member></AttachedPolicies><IsTruncated>false</IsTruncated>`))
+	api.onFunc("iam", "GetPolicy", func(r stubRequest) stubResponse {
+		return queryResponse("GetPolicy", `<Policy><Arn>`+r.Params.Get("PolicyArn")+`</Arn><DefaultVersionId>v1</DefaultVersionId></Policy>`)
+	})
+	api.onFunc("iam", "GetPolicyVersion", func(r stubRequest) stubResponse {
+		return queryResponse("GetPolicyVersion", `<PolicyVersion><VersionId>v1</VersionId><IsDefaultVersion>true</IsDefaultVersion><Document>`+url.QueryEscape(documents[r.Params.Get("PolicyArn")])+`</Document></PolicyVersion>`)
+	})
+	return api
+}
+
+func TestAwsIamPolicyEvaluation(t *testing.T) {
+	api := newIamPolicyEvaluationStubAPI(t)
+	principalArn := "arn:aws:sts::" + stubAccountId + ":assumed-role/app/session"
+
+	tests := []struct {
+		action    string
+		resource  string
+		context   string
+		decision  string
+		boundary  string
+		statement string
+	}{
+		{"s3:GetObject", "arn:aws:s3:::data/report.csv", `{"aws:SourceIp": "10.1.2.3"}`, iamDecisionAllowed, iamDecisionAllowed, "data"},
+		{"s3:GetObject", "arn:aws:s3:::data/report.csv", `{"aws:SourceIp": "192.0.2.1"}`, iamDecisionImplicitDeny, iamDecisionAllowed, ""},
+		{"ec2:DescribeRegions", "*", `{}`, iamDecisionImplicitDeny, iamDecisionImplicitDeny, "arn:aws:iam::" + stubAccountId + ":policy/managed"},
+		{"s3:DeleteObject", "arn:aws:s3:::data/report.csv", `{"aws:SourceIp": "10.1.2.3"}`, iamDecisionExplicitDeny, iamDecisionAllowed, "data"},
+	}
+	for _, test := range tests {
+		rows, err := runTableQuery(t, api, tableQuery{
+			Table:   "aws_iam_policy_evaluation",
+			Columns: []string{"principal_arn", "principal_type", "action", "resource_arn", "decision", "decision_details", "matched_statements", "unevaluated_policy_types"},
+			Quals: map[string]string{
+				"principal_arn":            principalArn,
+				"action":                   test.action,
+				"resource_arn":             test.resource,
+				"context":                  test.context,
+				"service_control_policies": `[]`,
+			},
+		})
+		if err != nil {
+			t.Fatalf("%s: query failed: %v", test.action, err)
+		}
+		if len(rows) != 1 {
+			t.Fatalf("%s: expected 1 row, got %d", test.action, len(rows))
+		}
+		row := rows[0]
+		if row["decision"] != test.decision {
+			t.Errorf("%s %s: expected %s, got %v (%v)", test.action, test.context, test.decision, row["decision"], row["decision_details"])
+		}
+		if got := row["decision_details"].(map[string]interface{})["permissions_boundary"]; got != test.boundary {
+			t.Errorf("%s: expected the boundary decision %s, got %v", test.action, test.boundary, got)
+		}
+		if row["principal_type"] != "assumed-role" || row["unevaluated_policy_types"] != nil {
+			t.Errorf("%s: unexpected row %v", test.action, row)
+		}
+		if test.statement != "" {
+			found := false
+			statements, _ := row["matched_statements"].([]interface{})
+			for _, s := range statements {
+				if s.(map[string]interface{})["policy_id"] == test.statement {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("%s: expected a matched statement of %s, got %v", test.action, test.statement, statements)
+			}
+		}
+	}
+}
+
+func TestAwsIamPolicyEvaluationServiceControlPolicies(t *testing.T) {
+	// Member accounts can't read SCPs
+	api := newIamPolicyEvaluationStubAPI(t)
+	api.on("organizations", "DescribeOrganization", jsonErrorResponse(http.StatusBadRequest, "AccessDeniedException", "not the management account"))
+
+	rows, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_iam_policy_evaluation",
+		Columns: []string{"decision", "missing_context_values", "unevaluated_policy_types"},
+		Quals: map[string]string{
+			"principal_arn": "arn:aws:iam::" + stubAccountId + ":role/app",
+			"action":        "s3:GetObject",
+			"resource_arn":  "arn:aws:s3:::data/report.csv",
+		},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(rows))
+	}
+	expected := map[string]interface{}{
+		"decision":                 iamDecisionImplicitDeny,
+		"missing_context_values":   []interface{}{"aws:sourceip"},
+		"unevaluated_policy_types": []interface{}{iamPolicyTypeServiceControl},
+	}
+	for column, value := range expected {
+		if !reflect.DeepEqual(rows[0][column], value) {
+			t.Errorf("expected %s %v, got %v", column, value, rows[0][column])
+		}
+	}
+}
+
+func TestAwsIamPolicyEvaluationOrganizationServiceControlPolicies(t *testing.T) {
+	api := newIamPolicyEvaluationStubAPI(t)
+	api.on("organizations", "DescribeOrganization", jsonResponse(map[string]interface{}{
+		"Organization": map[string]interface{}{"Id": "o-stub", "MasterAccountId": "999999999999", "FeatureSet": "ALL"},
+	}))
+	api.onFunc("organizations", "ListParents", func(r stubRequest) stubResponse {
+		if strings.Contains(string(r.Body), stubAccountId) {
+			return jsonResponse(map[string]interface{}{"Parents": []interface{}{map[string]interface{}{"Id": "ou-stub", "Type": "ORGANIZATIONAL_UNIT"}}})
+		}
+		return jsonResponse(map[string]interface{}{"Parents": []interface{}{map[string]interface{}{"Id": "r-stub", "Type": "ROOT"}}})
+	})
+	// The root allows everything, the OU only allows EC2
+	api.onFunc("organizations", "ListPoliciesForTarget", func(r stubRequest) stubResponse {
+		id := "p-full"
+		if strings.Contains(string(r.Body), "ou-stub") {
+			id = "p-ec2"
+		}
+		return jsonResponse(map[string]interface{}{"Policies": []interface{}{map[string]interface{}{"Id": id, "Arn": "arn:aws:organizations::999999999999:policy/o-stub/service_control_policy/" + id}}})
+	})
+	api.onFunc("organizations", "DescribePolicy", func(r stubRequest) stubResponse {
+		content := `{"Version": "2012-10-17", "Statement": {"Effect": "Allow", "Action": "*", "Resource": "*"}}`
+		if strings.Contains(string(r.Body), "p-ec2") {
+			content = `{"Version": "2012-10-17", "Statement": {"Effect": "Allow", "Action": "ec2:*", "Resource": "*"}}`
+		}
+		return jsonResponse(map[string]interface{}{"Policy": map[string]interface{}{"Content": content}})
+	})
+
+	rows, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_iam_policy_evaluation",
+		Columns: []string{"decision", "decision_details", "unevaluated_policy_types"},
+		Quals: map[string]string{
+			"principal_arn": "arn:aws:iam::" + stubAccountId + ":role/app",
+			"action":        "s3:GetObject",
+			"resource_arn":  "arn:aws:s3:::data/report.csv",
+			"context":       `{"aws:SourceIp": "10.1.2.3"}`,
+		},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(rows))
+	}
+	details := rows[0]["decision_details"].(map[string]interface{})
+	if rows[0]["decision"] != iamDecisionImplicitDeny || details[iamPolicyTypeServiceControl] != iamDecisionImplicitDeny || details[iamPolicyTypeIdentity] != iamDecisionAllowed {
+		t.Errorf("expected the OU SCP to deny the request, got %v", rows[0])
+	}
+	if rows[0]["unevaluated_policy_types"] != nil {
+		t.Errorf("expected the SCPs to be evaluated, got %v", rows[0]["unevaluated_policy_types"])
+	}
+	if n := len(api.calls("organizations", "DescribePolicy")); n != 2 {
+		t.Errorf("expected 2 DescribePolicy calls, got %d", n)
+	}
+}
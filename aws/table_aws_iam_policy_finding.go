@@ -0,0 +1,101 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+//// TABLE DEFINITION
+
+func tableAwsIamPolicyFinding(_ context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "aws_iam_policy_finding",
+		Description: "AWS IAM Policy Finding",
+		List: &plugin.ListConfig{
+			Hydrate: listIamPolicyFindings,
+			KeyColumns: []*plugin.KeyColumn{
+				{Name: "policy", Require: plugin.Required},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "check_id",
+				Description: "The ID of the check that found the issue, e.g. pass_role_all_resources.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "severity",
+				Description: "The severity of the finding: high, medium or low.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "title",
+				Description: "The title of the check.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "description",
+				Description: "The description of the issue found in the policy.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "remediation",
+				Description: "How to fix the issue.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "statement_index",
+				Description: "The index of the statement of the finding, starting at 0. Null for findings about the whole policy.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "sid",
+				Description: "The Sid of the statement of the finding.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("Sid").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "actions",
+				Description: "The actions involved in the finding, e.g. the actions of a privilege escalation.",
+				Type:        proto.ColumnType_JSON,
+				Transform:   transform.FromField("Actions").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "policy",
+				Description: "The policy document, as an object or a (URL encoded) string.",
+				Type:        proto.ColumnType_JSON,
+			},
+		},
+	}
+}
+
+type iamPolicyFindingRow struct {
+	iamPolicyFinding
+	Policy interface{}
+}
+
+//// LIST FUNCTION
+
+func listIamPolicyFindings(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	policy, raw, err := getIamPolicyDocumentQual(d, "policy")
+	if err != nil {
+		plugin.Logger(ctx).Error("aws_iam_policy_finding.listIamPolicyFindings", "policy_error", err)
+		return nil, err
+	}
+	if policy == nil {
+		return nil, nil
+	}
+
+	for _, finding := range getIamPolicyFindings(*policy) {
+		d.StreamListItem(ctx, iamPolicyFindingRow{iamPolicyFinding: finding, Policy: raw})
+
+		// Context can be cancelled due to manual cancellation or the limit has been hit
+		if d.RowsRemaining(ctx) == 0 {
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
@@ -111,6 +111,10 @@ func listIamPolicySimulation(ctx context.Context, d *plugin.QueryData, _ *plugin
 		plugin.Logger(ctx).Error("aws_iam_policy_simulator.listIamPolicySimulation", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	var params = &iam.SimulatePrincipalPolicyInput{
 		PolicySourceArn: &principalArn,
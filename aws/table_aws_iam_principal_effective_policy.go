@@ -0,0 +1,223 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// Sources of the statements of a principal.
+const (
+	iamPolicySourceInline              = "inline"
+	iamPolicySourceManaged             = "managed"
+	iamPolicySourceGroupInline         = "group_inline"
+	iamPolicySourceGroupManaged        = "group_managed"
+	iamPolicySourcePermissionsBoundary = "permissions_boundary"
+	iamPolicySourceServiceControl      = "service_control"
+)
+
+//// TABLE DEFINITION
+
+func tableAwsIamPrincipalEffectivePolicy(_ context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "aws_iam_principal_effective_policy",
+		Description: "AWS IAM Principal Effective Policy",
+		List: &plugin.ListConfig{
+			Hydrate: listIamPrincipalEffectivePolicies,
+			Tags:    map[string]string{"service": "iam"},
+			KeyColumns: []*plugin.KeyColumn{
+				{Name: "principal_arn", Require: plugin.Required},
+			},
+		},
+		Columns: awsGlobalRegionColumns([]*plugin.Column{
+			{
+				Name:        "principal_arn",
+				Description: "The ARN of the IAM user, IAM role or role session.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "principal_type",
+				Description: "The type of the principal: user, role or assumed-role.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "source",
+				Description: "How the policy of the statement applies to the principal: inline, managed, group_inline, group_managed, permissions_boundary or service_control.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "policy_type",
+				Description: "The type of the policy of the statement in the policy evaluation logic: identity, permissions_boundary or service_control.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "policy_id",
+				Description: "The ARN of the managed policy or SCP, or the name of the inline policy.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "attached_to",
+				Description: "The ARN of the user, group or role the policy is attached to, or the ID of the root, organizational unit or account for SCPs.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "service_control_level",
+				Description: "The level of the organization of SCPs, from 0 for the root to the account.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "statement_index",
+				Description: "The index of the statement in the policy, starting at 0.",
+				Type:        proto.ColumnType_INT,
+			},
+			{
+				Name:        "sid",
+				Description: "The Sid of the statement.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("Sid").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "effect",
+				Description: "The effect of the statement: Allow or Deny.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "statement",
+				Description: "The statement, in canonical form.",
+				Type:        proto.ColumnType_JSON,
+			},
+			{
+				Name:        "service_control_policies_unavailable",
+				Description: "True if the SCPs of the account can't be read with the credentials of the connection, and are not included.",
+				Type:        proto.ColumnType_BOOL,
+			},
+		}),
+	}
+}
+
+type iamPrincipalEffectivePolicyRow struct {
+	PrincipalArn                      string
+	PrincipalType                     string
+	Source                            string
+	PolicyType                        string
+	PolicyId                          string
+	AttachedTo                        string
+	ServiceControlLevel               *int
+	StatementIndex                    int
+	Sid                               string
+	Effect                            string
+	Statement                         Statement
+	ServiceControlPoliciesUnavailable bool
+}
+
+//// LIST FUNCTION
+
+func listIamPrincipalEffectivePolicies(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	logger := plugin.Logger(ctx)
+
+	principalArn := d.EqualsQualString("principal_arn")
+
+	commonData, err := getCommonColumns(ctx, d, h)
+	if err != nil {
+		return nil, err
+	}
+	commonColumnData := commonData.(*awsCommonColumnData)
+
+	a, err := arn.Parse(principalArn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid principal_arn %q: %v", principalArn, err)
+	}
+	if a.AccountID != commonColumnData.AccountId {
+		return nil, fmt.Errorf("principal_arn %s is not in the account of the connection (%s)", principalArn, commonColumnData.AccountId)
+	}
+
+	loader, err := newIamPolicyLoader(ctx, d)
+	if err != nil {
+		logger.Error("aws_iam_principal_effective_policy.listIamPrincipalEffectivePolicies", "client_error", err)
+		return nil, err
+	}
+	if loader == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
+	principal, err := loader.getPrincipalPolicies(ctx, principalArn)
+	if err != nil {
+		logger.Error("aws_iam_principal_effective_policy.listIamPrincipalEffectivePolicies", "api_error", err)
+		return nil, err
+	}
+	serviceControl, evaluated, err := getIamServiceControlPolicies(ctx, d, principal.AccountId)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := principal.Identity
+	if principal.PermissionsBoundary != nil {
+		policies = append(policies, *principal.PermissionsBoundary)
+	}
+	levels := make([]int, len(policies))
+	for level, scps := range serviceControl {
+		for _, scp := range scps {
+			policies = append(policies, scp)
+			levels = append(levels, level)
+		}
+	}
+
+	for i, policy := range policies {
+		for statementIndex, statement := range policy.Policy.Statements {
+			row := iamPrincipalEffectivePolicyRow{
+				PrincipalArn:                      principalArn,
+				PrincipalType:                     principal.PrincipalType,
+				Source:                            getIamPolicySource(policy),
+				PolicyType:                        policy.Type,
+				PolicyId:                          policy.Id,
+				AttachedTo:                        policy.Source,
+				StatementIndex:                    statementIndex,
+				Sid:                               statement.Sid,
+				Effect:                            statement.Effect,
+				Statement:                         statement,
+				ServiceControlPoliciesUnavailable: !evaluated,
+			}
+			if policy.Type == iamPolicyTypeServiceControl {
+				row.ServiceControlLevel = &levels[i]
+			}
+			d.StreamListItem(ctx, row)
+
+			// Context can be cancelled due to manual cancellation or the limit has been hit
+			if d.RowsRemaining(ctx) == 0 {
+				return nil, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+//// UTILITY FUNCTIONS
+
+// Identity policies are managed policies if identified by their ARN, and
+// inherited from a group if attached to a group.
+func getIamPolicySource(policy iamEvaluationPolicy) string {
+	switch policy.Type {
+	case iamPolicyTypePermissionsBoundary:
+		return iamPolicySourcePermissionsBoundary
+	case iamPolicyTypeServiceControl:
+		return iamPolicySourceServiceControl
+	}
+	managed := strings.HasPrefix(policy.Id, "arn:")
+	if a, err := arn.Parse(policy.Source); err == nil && strings.HasPrefix(a.Resource, "group/") {
+		if managed {
+			return iamPolicySourceGroupManaged
+		}
+		return iamPolicySourceGroupInline
+	}
+	if managed {
+		return iamPolicySourceManaged
+	}
+	return iamPolicySourceInline
+}
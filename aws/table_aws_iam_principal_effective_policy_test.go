@@ -0,0 +1,117 @@
+package aws
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestAwsIamPrincipalEffectivePolicyUser(t *testing.T) {
+	api := newStubAPI(t)
+	userArn := "arn:aws:iam::" + stubAccountId + ":user/alice"
+	groupArn := "arn:aws:iam::" + stubAccountId + ":group/admins"
+	managedArn := "arn:aws:iam::aws:policy/ReadOnlyAccess"
+	userPolicy := `{"Version": "2012-10-17", "Statement": [{"Sid": "Own", "Effect": "Allow", "Action": "iam:ChangePassword", "Resource": "` + userArn + `"}, {"Effect": "Deny", "Action": "s3:*", "Resource": "*"}]}`
+	groupPolicy := `{"Version": "2012-10-17", "Statement": {"Effect": "Allow", "Action": "*", "Resource": "*"}}`
+	managedPolicy := `{"Version": "2012-10-17", "Statement": {"Effect": "Allow", "Action": ["S3:Get*", "ec2:Describe*"], "Resource": "*"}}`
+
+	api.on("iam", "GetUser", queryResponse("GetUser", `<User><Path>/</Path><UserName>alice</UserName><UserId>AIDASTUB</UserId><Arn>`+userArn+`</Arn><CreateDate>2024-01-01T00:00:00Z</CreateDate></User>`))
+	api.on("iam", "ListUserPolicies", queryResponse("ListUserPolicies", `<PolicyNames><member>own</member></PolicyNames><IsTruncated>false</IsTruncated>`))
+	api.on("iam", "GetUserPolicy", queryResponse("GetUserPolicy", `<UserName>alice</UserName><PolicyName>own</PolicyName><PolicyDocument>`+url.QueryEscape(userPolicy)+`</PolicyDocument>`))
+	api.on("iam", "ListAttachedUserPolicies", queryResponse("ListAttachedUserPolicies", `<AttachedPolicies/><IsTruncated>false</IsTruncated>`))
+	api.on("iam", "ListGroupsForUser", queryResponse("ListGroupsForUser", `<Groups><member><Path>/</Path><GroupName>admins</GroupName><GroupId>AGPASTUB</GroupId><Arn>`+groupArn+`</Arn><CreateDate>2024-01-01T00:00:00Z</CreateDate></member></Groups><IsTruncated>false</IsTruncated>`))
+	api.on("iam", "ListGroupPolicies", queryResponse("ListGroupPolicies", `<PolicyNames><member>admin</member></PolicyNames><IsTruncated>false</IsTruncated>`))
+	api.on("iam", "GetGroupPolicy", queryResponse("GetGroupPolicy", `<GroupName>admins</GroupName><PolicyName>admin</PolicyName><PolicyDocument>`+url.QueryEscape(groupPolicy)+`</PolicyDocument>`))
+	api.on("iam", "ListAttachedGroupPolicies", queryResponse("ListAttachedGroupPolicies", `<AttachedPolicies><member><PolicyName>ReadOnlyAccess</PolicyName><PolicyArn>`+managedArn+`</PolicyArn></member></AttachedPolicies><IsTruncated>false</IsTruncated>`))
+	api.on("iam", "GetPolicy", queryResponse("GetPolicy", `<Policy><Arn>`+managedArn+`</Arn><DefaultVersionId>v3</DefaultVersionId></Policy>`))
+	api.on("iam", "GetPolicyVersion", queryResponse("GetPolicyVersion", `<PolicyVersion><VersionId>v3</VersionId><IsDefaultVersion>true</IsDefaultVersion><Document>`+url.QueryEscape(managedPolicy)+`</Document></PolicyVersion>`))
+	api.on("organizations", "DescribeOrganization", jsonErrorResponse(http.StatusBadRequest, "AWSOrganizationsNotInUseException", "not in an organization"))
+
+	rows, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_iam_principal_effective_policy",
+		Columns: []string{"principal_arn", "principal_type", "source", "policy_id", "attached_to", "statement_index", "sid", "effect", "statement", "service_control_policies_unavailable"},
+		Quals:   map[string]string{"principal_arn": userArn},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	var actual []string
+	for _, row := range rows {
+		if row["principal_type"] != "user" || row["service_control_policies_unavailable"] != false {
+			t.Errorf("unexpected row %v", row)
+		}
+		actual = append(actual, strings.Join([]string{row["source"].(string), row["policy_id"].(string), row["attached_to"].(string), row["effect"].(string)}, " "))
+	}
+	sort.Strings(actual)
+	expected := []string{
+		"group_inline admin " + groupArn + " Allow",
+		"group_managed " + managedArn + " " + groupArn + " Allow",
+		"inline own " + userArn + " Allow",
+		"inline own " + userArn + " Deny",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+
+	// Statements are in canonical form
+	for _, row := range rows {
+		if row["policy_id"] == managedArn {
+			actions := row["statement"].(map[string]interface{})["Action"]
+			if !reflect.DeepEqual(actions, []interface{}{"ec2:describe*", "s3:get*"}) {
+				t.Errorf("expected canonical actions, got %v", actions)
+			}
+		}
+		if row["sid"] == "Own" && row["statement_index"] != int64(0) {
+			t.Errorf("expected the statement index 0, got %v", row["statement_index"])
+		}
+	}
+}
+
+func TestAwsIamPrincipalEffectivePolicyRole(t *testing.T) {
+	api := newIamPolicyEvaluationStubAPI(t)
+	api.on("organizations", "DescribeOrganization", jsonResponse(map[string]interface{}{
+		"Organization": map[string]interface{}{"Id": "o-stub", "MasterAccountId": "999999999999", "FeatureSet": "ALL"},
+	}))
+	api.on("organizations", "ListParents", jsonResponse(map[string]interface{}{"Parents": []interface{}{map[string]interface{}{"Id": "r-stub", "Type": "ROOT"}}}))
+	api.on("organizations", "ListPoliciesForTarget", jsonResponse(map[string]interface{}{"Policies": []interface{}{map[string]interface{}{"Id": "p-full", "Arn": "arn:aws:organizations::999999999999:policy/o-stub/service_control_policy/p-full"}}}))
+	api.on("organizations", "DescribePolicy", jsonResponse(map[string]interface{}{"Policy": map[string]interface{}{"Content": `{"Version": "2012-10-17", "Statement": {"Effect": "Allow", "Action": "*", "Resource": "*"}}`}}))
+
+	rows, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_iam_principal_effective_policy",
+		Columns: []string{"source", "policy_type", "policy_id", "attached_to", "service_control_level", "statement_index"},
+		Quals:   map[string]string{"principal_arn": "arn:aws:iam::" + stubAccountId + ":role/app"},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	sources := map[string]int{}
+	for _, row := range rows {
+		source := row["source"].(string)
+		sources[source]++
+		switch source {
+		case iamPolicySourceServiceControl:
+			// The root, then the account
+			if row["attached_to"] == "r-stub" && row["service_control_level"] != int64(0) || row["attached_to"] == stubAccountId && row["service_control_level"] != int64(1) {
+				t.Errorf("unexpected SCP level %v", row)
+			}
+		case iamPolicySourcePermissionsBoundary:
+			if row["policy_type"] != iamPolicyTypePermissionsBoundary || row["service_control_level"] != nil {
+				t.Errorf("unexpected boundary row %v", row)
+			}
+		}
+	}
+	expected := map[string]int{
+		iamPolicySourceInline:              1,
+		iamPolicySourceManaged:             2,
+		iamPolicySourcePermissionsBoundary: 1,
+		iamPolicySourceServiceControl:      2,
+	}
+	if !reflect.DeepEqual(sources, expected) {
+		t.Errorf("expected statements %v, got %v", expected, sources)
+	}
+}
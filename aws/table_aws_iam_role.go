@@ -51,6 +51,7 @@ func tableAwsIamRole(ctx context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "iam", "action": "ListRolePolicies"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			// "Key" Columns
 			{
@@ -211,6 +212,10 @@ func listIamRoles(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateDat
 		plugin.Logger(ctx).Error("aws_iam_role.listIamRoles", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	maxItems := int32(1000)
 
@@ -270,6 +275,10 @@ func getIamRole(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData)
 		plugin.Logger(ctx).Error("aws_iam_role.getIamRole", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	var name string
 	if h.Item != nil {
@@ -302,6 +311,10 @@ func getAwsIamInstanceProfileData(ctx context.Context, d *plugin.QueryData, h *p
 		plugin.Logger(ctx).Error("aws_iam_role.getAwsIamInstanceProfileData", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	var associatedInstanceProfileArns []string
 	params := iam.ListInstanceProfilesForRoleInput{RoleName: role.RoleName}
@@ -338,6 +351,10 @@ func getAwsIamRoleAttachedPolicies(ctx context.Context, d *plugin.QueryData, h *
 		plugin.Logger(ctx).Error("aws_iam_role.getAwsIamRoleAttachedPolicies", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &iam.ListAttachedRolePoliciesInput{RoleName: role.RoleName}
 	paginator := iam.NewListAttachedRolePoliciesPaginator(svc, params, func(o *iam.ListAttachedRolePoliciesPaginatorOptions) {
@@ -373,6 +390,10 @@ func listAwsIamRoleInlinePolicies(ctx context.Context, d *plugin.QueryData, h *p
 		plugin.Logger(ctx).Error("aws_iam_role.listAwsIamRoleInlinePolicies", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &iam.ListRolePoliciesInput{RoleName: role.RoleName}
 	paginator := iam.NewListRolePoliciesPaginator(svc, params, func(o *iam.ListRolePoliciesPaginatorOptions) {
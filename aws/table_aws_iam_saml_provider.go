@@ -36,6 +36,7 @@ func tableAwsIamSamlProvider(_ context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "iam", "action": "GetSAMLProvider"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "arn",
@@ -102,6 +103,10 @@ func listIamSamlProviders(ctx context.Context, d *plugin.QueryData, h *plugin.Hy
 		plugin.Logger(ctx).Error("aws_iam_saml_provider.listIamSamlProviders", "service_creation_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &iam.ListSAMLProvidersInput{}
 
@@ -150,6 +155,10 @@ func getIamSamlProvider(ctx context.Context, d *plugin.QueryData, h *plugin.Hydr
 		plugin.Logger(ctx).Error("aws_iam_saml_provider.getIamSamlProvider", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &iam.GetSAMLProviderInput{
 		SAMLProviderArn: aws.String(arn),
@@ -36,6 +36,7 @@ func tableAwsIamServerCertificate(_ context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "iam", "action": "GetServerCertificate"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "name",
@@ -127,6 +128,10 @@ func listIamServerCertificates(ctx context.Context, d *plugin.QueryData, _ *plug
 		plugin.Logger(ctx).Error("aws_iam_server_certificate.listIamServerCertificates", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	maxItems := int32(100)
 
@@ -186,6 +191,10 @@ func getIamServerCertificate(ctx context.Context, d *plugin.QueryData, h *plugin
 		plugin.Logger(ctx).Error("aws_iam_server_certificate.getIamServerCertificate", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	var name string
 	if h.Item != nil {
@@ -26,6 +26,7 @@ func tableAwsIamUserServiceSpecificCredential(ctx context.Context) *plugin.Table
 				{Name: "user_name", Require: plugin.Optional},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "service_name",
@@ -80,6 +81,10 @@ func listAwsIamUserServiceSpecificCredentials(ctx context.Context, d *plugin.Que
 		plugin.Logger(ctx).Error("aws_iam_service_specific_credential.listAwsIamUserServiceSpecificCredentials", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	if d.EqualsQuals["user_name"].GetStringValue() != "" && *user.UserName != d.EqualsQuals["user_name"].GetStringValue() {
 		return nil, nil
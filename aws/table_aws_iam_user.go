@@ -64,6 +64,7 @@ func tableAwsIamUser(ctx context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "iam", "action": "ListUserPolicies"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "name",
@@ -196,6 +197,10 @@ func listIamUsers(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateDat
 		plugin.Logger(ctx).Error("aws_iam_role.listIamRoles", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	maxItems := int32(1000)
 	input := iam.ListUsersInput{}
@@ -262,6 +267,10 @@ func getIamUser(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData)
 		plugin.Logger(ctx).Error("aws_iam_user.getIamUser", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &iam.GetUserInput{
 		UserName: aws.String(name),
@@ -285,6 +294,10 @@ func getAwsIamUserLoginProfile(ctx context.Context, d *plugin.QueryData, h *plug
 		plugin.Logger(ctx).Error("aws_iam_user.getAwsIamUserLoginProfile", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &iam.GetLoginProfileInput{
 		UserName: name,
@@ -315,6 +328,10 @@ func getAwsIamUserData(ctx context.Context, d *plugin.QueryData, h *plugin.Hydra
 		plugin.Logger(ctx).Error("aws_iam_user.getAwsIamUserData", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &iam.GetUserInput{
 		UserName: user.UserName,
@@ -362,6 +379,10 @@ func getAwsIamUserAttachedPolicies(ctx context.Context, d *plugin.QueryData, h *
 		plugin.Logger(ctx).Error("aws_iam_user.getAwsIamUserAttachedPolicies", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &iam.ListAttachedUserPoliciesInput{
 		UserName: user.UserName,
@@ -393,6 +414,10 @@ func getAwsIamUserGroups(ctx context.Context, d *plugin.QueryData, h *plugin.Hyd
 		plugin.Logger(ctx).Error("aws_iam_user.getAwsIamUserGroups", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &iam.ListGroupsForUserInput{
 		UserName: user.UserName,
@@ -416,6 +441,10 @@ func getAwsIamUserMfaDevices(ctx context.Context, d *plugin.QueryData, h *plugin
 		plugin.Logger(ctx).Error("aws_iam_user.getAwsIamUserMfaDevices", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &iam.ListMFADevicesInput{
 		UserName: user.UserName,
@@ -439,6 +468,10 @@ func listAwsIamUserInlinePolicies(ctx context.Context, d *plugin.QueryData, h *p
 		plugin.Logger(ctx).Error("aws_iam_user.listAwsIamUserInlinePolicies", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &iam.ListUserPoliciesInput{
 		UserName: user.UserName,
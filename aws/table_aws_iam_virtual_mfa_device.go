@@ -31,6 +31,7 @@ func tableAwsIamVirtualMfaDevice(_ context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "iam", "action": "ListMFADeviceTags"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "serial_number",
@@ -119,6 +120,10 @@ func listIamVirtualMFADevices(ctx context.Context, d *plugin.QueryData, _ *plugi
 		plugin.Logger(ctx).Error("aws_iam_virtual_mfa_device.listIamVirtualMFADevices", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	maxItems := int32(1000)
 	input := iam.ListVirtualMFADevicesInput{}
@@ -182,6 +187,10 @@ func getIamMfaDeviceTags(ctx context.Context, d *plugin.QueryData, h *plugin.Hyd
 		plugin.Logger(ctx).Error("aws_iam_virtual_mfa_device.getIamMfaDeviceTags", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &iam.ListMFADeviceTagsInput{SerialNumber: data.SerialNumber}
 
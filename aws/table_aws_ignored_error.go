@@ -0,0 +1,106 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+//// TABLE DEFINITION
+
+func tableAwsIgnoredError(_ context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "aws_ignored_error",
+		Description: "AWS Ignored Error",
+		List: &plugin.ListConfig{
+			Hydrate: listIgnoredErrors,
+			KeyColumns: []*plugin.KeyColumn{
+				{Name: "table_name", Require: plugin.Optional},
+				{Name: "error_code", Require: plugin.Optional},
+			},
+		},
+		// Always list the errors recorded up to now
+		Cache: &plugin.TableCacheOptions{Enabled: false},
+		Columns: []*plugin.Column{
+			{
+				Name:        "table_name",
+				Description: "The table queried.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "region",
+				Description: "The region of the failed call, if the table is queried per region.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("Region").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "account_id",
+				Description: "The member account of the failed call, for organization connections.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("AccountId").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "hydrate_function",
+				Description: "The hydrate function of the table with service and action tags matching the failed call.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("HydrateFunction").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "service",
+				Description: "The AWS SDK service ID of the failed call, e.g. EC2.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("Service").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "operation",
+				Description: "The API operation of the failed call, e.g. DescribeInstances.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("Operation").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "error_code",
+				Description: "The error code.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "error_message",
+				Description: "The error message.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "reason",
+				Description: "Why the error was ignored: not_found for the not found error codes of the table, ignore_error_codes for the ignore_error_codes of the connection, or no_fips_endpoint for regions skipped with use_fips_endpoint.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "time",
+				Description: "The time the error was ignored.",
+				Type:        proto.ColumnType_TIMESTAMP,
+			},
+		},
+	}
+}
+
+//// LIST FUNCTION
+
+func listIgnoredErrors(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	tableNameQual := d.EqualsQualString("table_name")
+	errorCodeQual := d.EqualsQualString("error_code")
+
+	for _, e := range getIgnoredErrors(d.Connection.Name) {
+		if (tableNameQual != "" && tableNameQual != e.TableName) || (errorCodeQual != "" && errorCodeQual != e.ErrorCode) {
+			continue
+		}
+
+		d.StreamListItem(ctx, e)
+
+		// Context can be cancelled due to manual cancellation or the limit has been hit
+		if d.RowsRemaining(ctx) == 0 {
+			return nil, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,91 @@
+package aws
+
+import (
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestAwsIgnoredError(t *testing.T) {
+	// Errors are kept for the life of the process, so start from scratch
+	ignoredErrorLogs.Delete(stubConnection)
+
+	api := newStubAPI(t)
+	api.on("sns", "ListTopics", queryErrorResponse(http.StatusForbidden, "AuthorizationError", "not authorized"))
+	api.on("sns", "GetTopicAttributes", queryErrorResponse(http.StatusNotFound, "NotFound", "Topic does not exist"))
+
+	rows, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_sns_topic",
+		Columns: []string{"topic_arn"},
+		Config:  `ignore_error_codes = ["Authorization*"]`,
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 0 {
+		t.Fatalf("expected no rows, got %v", rows)
+	}
+	_, err = runTableQuery(t, api, tableQuery{
+		Table:   "aws_sns_topic",
+		Columns: []string{"topic_arn"},
+		Quals:   map[string]string{"topic_arn": "arn:aws:sns:us-east-1:123456789012:missing"},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	rows, err = runTableQuery(t, api, tableQuery{
+		Table: "aws_ignored_error",
+		Quals: map[string]string{"table_name": "aws_sns_topic"},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d: %v", len(rows), rows)
+	}
+	// Rows are streamed concurrently
+	sort.Slice(rows, func(i, j int) bool { return rows[i]["time"].(time.Time).Before(rows[j]["time"].(time.Time)) })
+	expected := []map[string]interface{}{
+		{"region": "us-east-1", "hydrate_function": "listAwsSnsTopics", "service": "SNS", "operation": "ListTopics", "error_code": "AuthorizationError", "reason": "ignore_error_codes"},
+		{"region": "us-east-1", "hydrate_function": "getTopicAttributes", "service": "SNS", "operation": "GetTopicAttributes", "error_code": "NotFound", "reason": "not_found"},
+	}
+	for i, row := range rows {
+		for column, value := range expected[i] {
+			if row[column] != value {
+				t.Errorf("row %d: expected %s %v, got %v", i, column, value, row[column])
+			}
+		}
+	}
+}
+
+func TestAwsIgnoredErrorServiceTag(t *testing.T) {
+	ignoredErrorLogs.Delete(stubConnection)
+
+	// The CloudWatch Logs service ID doesn't match the "logs" service tag
+	api := newStubAPI(t)
+	api.on("logs", "DescribeLogGroups", jsonErrorResponse(http.StatusBadRequest, "AccessDeniedException", "not authorized"))
+	_, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_cloudwatch_log_group",
+		Columns: []string{"name"},
+		Config:  `ignore_error_codes = ["AccessDenied*"]`,
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	rows, err := runTableQuery(t, api, tableQuery{
+		Table: "aws_ignored_error",
+		Quals: map[string]string{"table_name": "aws_cloudwatch_log_group"},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d: %v", len(rows), rows)
+	}
+	if rows[0]["hydrate_function"] != "getCloudwatchLogGroup,listCloudwatchLogGroups" {
+		t.Errorf("expected hydrate_function getCloudwatchLogGroup,listCloudwatchLogGroups, got %v", rows[0]["hydrate_function"])
+	}
+}
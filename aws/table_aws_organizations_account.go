@@ -147,6 +147,10 @@ func listOrganizationsAccounts(ctx context.Context, d *plugin.QueryData, h *plug
 		plugin.Logger(ctx).Error("aws_organizations_account.listOrganizationsAccounts", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	// Limit the result
 	maxItems := int32(20)
@@ -228,6 +232,10 @@ func getOrganizationsAccount(ctx context.Context, d *plugin.QueryData, _ *plugin
 		plugin.Logger(ctx).Error("aws_organizations_account.getOrganizationsAccount", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &organizations.DescribeAccountInput{
 		AccountId: &accountId,
@@ -321,6 +329,10 @@ func getOrganizationsResourceTags(ctx context.Context, d *plugin.QueryData, reso
 		plugin.Logger(ctx).Error("aws_organizations_account.getOrganizationsResourceTags", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &organizations.ListTagsForResourceInput{
 		ResourceId: &resourceId,
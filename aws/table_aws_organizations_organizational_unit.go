@@ -106,6 +106,10 @@ func listOrganizationsOrganizationalUnits(ctx context.Context, d *plugin.QueryDa
 		plugin.Logger(ctx).Error("aws_organizations_organizational_unit.listOrganizationsOrganizationalUnits", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	// Limiting the result
 	maxItems := int32(20)
@@ -177,6 +181,10 @@ func getOrganizationsOrganizationalUnit(ctx context.Context, d *plugin.QueryData
 		plugin.Logger(ctx).Error("aws_organizations_organizational_unit.getOrganizationsOrganizationalUnit", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &organizations.DescribeOrganizationalUnitInput{
 		OrganizationalUnitId: aws.String(orgUnitId),
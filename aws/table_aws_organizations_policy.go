@@ -109,6 +109,10 @@ func listOrganizationsPolicies(ctx context.Context, d *plugin.QueryData, _ *plug
 		plugin.Logger(ctx).Error("aws_organizations_policy.listOrganizationsPolicies", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	policyType := d.EqualsQualString("type")
 
@@ -187,6 +191,10 @@ func getOrganizationsPolicy(ctx context.Context, d *plugin.QueryData, h *plugin.
 		plugin.Logger(ctx).Error("aws_organizations_policy.getOrganizationsPolicy", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &organizations.DescribePolicyInput{
 		PolicyId: aws.String(policyId),
@@ -114,6 +114,10 @@ func listOrganizationsPolicyTragets(ctx context.Context, d *plugin.QueryData, _
 		plugin.Logger(ctx).Error("aws_organizations_policy_target.listOrganizationsPolicyTragets", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	policyType := d.EqualsQualString("type")
 	targetId := d.EqualsQualString("target_id")
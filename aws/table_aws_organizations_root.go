@@ -72,6 +72,10 @@ func listOrganizationsRoots(ctx context.Context, d *plugin.QueryData, _ *plugin.
 		plugin.Logger(ctx).Error("aws_organizations_root.listOrganizationsRoots", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	// Limiting the result
 	maxItems := int32(20)
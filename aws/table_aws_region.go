@@ -19,6 +19,7 @@ func tableAwsRegion(_ context.Context) *plugin.Table {
 		},
 		// Get is not implemented because the API is not paged anyway, so
 		// the List has the same cost but better caching benefit.
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsAccountColumns([]*plugin.Column{
 			{
 				Name:        "name",
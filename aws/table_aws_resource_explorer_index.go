@@ -29,6 +29,7 @@ func tableAWSResourceExplorerIndex(_ context.Context) *plugin.Table {
 				{Name: "region", Require: plugin.Optional},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsAccountColumns([]*plugin.Column{
 			{
 				Name:        "arn",
@@ -19,6 +19,7 @@ func tableAWSResourceExplorerSupportedResourceType(_ context.Context) *plugin.Ta
 			Hydrate: listAWSExplorerSupportedTypes,
 			Tags:    map[string]string{"service": "resource-explorer-2", "action": "ListSupportedResourceTypes"},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsAccountColumns([]*plugin.Column{
 			{
 				Name:        "resource_type",
@@ -37,6 +37,7 @@ func tableAwsRoute53Domain(_ context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "route53domains", "action": "ListTagsForDomain"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "domain_name",
@@ -236,6 +237,10 @@ func listRoute53Domains(ctx context.Context, d *plugin.QueryData, _ *plugin.Hydr
 		plugin.Logger(ctx).Error("aws_route53_domain.listRoute53Domains", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	maxItems := int32(100)
 	input := route53domains.ListDomainsInput{}
@@ -298,6 +303,10 @@ func getRoute53Domain(ctx context.Context, d *plugin.QueryData, h *plugin.Hydrat
 		plugin.Logger(ctx).Error("aws_route53_domain.getRoute53Domain", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	// Build the params
 	params := &route53domains.GetDomainDetailInput{
@@ -323,6 +332,10 @@ func getRoute53DomainTags(ctx context.Context, d *plugin.QueryData, h *plugin.Hy
 		plugin.Logger(ctx).Error("aws_route53_domain.getRoute53DomainTags", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	// Build the params
 	params := route53domains.ListTagsForDomainInput{
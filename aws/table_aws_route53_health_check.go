@@ -39,6 +39,7 @@ func tableAwsRoute53HealthCheck(_ context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "route53", "action": "ListTagsForResource"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "id",
@@ -126,6 +127,10 @@ func listHealthChecks(ctx context.Context, d *plugin.QueryData, _ *plugin.Hydrat
 		plugin.Logger(ctx).Error("aws_route53_health_check.listHealthChecks", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	maxItems := int32(100)
 	input := route53.ListHealthChecksInput{}
@@ -180,6 +185,10 @@ func getHealthCheck(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateD
 		plugin.Logger(ctx).Error("aws_route53_health_check.getHealthCheck", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 	id := d.EqualsQuals["id"].GetStringValue()
 
 	// Validate user input
@@ -210,6 +219,10 @@ func getHealthCheckStatus(ctx context.Context, d *plugin.QueryData, h *plugin.Hy
 		plugin.Logger(ctx).Error("aws_route53_health_check.getHealthCheckStatus", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &route53.GetHealthCheckStatusInput{
 		HealthCheckId: healthCheck.Id,
@@ -240,6 +253,10 @@ func getHealthCheckTags(ctx context.Context, d *plugin.QueryData, h *plugin.Hydr
 		plugin.Logger(ctx).Error("aws_route53_health_check.getHealthCheckTags", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &route53.ListTagsForResourceInput{
 		ResourceId:   healthCheck.Id,
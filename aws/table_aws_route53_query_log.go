@@ -35,6 +35,7 @@ func tableAwsRoute53QueryLog(_ context.Context) *plugin.Table {
 				ShouldIgnoreErrorFunc: shouldIgnoreErrors([]string{"NoSuchHostedZone"}),
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsRegionalColumns([]*plugin.Column{
 			{
 				Name:        "id",
@@ -82,6 +83,10 @@ func listRoute53QueryLogs(ctx context.Context, d *plugin.QueryData, _ *plugin.Hy
 		plugin.Logger(ctx).Error("aws_route53_query_log.listRoute53QueryLogs", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	maxItems := int32(100)
 	// Reduce the basic request limit down if the user has only requested a small number of rows
@@ -137,6 +142,10 @@ func getRoute53QueryLog(ctx context.Context, d *plugin.QueryData, h *plugin.Hydr
 		plugin.Logger(ctx).Error("aws_route53_query_log.getRoute53QueryLog", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	id := d.EqualsQualString("id")
 	if id == "" {
@@ -28,6 +28,7 @@ func tableAwsRoute53TrafficPolicyInstance(_ context.Context) *plugin.Table {
 			Hydrate: listTrafficPolicyInstances,
 			Tags:    map[string]string{"service": "route53", "action": "ListTrafficPolicyInstances"},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "name",
@@ -103,6 +104,10 @@ func listTrafficPolicyInstances(ctx context.Context, d *plugin.QueryData, _ *plu
 		plugin.Logger(ctx).Error("aws_route53_traffic_policy_instance.listTrafficPolicyInstances", "connection_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	// Limiting the results
 	maxLimit := int32(100)
@@ -175,6 +180,10 @@ func getTrafficPolicyInstance(ctx context.Context, d *plugin.QueryData, h *plugi
 		plugin.Logger(ctx).Error("aws_route53_traffic_policy_instance.getTrafficPolicyInstance", "connection_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &route53.GetTrafficPolicyInstanceInput{
 		Id: aws.String(id),
@@ -31,6 +31,7 @@ func tableAwsRoute53VPCAssociationAuthorization(_ context.Context) *plugin.Table
 			},
 			Tags: map[string]string{"service": "route53", "action": "ListVPCAssociationAuthorizations"},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "hosted_zone_id",
@@ -76,6 +77,10 @@ func listVPCAssociationAuthorization(ctx context.Context, d *plugin.QueryData, h
 		plugin.Logger(ctx).Error("aws_route53_zone_vpc_association_authorization.listVPCAssociationAuthorization", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	var hostedZoneId = d.EqualsQualString("hosted_zone_id")
 
@@ -49,6 +49,7 @@ func tableAwsRoute53Zone(_ context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "route53", "action": "GetDNSSEC"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "name",
@@ -178,6 +179,10 @@ func listHostedZones(ctx context.Context, d *plugin.QueryData, _ *plugin.Hydrate
 		plugin.Logger(ctx).Error("aws_route53_zone.listHostedZones", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	// https://docs.aws.amazon.com/Route53/latest/APIReference/API_ListHostedZones.html
 	// The maximum/minimum record set per page is not mentioned in doc, so it has been set 1000 to max and 1 to min
@@ -235,6 +240,10 @@ func getHostedZone(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateDa
 		plugin.Logger(ctx).Error("aws_route53_zone.getHostedZone", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	id := d.EqualsQuals["id"].GetStringValue()
 
@@ -274,6 +283,10 @@ func getHostedZoneTags(ctx context.Context, d *plugin.QueryData, h *plugin.Hydra
 		plugin.Logger(ctx).Error("aws_route53_zone.getHostedZoneTags", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &route53.ListTagsForResourceInput{
 		ResourceId:   &strings.Split(*hostedZone.Id, "/")[2],
@@ -305,6 +318,10 @@ func getHostedZoneQueryLoggingConfigs(ctx context.Context, d *plugin.QueryData,
 		plugin.Logger(ctx).Error("aws_route53_zone.getHostedZoneQueryLoggingConfigs", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &route53.ListQueryLoggingConfigsInput{
 		HostedZoneId: &strings.Split(*hostedZone.Id, "/")[2],
@@ -338,6 +355,10 @@ func getHostedZoneDNSSEC(ctx context.Context, d *plugin.QueryData, h *plugin.Hyd
 		plugin.Logger(ctx).Error("aws_route53_zone.getHostedZoneDNSSEC", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &route53.GetDNSSECInput{
 		HostedZoneId: hostedZone.Id,
@@ -376,6 +397,10 @@ func getRoute53HostedZoneLimit(ctx context.Context, d *plugin.QueryData, h *plug
 		plugin.Logger(ctx).Error("aws_route53_zone.getRoute53HostedZoneLimit", "client_error", err)
 		return nil, err
 	}
+	if svc == nil {
+		// Unsupported partition, return no data
+		return nil, nil
+	}
 
 	params := &route53.GetHostedZoneLimitInput{
 		HostedZoneId: hostedZone.Id,
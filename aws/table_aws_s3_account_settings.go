@@ -29,6 +29,7 @@ func tableAwsS3AccountSettings(_ context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "s3", "action": "GetAccountPublicAccessBlock"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "block_public_acls",
@@ -102,6 +102,7 @@ func tableAwsS3Bucket(_ context.Context) *plugin.Table {
 				Tags:    map[string]string{"service": "s3", "action": "GetBucketWebsite"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsAccountColumns([]*plugin.Column{
 			{
 				Name:        "name",
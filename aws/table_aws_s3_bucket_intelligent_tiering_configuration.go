@@ -33,6 +33,7 @@ func tableAwsS3BucketIntelligentTieringConfiguration(_ context.Context) *plugin.
 				{Name: "bucket_name", Require: plugin.Optional, CacheMatch: "exact"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsRegionalColumns([]*plugin.Column{
 			{
 				Name:        "bucket_name",
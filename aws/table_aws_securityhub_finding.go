@@ -479,7 +479,8 @@ func buildListFindingsParam(quals plugin.KeyColumnQualMap) *types.AwsSecurityFin
 func extractStandardControlArn(_ context.Context, d *transform.TransformData) (interface{}, error) {
 	findingArn := d.HydrateItem.(types.AwsSecurityFinding).Id
 
-	if strings.Contains(*findingArn, "arn:aws:securityhub") {
+	// The finding ARN can be in any partition, e.g. arn:aws-cn:securityhub
+	if strings.HasPrefix(*findingArn, "arn:") && strings.Contains(*findingArn, ":securityhub:") {
 		standardControlArn := strings.Replace(strings.Split(*findingArn, "/finding")[0], "subscription", "control", 1)
 		return standardControlArn, nil
 	}
@@ -115,9 +115,9 @@ func listSecurityHubStandardsControls(ctx context.Context, d *plugin.QueryData,
 
 	var standardsSubscriptionArn string
 	if strings.Contains(standardsArn, "standards") {
-		standardsSubscriptionArn = "arn:aws:securityhub:" + region + ":" + commonColumnData.AccountId + ":subscription" + strings.Split(standardsArn, "standards")[1]
+		standardsSubscriptionArn = "arn:" + commonColumnData.Partition + ":securityhub:" + region + ":" + commonColumnData.AccountId + ":subscription" + strings.Split(standardsArn, "standards")[1]
 	} else {
-		standardsSubscriptionArn = "arn:aws:securityhub:" + region + ":" + commonColumnData.AccountId + ":subscription" + strings.Split(standardsArn, "ruleset")[1]
+		standardsSubscriptionArn = "arn:" + commonColumnData.Partition + ":securityhub:" + region + ":" + commonColumnData.AccountId + ":subscription" + strings.Split(standardsArn, "ruleset")[1]
 	}
 
 	// Create session
@@ -0,0 +1,205 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/turbot/go-kit/helpers"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+type serviceRegionAvailability struct {
+	ServiceId              string
+	Region                 string
+	RegionDescription      string
+	Partition              string
+	Available              bool
+	GlobalService          bool
+	RegionOptInStatus      string
+	ServiceRegionsOverride bool
+	Queried                bool
+	Reason                 string
+	CatalogSource          string
+}
+
+//// TABLE DEFINITION
+
+func tableAwsServiceRegionAvailability(_ context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "aws_service_region_availability",
+		Description: "AWS Service Region Availability",
+		List: &plugin.ListConfig{
+			Hydrate: listServiceRegionAvailability,
+			KeyColumns: []*plugin.KeyColumn{
+				{Name: "service_id", Require: plugin.Optional},
+				{Name: "region", Require: plugin.Optional},
+			},
+		},
+		Columns: []*plugin.Column{
+			{
+				Name:        "service_id",
+				Description: "The endpoint ID of the service, e.g. ec2 or inspector2. This is the key used in service_regions.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "region",
+				Description: "The region.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "region_description",
+				Description: "The description of the region, e.g. US East (N. Virginia).",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "partition",
+				Description: "The partition of the connection, e.g. aws or aws-cn.",
+				Type:        proto.ColumnType_STRING,
+			},
+			{
+				Name:        "available",
+				Description: "True if the service is available in the region, according to the region catalog.",
+				Type:        proto.ColumnType_BOOL,
+			},
+			{
+				Name:        "global_service",
+				Description: "True if the service has a single global endpoint for the partition (e.g. iam), rather than regional endpoints.",
+				Type:        proto.ColumnType_BOOL,
+			},
+			{
+				Name:        "region_opt_in_status",
+				Description: "The opt-in status of the region for the account: opt-in-not-required, opted-in or not-opted-in. Null if the region list could not be retrieved.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("RegionOptInStatus").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "service_regions_override",
+				Description: "True if the regions for the service are set by service_regions in the connection config, rather than regions.",
+				Type:        proto.ColumnType_BOOL,
+			},
+			{
+				Name:        "queried",
+				Description: "True if tables for the service query the region.",
+				Type:        proto.ColumnType_BOOL,
+			},
+			{
+				Name:        "reason",
+				Description: "Why tables for the service do not query the region. Null if the region is queried.",
+				Type:        proto.ColumnType_STRING,
+				Transform:   transform.FromField("Reason").Transform(transform.NullIfZeroValue),
+			},
+			{
+				Name:        "catalog_source",
+				Description: "Where the availability of the service comes from: embedded (the catalog shipped with the plugin) or file (region_catalog_file).",
+				Type:        proto.ColumnType_STRING,
+			},
+		},
+	}
+}
+
+//// LIST FUNCTION
+
+func listServiceRegionAvailability(ctx context.Context, d *plugin.QueryData, h *plugin.HydrateData) (interface{}, error) {
+	catalog, err := getRegionCatalog(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prefer the partition of the caller identity, but still show the
+	// catalog if it is not available.
+	var partitionName string
+	if commonColumnData, err := getCommonColumns(ctx, d, h); err == nil {
+		partitionName = commonColumnData.(*awsCommonColumnData).Partition
+	} else {
+		plugin.Logger(ctx).Warn("aws_service_region_availability.listServiceRegionAvailability", "common_columns_error", err)
+		defaultRegion, err := getDefaultRegion(ctx, d, h)
+		if err != nil {
+			return nil, err
+		}
+		partitionName = awsPartitionFromRegion(defaultRegion)
+	}
+	partition, ok := catalog.partition(partitionName)
+	if !ok {
+		plugin.Logger(ctx).Warn("aws_service_region_availability.listServiceRegionAvailability", "partition", partitionName, "status", "partition not in region catalog")
+		return nil, nil
+	}
+
+	// Opt-in status per region, when the region list is available
+	optInStatus := map[string]string{}
+	if i, err := listRawAwsRegions(ctx, d, h); err == nil {
+		for _, r := range i.([]types.Region) {
+			optInStatus[aws.ToString(r.RegionName)] = aws.ToString(r.OptInStatus)
+		}
+	}
+
+	awsSpcConfig := GetConfig(d.Connection)
+	serviceQual := d.EqualsQualString("service_id")
+	regionQual := d.EqualsQualString("region")
+
+	for _, serviceID := range partition.serviceIds() {
+		if serviceQual != "" && serviceQual != serviceID {
+			continue
+		}
+
+		_, override := getServiceRegionsConfig(awsSpcConfig, serviceID)
+		queryRegions, err := listQueryRegionsForService(ctx, d, serviceID)
+		if err != nil {
+			plugin.Logger(ctx).Error("aws_service_region_availability.listServiceRegionAvailability", "service_id", serviceID, "query_regions_error", err)
+			return nil, err
+		}
+		_, global := partition.GlobalServices[serviceID]
+		source := "embedded"
+		if partition.overriddenServices[serviceID] {
+			source = "file"
+		}
+
+		for _, region := range partition.regionIds() {
+			if regionQual != "" && regionQual != region {
+				continue
+			}
+
+			item := &serviceRegionAvailability{
+				ServiceId:              serviceID,
+				Region:                 region,
+				RegionDescription:      partition.Regions[region],
+				Partition:              partition.Partition,
+				Available:              helpers.StringSliceContains(partition.Services[serviceID], region),
+				GlobalService:          global,
+				RegionOptInStatus:      optInStatus[region],
+				ServiceRegionsOverride: override,
+				CatalogSource:          source,
+			}
+			switch {
+			case global && !item.Available:
+				item.Reason = "global service, queried through its global endpoint"
+			case !item.Available:
+				item.Reason = "service is not available in the region"
+			case item.RegionOptInStatus == "not-opted-in":
+				item.Reason = "region is not opted in for the account"
+			case !helpers.StringSliceContains(queryRegions, region) && override:
+				item.Reason = "region does not match service_regions for the service"
+			case !helpers.StringSliceContains(queryRegions, region) && awsSpcConfig.Regions == nil:
+				item.Reason = "regions is not set in the connection config, so only the default region is queried"
+			case !helpers.StringSliceContains(queryRegions, region):
+				item.Reason = "region does not match the regions config"
+			case !global && !hasFipsEndpointForServiceRegion(catalog, awsSpcConfig, serviceID, region):
+				item.Reason = "service has no FIPS endpoint in the region, and use_fips_endpoint is set"
+			default:
+				item.Queried = true
+			}
+
+			d.StreamListItem(ctx, item)
+
+			// Context can be cancelled due to manual cancellation or the limit has been hit
+			if d.RowsRemaining(ctx) == 0 {
+				return nil, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,45 @@
+package aws
+
+import (
+	"testing"
+)
+
+func TestAwsServiceRegionAvailability(t *testing.T) {
+	api := newMultiRegionStubAPI(t)
+	rows, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_service_region_availability",
+		Columns: []string{"region", "available", "queried", "reason"},
+		Quals:   map[string]string{"service_id": "sns"},
+		Regions: []string{"us-*", "!us-west-*"},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+
+	byRegion := map[string]map[string]interface{}{}
+	for _, row := range rows {
+		byRegion[row["region"].(string)] = row
+	}
+	expected := map[string]struct {
+		queried bool
+		reason  interface{}
+	}{
+		"us-east-1":  {true, nil},
+		"us-east-2":  {true, nil},
+		"us-west-2":  {false, "region does not match the regions config"},
+		"ap-south-1": {false, "region does not match the regions config"},
+	}
+	for region, e := range expected {
+		row, ok := byRegion[region]
+		if !ok {
+			t.Errorf("%s: no row", region)
+			continue
+		}
+		if row["available"] != true {
+			t.Errorf("%s: expected sns to be available", region)
+		}
+		if row["queried"] != e.queried || row["reason"] != e.reason {
+			t.Errorf("%s: expected queried %v (%v), got %v (%v)", region, e.queried, e.reason, row["queried"], row["reason"])
+		}
+	}
+}
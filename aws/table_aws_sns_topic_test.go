@@ -0,0 +1,98 @@
+package aws
+
+import (
+	"net/http"
+	"sort"
+	"testing"
+)
+
+const testSnsTopicArn = "arn:aws:sns:us-east-1:" + stubAccountId + ":"
+
+func snsTopicAttributesResponse(name string) stubResponse {
+	return queryResponse("GetTopicAttributes", `<Attributes>`+
+		`<entry><key>TopicArn</key><value>`+testSnsTopicArn+name+`</value></entry>`+
+		`<entry><key>DisplayName</key><value>`+name+` display</value></entry>`+
+		`</Attributes>`)
+}
+
+func TestAwsSnsTopicList(t *testing.T) {
+	api := newStubAPI(t)
+	api.on("sns", "ListTopics",
+		queryResponse("ListTopics", `<Topics><member><TopicArn>`+testSnsTopicArn+`alpha</TopicArn></member></Topics><NextToken>page2</NextToken>`),
+		queryResponse("ListTopics", `<Topics><member><TopicArn>`+testSnsTopicArn+`beta</TopicArn></member></Topics>`),
+	)
+	api.onFunc("sns", "GetTopicAttributes", func(r stubRequest) stubResponse {
+		name := r.Params.Get("TopicArn")[len(testSnsTopicArn):]
+		return snsTopicAttributesResponse(name)
+	})
+
+	rows, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_sns_topic",
+		Columns: []string{"topic_arn", "display_name", "region", "account_id"},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d: %v", len(rows), rows)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i]["topic_arn"].(string) < rows[j]["topic_arn"].(string) })
+
+	if got := rows[1]["display_name"]; got != "beta display" {
+		t.Errorf("display_name: expected %q, got %q", "beta display", got)
+	}
+	if got := rows[0]["region"]; got != "us-east-1" {
+		t.Errorf("region: expected %q, got %q", "us-east-1", got)
+	}
+	if got := rows[0]["account_id"]; got != stubAccountId {
+		t.Errorf("account_id: expected %q, got %q", stubAccountId, got)
+	}
+
+	pages := api.calls("sns", "ListTopics")
+	if len(pages) != 2 {
+		t.Fatalf("expected 2 ListTopics calls, got %d", len(pages))
+	}
+	if got := pages[1].Params.Get("NextToken"); got != "page2" {
+		t.Errorf("second page: expected NextToken %q, got %q", "page2", got)
+	}
+}
+
+func TestAwsSnsTopicGet(t *testing.T) {
+	api := newStubAPI(t)
+	api.on("sns", "GetTopicAttributes", snsTopicAttributesResponse("alpha"))
+
+	rows, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_sns_topic",
+		Columns: []string{"topic_arn", "display_name"},
+		Quals:   map[string]string{"topic_arn": testSnsTopicArn + "alpha"},
+	})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d: %v", len(rows), rows)
+	}
+	if got := rows[0]["display_name"]; got != "alpha display" {
+		t.Errorf("display_name: expected %q, got %q", "alpha display", got)
+	}
+	if len(api.calls("sns", "ListTopics")) != 0 {
+		t.Errorf("expected the get call to skip ListTopics")
+	}
+}
+
+func TestAwsSnsTopicGetNotFound(t *testing.T) {
+	api := newStubAPI(t)
+	api.on("sns", "GetTopicAttributes", queryErrorResponse(http.StatusNotFound, "NotFound", "Topic does not exist"))
+
+	rows, err := runTableQuery(t, api, tableQuery{
+		Table:   "aws_sns_topic",
+		Columns: []string{"topic_arn", "display_name"},
+		Quals:   map[string]string{"topic_arn": testSnsTopicArn + "missing"},
+	})
+	if err != nil {
+		t.Fatalf("expected the NotFound error to be ignored, got: %v", err)
+	}
+	if len(rows) != 0 {
+		t.Fatalf("expected no rows, got %d: %v", len(rows), rows)
+	}
+}
@@ -152,5 +152,5 @@ func ssoAdminPermissionSetToInstanceArn(ps string) (string, error) {
 	if len(parts) != 3 || parts[0] != "permissionSet" {
 		return "", fmt.Errorf("not a permission set ARN")
 	}
-	return fmt.Sprintf("arn:aws:sso:::instance/%s", parts[1]), nil
+	return fmt.Sprintf("arn:%s:sso:::instance/%s", a.Partition, parts[1]), nil
 }
@@ -16,6 +16,7 @@ func tableAwsSTSCallerIdentity(_ context.Context) *plugin.Table {
 			Hydrate: getStsCallerIdentity,
 			Tags:    map[string]string{"service": "sts", "action": "GetCallerIdentity"},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsAccountColumns([]*plugin.Column{
 			{
 				Name:        "arn",
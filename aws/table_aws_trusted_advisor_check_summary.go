@@ -39,6 +39,7 @@ func tableAwsTrustedAdvisorCheckSummary(_ context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "support", "action": "DescribeTrustedAdvisorCheckSummaries"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsAccountColumns([]*plugin.Column{
 			{
 				Name:        "name",
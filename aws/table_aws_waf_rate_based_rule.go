@@ -39,6 +39,7 @@ func tableAwsWafRateBasedRule(_ context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "waf", "action": "ListTagsForResource"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "name",
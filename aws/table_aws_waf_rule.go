@@ -37,6 +37,7 @@ func tableAwsWAFRule(_ context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "waf", "action": "ListTagsForResource"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "name",
@@ -44,6 +44,7 @@ func tableAwsWafRuleGroup(_ context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "waf", "action": "ListTagsForResource"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "name",
@@ -47,6 +47,7 @@ func tableAwsWafWebAcl(_ context.Context) *plugin.Table {
 				Tags: map[string]string{"service": "waf", "action": "ListTagsForResource"},
 			},
 		},
+		GetMatrixItemFunc: OrganizationAccountMatrix,
 		Columns: awsGlobalRegionColumns([]*plugin.Column{
 			{
 				Name:        "name",
@@ -304,7 +305,7 @@ func classicWebAclData(item interface{}, ctx context.Context, d *plugin.QueryDat
 			return nil
 		}
 		commonColumnData := commonData.(*awsCommonColumnData)
-		data["Arn"] = fmt.Sprintf("arn:aws:waf::%s:webacl/%s", commonColumnData.AccountId, *item.WebACLId)
+		data["Arn"] = fmt.Sprintf("arn:%s:waf::%s:webacl/%s", commonColumnData.Partition, commonColumnData.AccountId, *item.WebACLId)
 		data["Name"] = *item.Name
 	}
 	return data
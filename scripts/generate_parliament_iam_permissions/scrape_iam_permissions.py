@@ -170,9 +170,10 @@ def scrape():
                         continue
 
                     if len(cells) != 6:
-                        # Sometimes the privilege contains Scenarios, and I don't know how to handle this
-                        break
-                        # raise Exception("Unexpected format in {}: {}".format(prefix, row))
+                        # Sometimes the privilege contains Scenarios, e.g. ec2:RunInstances, and
+                        # I don't know how to handle this. Skip the row, not the rest of the table.
+                        row_number += 1
+                        continue
 
                     # See if this cell spans multiple rows
                     rowspan = 1
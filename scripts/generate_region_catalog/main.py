@@ -0,0 +1,174 @@
+"""Generate aws/region_catalog.json from the AWS endpoints model.
+
+The catalog lists the regions of each partition, the regions each service
+is available in, the regions each service has a FIPS endpoint in, and the FIPS
+endpoints of the partition. It is embedded in the plugin and used to decide
+which regions to query for each table. Run this script to pick up new regions and services:
+
+    python3 main.py [endpoints.json path or URL] [output path]
+
+By default, the endpoints model is downloaded from botocore, which is kept up
+to date by AWS, and the catalog is written to aws/region_catalog.json.
+"""
+
+import json
+import os
+import sys
+import urllib.request
+
+DEFAULT_SOURCE = "https://raw.githubusercontent.com/boto/botocore/develop/botocore/data/endpoints.json"
+DEFAULT_OUTPUT = os.path.join(os.path.dirname(os.path.abspath(__file__)), "..", "..", "aws", "region_catalog.json")
+
+
+def load(source):
+    if source.startswith("https://") or source.startswith("http://"):
+        with urllib.request.urlopen(source) as response:
+            return json.load(response)
+    with open(source) as f:
+        return json.load(f)
+
+
+def build_partition(partition):
+    regions = {
+        region: info.get("description", "")
+        for region, info in partition["regions"].items()
+    }
+
+    services = {}
+    global_services = {}
+    fips_services = {}
+    fips_hostnames = set()
+    for service_id, service in partition["services"].items():
+        endpoints = service.get("endpoints", {})
+        # Only endpoints named after a region of the partition count, which
+        # skips FIPS and other variants (e.g. fips-us-east-1, aws-global).
+        service_regions = sorted(r for r in endpoints if r in regions)
+        services[service_id] = service_regions
+
+        # Global services have a single endpoint for the partition, signed
+        # for one region.
+        if service.get("isRegionalized", True) is False and "partitionEndpoint" in service:
+            endpoint = endpoints.get(service["partitionEndpoint"], {})
+            signing_region = endpoint.get("credentialScope", {}).get("region", "")
+            if signing_region:
+                global_services[service_id] = signing_region
+
+        fips_regions = build_fips_regions(service, regions, global_services.get(service_id))
+        if fips_regions:
+            fips_services[service_id] = fips_regions
+        fips_hostnames.update(build_fips_hostnames(partition, service_id, service))
+
+    return {
+        "partition": partition["partition"],
+        "name": partition.get("partitionName", ""),
+        "region_regex": partition.get("regionRegex", ""),
+        "regions": regions,
+        "global_services": global_services,
+        "services": services,
+        "fips_services": fips_services,
+        "fips_hostnames": sorted(fips_hostnames),
+    }
+
+
+def find_variant(variants, tags):
+    for variant in variants or []:
+        if sorted(variant.get("tags", [])) == tags:
+            return variant
+    return None
+
+
+def build_fips_regions(service, regions, global_region):
+    """List the regions a service has a FIPS endpoint in.
+
+    Like FIPS hostnames, only endpoints with an explicit FIPS variant, or of a
+    service with a FIPS variant in its defaults, count. Global services count
+    for their signing region if the partition endpoint has a FIPS variant.
+    """
+    service_default = find_variant(service.get("defaults", {}).get("variants"), ["fips"])
+    fips_regions = set()
+    for region, endpoint in service.get("endpoints", {}).items():
+        if endpoint.get("deprecated"):
+            continue
+        if find_variant(endpoint.get("variants"), ["fips"]) is None and (service_default is None or region not in regions):
+            continue
+        if region in regions:
+            fips_regions.add(region)
+        elif global_region and region == service.get("partitionEndpoint"):
+            fips_regions.add(global_region)
+    return sorted(fips_regions)
+
+
+def build_fips_hostnames(partition, service_id, service):
+    """List the FIPS hostnames of a service, including dual-stack FIPS.
+
+    Only endpoints with an explicit FIPS variant count, or the region endpoints
+    of a service with a FIPS variant in its defaults (e.g. ec2 in aws-us-gov).
+    The partition defaults have a FIPS hostname template for every service,
+    but most services have no FIPS endpoint, and the hostname built from the
+    template doesn't exist.
+    """
+    hostnames = []
+    for tags in [["fips"], ["dualstack", "fips"]]:
+        partition_default = find_variant(partition.get("defaults", {}).get("variants"), tags) or {}
+        service_default = find_variant(service.get("defaults", {}).get("variants"), tags) or {}
+        for region, endpoint in service.get("endpoints", {}).items():
+            variant = find_variant(endpoint.get("variants"), tags)
+            if variant is None and service_default and region in partition["regions"]:
+                variant = service_default
+            if variant is None or endpoint.get("deprecated"):
+                continue
+            hostname = variant.get("hostname") or service_default.get("hostname") or partition_default.get("hostname")
+            dns_suffix = variant.get("dnsSuffix") or service_default.get("dnsSuffix") or partition_default.get("dnsSuffix") or partition["dnsSuffix"]
+            if not hostname:
+                continue
+            hostnames.append(hostname.replace("{service}", service_id).replace("{region}", region).replace("{dnsSuffix}", dns_suffix))
+    return hostnames
+
+
+def write_catalog(catalog, output):
+    # One service per line keeps the file small and the diffs readable.
+    with open(output, "w") as f:
+        f.write("{\n")
+        f.write('  "source": {0},\n'.format(json.dumps(catalog["source"])))
+        f.write('  "partitions": [\n')
+        for i, partition in enumerate(catalog["partitions"]):
+            f.write("    {\n")
+            for key in ["partition", "name", "region_regex"]:
+                f.write('      "{0}": {1},\n'.format(key, json.dumps(partition[key])))
+            for key in ["regions", "global_services"]:
+                f.write('      "{0}": {{\n'.format(key))
+                items = sorted(partition[key].items())
+                for j, (k, v) in enumerate(items):
+                    f.write('        {0}: {1}{2}\n'.format(json.dumps(k), json.dumps(v), "," if j < len(items) - 1 else ""))
+                f.write("      },\n")
+            for key in ["services", "fips_services"]:
+                f.write('      "{0}": {{\n'.format(key))
+                services = sorted(partition[key].items())
+                for j, (service_id, regions) in enumerate(services):
+                    f.write('        {0}: {1}{2}\n'.format(json.dumps(service_id), json.dumps(regions), "," if j < len(services) - 1 else ""))
+                f.write("      },\n")
+            f.write('      "fips_hostnames": [\n')
+            hostnames = partition["fips_hostnames"]
+            for j, hostname in enumerate(hostnames):
+                f.write('        {0}{1}\n'.format(json.dumps(hostname), "," if j < len(hostnames) - 1 else ""))
+            f.write("      ]\n")
+            f.write("    }}{0}\n".format("," if i < len(catalog["partitions"]) - 1 else ""))
+        f.write("  ]\n")
+        f.write("}\n")
+
+
+def main():
+    source = sys.argv[1] if len(sys.argv) > 1 else DEFAULT_SOURCE
+    output = sys.argv[2] if len(sys.argv) > 2 else DEFAULT_OUTPUT
+
+    model = load(source)
+    catalog = {
+        "source": source,
+        "partitions": [build_partition(p) for p in model["partitions"]],
+    }
+    write_catalog(catalog, output)
+    print("Complete")
+
+
+if __name__ == "__main__":
+    main()